@@ -0,0 +1,193 @@
+package apierror
+
+import (
+	"backend/logging"
+	"backend/store"
+	"backend/validation"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Stable error codes, for the frontend and scripts to branch on. Never rename one: add a new code instead.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeUnauthenticated    = "unauthenticated"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeAlreadyExists      = "already_exists"
+	CodeReferenceViolation = "reference_violation"
+	CodeInternal           = "internal"
+
+	CodeEmailExists             = "email_exists"
+	CodeUserNotFound            = "user_not_found"
+	CodeRoleNotFound            = "role_not_found"
+	CodeCustomerNotFound        = "customer_not_found"
+	CodeAssociationExists       = "association_exists"
+	CodeRoleInUse               = "role_in_use"
+	CodeOutboxOperationNotFound = "outbox_operation_not_found"
+	CodeFirebaseUnavailable     = "firebase_unavailable"
+	CodeImportInvalid           = "import_invalid"
+	CodePolicyInvalid           = "policy_invalid"
+)
+
+// Error is the single error type of the API: an HTTP status, a stable code, a user-facing message
+// and optional details. It is rendered by the Render middleware as
+//
+//	{"code": "role_in_use", "message": "...", "type": "warning", "details": ..., "request_id": "..."}
+//
+// Type is "warning" for the mistakes the user can fix (4xx other than 401 and 403), "error" otherwise.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details interface{}
+	// Cause is logged, and never sent to the client
+	Cause error
+}
+
+func New(status int, code string, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Code + ": " + e.Message + ": " + e.Cause.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// WithDetails returns a copy of e with details
+func (e *Error) WithDetails(details interface{}) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// Wrap returns a copy of e caused by cause
+func (e *Error) Wrap(cause error) *Error {
+	copied := *e
+	copied.Cause = cause
+	return &copied
+}
+
+// Helpers for the most common errors
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, message)
+}
+
+func NotFound(code string, message string) *Error {
+	return New(http.StatusNotFound, code, message)
+}
+
+func Conflict(code string, message string) *Error {
+	return New(http.StatusConflict, code, message)
+}
+
+// Internal is an unexpected error. Its message is generic: the cause is only logged
+func Internal(cause error) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, "Something went wrong. Please try again, or contact us with the request id.").Wrap(cause)
+}
+
+// InvalidBody is returned when the request body (or query, or uri) cannot be bound.
+// If it is bound but breaks the rules of its `binding` tags, it is a Validation error
+func InvalidBody(cause error) *Error {
+	var invalid validator.ValidationErrors
+	if errors.As(cause, &invalid) {
+		return Validation(invalid)
+	}
+	return BadRequest("Invalid request: " + cause.Error()).Wrap(cause)
+}
+
+// Validation is returned (as 422) when fields of the request are invalid, with the error of each field as details
+func Validation(errs validator.ValidationErrors) *Error {
+	return invalidFields(validation.Fields(errs), errs)
+}
+
+func invalidFields(fields []validation.FieldError, cause error) *Error {
+	return New(http.StatusUnprocessableEntity, CodeValidationFailed, "Make sure all fields are filled in correctly: "+validation.Summary(fields)).
+		WithDetails(fields).Wrap(cause)
+}
+
+// From converts any error to an *Error: known database errors get their own code, anything else is internal
+func From(err error) *Error {
+	var apiErr *Error
+	var invalidSort *store.InvalidSortError
+	var invalidFilter *store.InvalidFilterError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.As(err, &invalidSort):
+		field := validation.FieldError{Field: "sort", Rule: "sortable", Param: strings.Join(invalidSort.Sortable, " "),
+			Message: "must be one of: " + strings.Join(invalidSort.Sortable, ", ")}
+		return invalidFields([]validation.FieldError{field}, err)
+	case errors.As(err, &invalidFilter):
+		return invalidFields([]validation.FieldError{{Field: "filter", Rule: "filter", Message: invalidFilter.Message}}, err)
+	case errors.Is(err, bcrypt.ErrPasswordTooLong):
+		// The max rule of the password counts characters, bcrypt counts bytes
+		return invalidFields([]validation.FieldError{{Field: "password", Rule: "max", Param: "72", Message: "must be at most 72 bytes long"}}, err)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NotFound(CodeNotFound, "The requested record does not exist.").Wrap(err)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return Conflict(CodeAlreadyExists, "This record already exists.").Wrap(err)
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return Conflict(CodeReferenceViolation, "This record is referenced by (or references) another record.").Wrap(err)
+	default:
+		return Internal(err)
+	}
+}
+
+// Abort stops the request of c with err, rendered by the Render middleware.
+// Server errors are logged here, with the caller (the handler) as source.
+func Abort(c *gin.Context, err error) {
+	apiErr := From(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		logging.ErrorSkip(c, apiErr, 1)
+	}
+	_ = c.Error(apiErr)
+	c.Abort()
+}
+
+// Recovery turns a panic in a handler into an internal error (rendered by Render), and logs it with the stack
+var Recovery = gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered interface{}) {
+	slog.ErrorContext(c.Request.Context(), "panic", slog.String("error", fmt.Sprint(recovered)), slog.String("stack", string(debug.Stack())))
+	_ = c.Error(Internal(nil))
+	c.Abort()
+})
+
+// Render is the middleware rendering the error of the request (the last one passed to Abort) as the JSON envelope,
+// unless a response has already been written
+func Render(c *gin.Context) {
+	c.Next()
+
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+	apiErr := From(c.Errors.Last().Err)
+
+	errorType := "error"
+	if apiErr.Status >= 400 && apiErr.Status < 500 && apiErr.Status != http.StatusUnauthorized && apiErr.Status != http.StatusForbidden {
+		errorType = "warning"
+	}
+	body := gin.H{"code": apiErr.Code, "message": apiErr.Message, "type": errorType}
+	if apiErr.Details != nil {
+		body["details"] = apiErr.Details
+	}
+	if requestID := c.Writer.Header().Get(logging.RequestIDHeader); requestID != "" {
+		body["request_id"] = requestID
+	}
+	c.JSON(apiErr.Status, body)
+}
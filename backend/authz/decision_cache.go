@@ -0,0 +1,274 @@
+package authz
+
+import (
+	"backend/models"
+	"backend/tracing"
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/casbin/casbin/v2"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Stats are the counters of a DecisionCache
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+}
+
+// DecisionCache is an LRU cache of the enforcer's decisions (and of the implicit permissions of each subject).
+// It is registered as the store's policy listener, and invalidated precisely:
+// a rule change only drops the entries of its subject, and of the subjects inheriting from it through roles.
+type DecisionCache struct {
+	enforcer *casbin.SyncedEnforcer
+	capacity int
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[entryKey]*list.Element
+	// cached entries of each subject
+	bySubject map[string]map[entryKey]struct{}
+	// implicit roles of each subject with cached entries, and the reverse index
+	roles  map[string][]string
+	byRole map[string]map[string]struct{}
+	// bumped by every invalidation, so that a decision computed before an invalidation is not cached
+	generation uint64
+
+	hits, misses, evictions uint64
+}
+
+type entryKey struct {
+	sub string
+	obj string
+	act string
+	// the entry holds the implicit permissions of sub (obj and act are empty)
+	permissions bool
+}
+
+type entry struct {
+	key         entryKey
+	allowed     bool
+	permissions [][]string
+}
+
+func NewDecisionCache(enforcer *casbin.SyncedEnforcer, capacity int) *DecisionCache {
+	return &DecisionCache{
+		enforcer:  enforcer,
+		capacity:  capacity,
+		lru:       list.New(),
+		entries:   make(map[entryKey]*list.Element),
+		bySubject: make(map[string]map[entryKey]struct{}),
+		roles:     make(map[string][]string),
+		byRole:    make(map[string]map[string]struct{}),
+	}
+}
+
+// Enforce decides whether sub can do act on obj, like enforcer.Enforce.
+// The decision is traced as a child of the span in ctx.
+func (c *DecisionCache) Enforce(ctx context.Context, sub string, obj string, act string) (allowed bool, err error) {
+	_, span := tracing.Start(ctx, "casbin.enforce", attribute.String("casbin.sub", sub), attribute.String("casbin.obj", obj), attribute.String("casbin.act", act))
+	defer func() {
+		span.SetAttributes(attribute.Bool("casbin.allowed", allowed))
+		tracing.End(span, err)
+	}()
+
+	key := entryKey{sub: sub, obj: obj, act: act}
+	cached, hit := c.get(key)
+	span.SetAttributes(attribute.Bool("casbin.cache_hit", hit))
+	if hit {
+		return cached.allowed, nil
+	}
+
+	generation, roles, err := c.prepare(sub)
+	if err != nil {
+		return false, err
+	}
+	allowed, err = c.enforcer.Enforce(sub, obj, act)
+	if err != nil {
+		return false, err
+	}
+
+	c.put(generation, roles, &entry{key: key, allowed: allowed})
+	return allowed, nil
+}
+
+// ImplicitPermissions returns the permissions of sub, including those of its roles,
+// like enforcer.GetImplicitPermissionsForUser. The lookup is traced as a child of the span in ctx.
+func (c *DecisionCache) ImplicitPermissions(ctx context.Context, sub string) (permissions [][]string, err error) {
+	_, span := tracing.Start(ctx, "casbin.implicit_permissions", attribute.String("casbin.sub", sub))
+	defer func() { tracing.End(span, err) }()
+
+	key := entryKey{sub: sub, permissions: true}
+	cached, hit := c.get(key)
+	span.SetAttributes(attribute.Bool("casbin.cache_hit", hit))
+	if hit {
+		return cached.permissions, nil
+	}
+
+	generation, roles, err := c.prepare(sub)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err = c.enforcer.GetImplicitPermissionsForUser(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	c.put(generation, roles, &entry{key: key, permissions: permissions})
+	return permissions, nil
+}
+
+// Stats returns the counters of the cache
+func (c *DecisionCache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{Hits: c.hits, Misses: c.misses, Evictions: c.evictions, Size: c.lru.Len(), Capacity: c.capacity}
+}
+
+// PolicyChanged invalidates the entries depending on the subject of the changed rules
+func (c *DecisionCache) PolicyChanged(change models.PolicyChange, rules [][]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+
+	if change.Operation == models.PolicyRemoveFiltered {
+		// Only a filter on the subject (or the user of a grouping rule) can be invalidated precisely
+		if change.FieldIndex == 0 && len(rules) == 1 && len(rules[0]) > 0 && rules[0][0] != "" {
+			c.invalidate(rules[0][0])
+		} else {
+			c.purge()
+		}
+		return
+	}
+
+	// The first field is the subject of a policy rule, or the user (or role) gaining or losing a role in a grouping rule
+	for _, rule := range rules {
+		if len(rule) > 0 {
+			c.invalidate(rule[0])
+		}
+	}
+}
+
+// PolicyReloaded drops every entry
+func (c *DecisionCache) PolicyReloaded() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.purge()
+}
+
+func (c *DecisionCache) get(key entryKey) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.lru.MoveToFront(element)
+	return element.Value.(*entry), true
+}
+
+// prepare returns the current generation, and the implicit roles of sub if they are not known yet.
+// The generation is read first, so a role change during the lookup prevents caching.
+func (c *DecisionCache) prepare(sub string) (uint64, []string, error) {
+	c.mu.Lock()
+	generation := c.generation
+	roles, known := c.roles[sub]
+	c.mu.Unlock()
+
+	if known {
+		return generation, roles, nil
+	}
+	roles, err := c.enforcer.GetImplicitRolesForUser(sub)
+	if roles == nil {
+		roles = []string{}
+	}
+	return generation, roles, err
+}
+
+func (c *DecisionCache) put(generation uint64, roles []string, value *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The policy changed while the decision was computed
+	if generation != c.generation {
+		return
+	}
+	if _, ok := c.entries[value.key]; ok {
+		return
+	}
+
+	sub := value.key.sub
+	if _, known := c.roles[sub]; !known {
+		c.roles[sub] = roles
+		for _, role := range roles {
+			if c.byRole[role] == nil {
+				c.byRole[role] = make(map[string]struct{})
+			}
+			c.byRole[role][sub] = struct{}{}
+		}
+	}
+	if c.bySubject[sub] == nil {
+		c.bySubject[sub] = make(map[entryKey]struct{})
+	}
+	c.bySubject[sub][value.key] = struct{}{}
+	c.entries[value.key] = c.lru.PushFront(value)
+
+	for c.lru.Len() > c.capacity {
+		c.remove(c.lru.Back().Value.(*entry).key)
+		c.evictions++
+	}
+}
+
+// invalidate drops the entries of subject, and of every subject inheriting from it
+func (c *DecisionCache) invalidate(subject string) {
+	for inheriting := range c.byRole[subject] {
+		c.forget(inheriting)
+	}
+	c.forget(subject)
+}
+
+// forget drops the entries (and so the known roles) of subject
+func (c *DecisionCache) forget(subject string) {
+	for key := range c.bySubject[subject] {
+		c.remove(key)
+	}
+}
+
+// remove drops an entry, and the known roles of its subject if it was the last one
+func (c *DecisionCache) remove(key entryKey) {
+	element, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.lru.Remove(element)
+	delete(c.entries, key)
+	delete(c.bySubject[key.sub], key)
+	if len(c.bySubject[key.sub]) > 0 {
+		return
+	}
+
+	delete(c.bySubject, key.sub)
+	for _, role := range c.roles[key.sub] {
+		delete(c.byRole[role], key.sub)
+		if len(c.byRole[role]) == 0 {
+			delete(c.byRole, role)
+		}
+	}
+	delete(c.roles, key.sub)
+}
+
+func (c *DecisionCache) purge() {
+	c.lru.Init()
+	c.entries = make(map[entryKey]*list.Element)
+	c.bySubject = make(map[string]map[entryKey]struct{})
+	c.roles = make(map[string][]string)
+	c.byRole = make(map[string]map[string]struct{})
+}
@@ -0,0 +1,156 @@
+package authz
+
+import (
+	"backend/store"
+	"backend/store/storetest"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestCache returns a store on a throwaway database, and a cache of capacity notified of its policy changes
+func newTestCache(t *testing.T, capacity int) (*store.Store, *DecisionCache, func(change func(p *store.PolicyTx) error)) {
+	t.Helper()
+	st := storetest.Open(t)
+
+	enforcer, err := st.NewEnforcer("../config/rbac_model.conf")
+	if err != nil {
+		t.Fatalf("failed to create the enforcer: %v", err)
+	}
+	cache := NewDecisionCache(enforcer, capacity)
+	st.OnPolicyChange(cache)
+	if _, err = st.NewPolicyWatcher(enforcer, time.Minute); err != nil {
+		t.Fatalf("failed to create the policy watcher: %v", err)
+	}
+
+	change := func(change func(p *store.PolicyTx) error) {
+		t.Helper()
+		err := st.Atomic(context.Background(), enforcer, func(uow *store.UnitOfWork) error { return change(uow.Policies) })
+		if err != nil {
+			t.Fatalf("policy change failed: %v", err)
+		}
+	}
+	return st, cache, change
+}
+
+// check enforces sub, obj, act through cache, and checks the decision and whether it was cached
+func check(t *testing.T, cache *DecisionCache, sub string, obj string, act string, wantAllowed bool, wantHit bool) {
+	t.Helper()
+	before := cache.Stats().Hits
+	allowed, err := cache.Enforce(context.Background(), sub, obj, act)
+	if err != nil {
+		t.Fatalf("Enforce(%s, %s, %s) failed: %v", sub, obj, act, err)
+	}
+	if allowed != wantAllowed {
+		t.Errorf("Enforce(%s, %s, %s) = %t, want %t", sub, obj, act, allowed, wantAllowed)
+	}
+	if hit := cache.Stats().Hits > before; hit != wantHit {
+		t.Errorf("Enforce(%s, %s, %s) cache hit = %t, want %t", sub, obj, act, hit, wantHit)
+	}
+}
+
+func TestDecisionCacheInvalidatesSubject(t *testing.T) {
+	_, cache, change := newTestCache(t, 100)
+	change(func(p *store.PolicyTx) error { return p.AddPolicy("bob", "data", "read") })
+
+	check(t, cache, "alice", "data", "read", false, false)
+	check(t, cache, "bob", "data", "read", true, false)
+	check(t, cache, "alice", "data", "read", false, true)
+
+	// Only the entries of alice are dropped
+	change(func(p *store.PolicyTx) error { return p.AddPolicy("alice", "data", "read") })
+	check(t, cache, "alice", "data", "read", true, false)
+	check(t, cache, "bob", "data", "read", true, true)
+
+	change(func(p *store.PolicyTx) error { return p.RemovePolicy("alice", "data", "read") })
+	check(t, cache, "alice", "data", "read", false, false)
+	check(t, cache, "bob", "data", "read", true, true)
+
+	// A filter on the subject drops its entries only
+	change(func(p *store.PolicyTx) error { return p.RemoveFilteredPolicy(0, "bob") })
+	check(t, cache, "bob", "data", "read", false, false)
+	check(t, cache, "alice", "data", "read", false, true)
+
+	// Any other filter drops every entry
+	change(func(p *store.PolicyTx) error { return p.RemoveFilteredPolicy(1, "other") })
+	check(t, cache, "alice", "data", "read", false, false)
+	check(t, cache, "bob", "data", "read", false, false)
+}
+
+func TestDecisionCacheInvalidatesRole(t *testing.T) {
+	_, cache, change := newTestCache(t, 100)
+	change(func(p *store.PolicyTx) error {
+		if err := p.AddGroupingPolicy("alice", "admin"); err != nil {
+			return err
+		}
+		return p.AddPolicy("carol", "data", "read")
+	})
+
+	check(t, cache, "alice", "data", "write", false, false)
+	check(t, cache, "carol", "data", "read", true, false)
+	permissions, err := cache.ImplicitPermissions(context.Background(), "alice")
+	if err != nil || len(permissions) != 0 {
+		t.Fatalf("ImplicitPermissions(alice) = %q, %v, want none", permissions, err)
+	}
+
+	// A permission given to the role drops the entries of the users having it, and only theirs
+	change(func(p *store.PolicyTx) error { return p.AddPolicy("admin", "data", "write") })
+	check(t, cache, "alice", "data", "write", true, false)
+	check(t, cache, "carol", "data", "read", true, true)
+	permissions, err = cache.ImplicitPermissions(context.Background(), "alice")
+	if want := [][]string{{"admin", "data", "write"}}; err != nil || !reflect.DeepEqual(permissions, want) {
+		t.Errorf("ImplicitPermissions(alice) = %q, %v, want %q", permissions, err, want)
+	}
+
+	// Taking the role from the user drops its entries
+	change(func(p *store.PolicyTx) error { return p.RemoveFilteredGroupingPolicy(0, "alice") })
+	check(t, cache, "alice", "data", "write", false, false)
+	check(t, cache, "carol", "data", "read", true, true)
+
+	// Giving it a role too: its roles are looked up again
+	change(func(p *store.PolicyTx) error { return p.AddGroupingPolicy("alice", "admin") })
+	check(t, cache, "alice", "data", "write", true, false)
+
+	// Removing the role's permission, with a filter on the role
+	change(func(p *store.PolicyTx) error { return p.RemoveFilteredPolicy(0, "admin") })
+	check(t, cache, "alice", "data", "write", false, false)
+	check(t, cache, "carol", "data", "read", true, true)
+}
+
+func TestDecisionCacheReload(t *testing.T) {
+	_, cache, change := newTestCache(t, 100)
+	change(func(p *store.PolicyTx) error { return p.AddPolicy("alice", "data", "read") })
+	check(t, cache, "alice", "data", "read", true, false)
+
+	cache.PolicyReloaded()
+	check(t, cache, "alice", "data", "read", true, false)
+	if size := cache.Stats().Size; size != 1 {
+		t.Errorf("size = %d after reload and one decision, want 1", size)
+	}
+}
+
+func TestDecisionCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	_, cache, change := newTestCache(t, 2)
+	change(func(p *store.PolicyTx) error { return p.AddPolicy("alice", "data", "read") })
+
+	check(t, cache, "alice", "data", "read", true, false)
+	check(t, cache, "bob", "data", "read", false, false)
+	// alice becomes the most recently used, so bob is evicted for carol
+	check(t, cache, "alice", "data", "read", true, true)
+	check(t, cache, "carol", "data", "read", false, false)
+
+	check(t, cache, "alice", "data", "read", true, true)
+	check(t, cache, "carol", "data", "read", false, true)
+	check(t, cache, "bob", "data", "read", false, false)
+
+	want := Stats{Hits: 3, Misses: 4, Evictions: 2, Size: 2, Capacity: 2}
+	if stats := cache.Stats(); stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	// Entries surviving evictions are still invalidated by subject
+	change(func(p *store.PolicyTx) error { return p.AddPolicy("bob", "data", "read") })
+	check(t, cache, "bob", "data", "read", true, false)
+	check(t, cache, "carol", "data", "read", false, true)
+}
@@ -0,0 +1,271 @@
+// Package client is the Go client of the admin API (/api/v2), for the services managing customers, employees,
+// users, roles and permissions programmatically:
+//
+//	api := client.New("https://rbac.example.com", client.ServiceTokenSource(firebaseAuth, apiKey, "billing-service"))
+//	err := api.Customers.AddUser(ctx, 1996, "jane@example.com")
+//	if errors.Is(err, client.ErrUserNotFound) {
+//		...
+//	}
+//
+// Failed requests are retried (see RetryPolicy), and API errors are returned as *Error.
+package client
+
+import (
+	"backend/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy retries the idempotent requests (all but POST) failing with a network error, 429, 502, 503 or 504.
+// The delay doubles on every attempt, up to MaxDelay, unless the response has a Retry-After header
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts of a request, including the first one (1 disables retries)
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of the clients returned by New
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 200 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// Client calls the API of the backend at baseURL, authenticated with the tokens of its TokenSource.
+// Its routes are grouped by resource, like the routes of the API.
+type Client struct {
+	baseURL string
+	tokens  TokenSource
+
+	// HTTPClient sends the requests (http.DefaultClient if nil)
+	HTTPClient *http.Client
+	Retry      RetryPolicy
+	// UserAgent of the requests, e.g. the name of the calling service
+	UserAgent string
+
+	Users     *UsersClient
+	Roles     *RolesClient
+	Employees *EmployeesClient
+	Customers *CustomersClient
+	Outbox    *OutboxClient
+	Imports   *ImportsClient
+	Exports   *ExportsClient
+	Policy    *PolicyClient
+}
+
+// New returns a client of the backend at baseURL (e.g. "https://rbac.example.com", without /api/v2)
+func New(baseURL string, tokens TokenSource) *Client {
+	c := &Client{baseURL: strings.TrimSuffix(baseURL, "/") + "/api/v2", tokens: tokens, Retry: DefaultRetryPolicy, UserAgent: "rbac-go-client"}
+	c.Users = &UsersClient{c}
+	c.Roles = &RolesClient{c}
+	c.Employees = &EmployeesClient{c}
+	c.Customers = &CustomersClient{c}
+	c.Outbox = &OutboxClient{c}
+	c.Imports = &ImportsClient{c}
+	c.Exports = &ExportsClient{c}
+	c.Policy = &PolicyClient{c}
+	return c
+}
+
+// CacheStats are the counters of the authorization decision cache of the backend
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+}
+
+// MyPermissions returns the permissions of the user of the token, including those of its roles,
+// each one as [subject, object, action]
+func (c *Client) MyPermissions(ctx context.Context) ([][]string, error) {
+	var permissions [][]string
+	err := c.do(ctx, http.MethodGet, "/me/permissions", nil, nil, &permissions)
+	return permissions, err
+}
+
+// DecisionCacheStats returns the counters of the authorization decision cache
+func (c *Client) DecisionCacheStats(ctx context.Context) (CacheStats, error) {
+	var stats CacheStats
+	err := c.do(ctx, http.MethodGet, "/authz/cache", nil, nil, &stats)
+	return stats, err
+}
+
+// Check decides whether check.Subject can do check.Action on check.Object, with the policy rule and the roles explaining it
+func (c *Client) Check(ctx context.Context, check models.AuthzCheck) (models.AuthzDecision, error) {
+	var decision models.AuthzDecision
+	err := c.do(ctx, http.MethodPost, "/authz/check", nil, check, &decision)
+	return decision, err
+}
+
+// CheckAll decides up to 100 checks, returning the decisions in their order
+func (c *Client) CheckAll(ctx context.Context, checks []models.AuthzCheck) ([]models.AuthzDecision, error) {
+	var decisions []models.AuthzDecision
+	err := c.do(ctx, http.MethodPost, "/authz/check/batch", nil, models.AuthzChecks{Checks: checks}, &decisions)
+	return decisions, err
+}
+
+// do sends a request to the route at path (under /api/v2) with query and the JSON of body (if not nil),
+// and decodes the JSON response in result (if not nil), or copies the response to result if it is an io.Writer
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body interface{}, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to encode request body: %w", err)
+		}
+	}
+	return c.doRaw(ctx, method, path, query, "application/json", payload, result)
+}
+
+// doRaw is do with a payload already encoded in contentType
+func (c *Client) doRaw(ctx context.Context, method string, path string, query url.Values, contentType string, payload []byte, result interface{}) error {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	attempts := c.Retry.MaxAttempts
+	if attempts < 1 || method == http.MethodPost {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		response, err := c.send(ctx, method, endpoint, contentType, payload)
+		if err == nil && response.StatusCode < 300 {
+			defer response.Body.Close()
+			if result == nil || response.StatusCode == http.StatusNoContent {
+				return nil
+			}
+			if writer, ok := result.(io.Writer); ok {
+				_, err = io.Copy(writer, response.Body)
+				return err
+			}
+			if err = json.NewDecoder(response.Body).Decode(result); err != nil {
+				return fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+			}
+			return nil
+		}
+
+		var retryAfter time.Duration
+		if err == nil {
+			retryAfter = parseRetryAfter(response.Header.Get("Retry-After"))
+			err = decodeError(response)
+			response.Body.Close()
+		}
+		if attempt >= attempts || !retryable(ctx, err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.backoff(attempt, retryAfter)):
+		}
+	}
+}
+
+// send sends one attempt of a request, with a fresh token
+func (c *Client) send(ctx context.Context, method string, endpoint string, contentType string, payload []byte) (*http.Response, error) {
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return nil, &TokenError{Err: err}
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Accept", "application/json")
+	if payload != nil {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if c.UserAgent != "" {
+		request.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(request)
+}
+
+// retryable reports whether the request failing with err may succeed if sent again
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var tokenErr *TokenError
+	if errors.As(err, &tokenErr) {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	// Network error
+	return true
+}
+
+// backoff is the delay before the attempt following attempt, with jitter
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	delay := c.Retry.BaseDelay << (attempt - 1)
+	if delay <= 0 || (c.Retry.MaxDelay > 0 && delay > c.Retry.MaxDelay) {
+		delay = c.Retry.MaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// parseRetryAfter parses a Retry-After header in seconds (0 if absent or a date)
+func parseRetryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// filterQuery is the query of the keyword, filter, page and sort of a list
+func filterQuery(filter models.Filter) url.Values {
+	query := url.Values{}
+	if filter.Keyword != "" {
+		query.Set("keyword", filter.Keyword)
+	}
+	if filter.Expression != "" {
+		query.Set("filter", filter.Expression)
+	}
+	if filter.Page > 0 {
+		query.Set("page", strconv.Itoa(filter.Page))
+	}
+	if filter.PageSize > 0 {
+		query.Set("page_size", strconv.Itoa(filter.PageSize))
+	}
+	if filter.Sort != "" {
+		query.Set("sort", filter.Sort)
+	}
+	return query
+}
+
+// segment escapes a value of a path segment (an id, a role or a resource)
+func segment(value interface{}) string {
+	return url.PathEscape(fmt.Sprint(value))
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"backend/models"
+	"context"
+	"net/http"
+)
+
+// CustomersClient calls the routes of /api/v2/customers
+type CustomersClient struct {
+	client *Client
+}
+
+// List returns a page of customers. See the documentation of the API for the fields of filter.Expression
+func (c *CustomersClient) List(ctx context.Context, filter models.Filter) (models.Page[models.Customer], error) {
+	var page models.Page[models.Customer]
+	err := c.client.do(ctx, http.MethodGet, "/customers", filterQuery(filter), nil, &page)
+	return page, err
+}
+
+// Create adds a customer, with its podio id
+func (c *CustomersClient) Create(ctx context.Context, id int, fullName string) (models.Customer, error) {
+	var customer models.Customer
+	err := c.client.do(ctx, http.MethodPost, "/customers", nil, models.AddCustomerRequest{Id: id, FullName: fullName}, &customer)
+	return customer, err
+}
+
+func (c *CustomersClient) Rename(ctx context.Context, id int, fullName string) (models.Customer, error) {
+	var customer models.Customer
+	err := c.client.do(ctx, http.MethodPut, "/customers/"+segment(id), nil, models.RenameRequest{FullName: fullName}, &customer)
+	return customer, err
+}
+
+// Delete deletes a customer, and the users associated only with it
+func (c *CustomersClient) Delete(ctx context.Context, id int) error {
+	return c.client.do(ctx, http.MethodDelete, "/customers/"+segment(id), nil, nil, nil)
+}
+
+// Users returns the users of a customer, with their access to its data
+func (c *CustomersClient) Users(ctx context.Context, id int) ([]models.CustomerUser, error) {
+	var users []models.CustomerUser
+	err := c.client.do(ctx, http.MethodGet, "/customers/"+segment(id)+"/users", nil, nil, &users)
+	return users, err
+}
+
+// AddUser associates the user of email with a customer.
+// It fails with ErrUserNotFound if there is no user with email, and with ErrAssociationExists if they are already associated
+func (c *CustomersClient) AddUser(ctx context.Context, id int, email string) error {
+	return c.client.do(ctx, http.MethodPost, "/customers/"+segment(id)+"/users", nil, models.AssociateUserRequest{Email: email}, nil)
+}
+
+// RemoveUser dissociates a user from a customer (the user is deleted if it has no other customer)
+func (c *CustomersClient) RemoveUser(ctx context.Context, id int, uid string) error {
+	return c.client.do(ctx, http.MethodDelete, "/customers/"+segment(id)+"/users/"+segment(uid), nil, nil, nil)
+}
+
+// GrantAccess gives a user access to the data of a customer: object is models.AccessFinance or models.AccessPerformance
+func (c *CustomersClient) GrantAccess(ctx context.Context, id int, uid string, object string) error {
+	return c.client.do(ctx, http.MethodPut, accessPath(id, uid, object), nil, nil, nil)
+}
+
+// RevokeAccess takes the access of a user to the data of a customer
+func (c *CustomersClient) RevokeAccess(ctx context.Context, id int, uid string, object string) error {
+	return c.client.do(ctx, http.MethodDelete, accessPath(id, uid, object), nil, nil, nil)
+}
+
+func accessPath(id int, uid string, object string) string {
+	return "/customers/" + segment(id) + "/users/" + segment(uid) + "/access/" + segment(object)
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"backend/models"
+	"context"
+	"net/http"
+)
+
+// EmployeesClient calls the routes of /api/v2/employees
+type EmployeesClient struct {
+	client *Client
+}
+
+// List returns a page of employees. See the documentation of the API for the fields of filter.Expression
+func (c *EmployeesClient) List(ctx context.Context, filter models.Filter) (models.Page[models.Employee], error) {
+	var page models.Page[models.Employee]
+	err := c.client.do(ctx, http.MethodGet, "/employees", filterQuery(filter), nil, &page)
+	return page, err
+}
+
+// Create adds an employee, and returns it with the id given by the backend
+func (c *EmployeesClient) Create(ctx context.Context, fullName string) (models.Employee, error) {
+	var employee models.Employee
+	err := c.client.do(ctx, http.MethodPost, "/employees", nil, models.AddEmployeeRequest{FullName: fullName}, &employee)
+	return employee, err
+}
+
+func (c *EmployeesClient) Rename(ctx context.Context, id int, fullName string) (models.Employee, error) {
+	var employee models.Employee
+	err := c.client.do(ctx, http.MethodPut, "/employees/"+segment(id), nil, models.RenameRequest{FullName: fullName}, &employee)
+	return employee, err
+}
+
+// Delete deletes an employee with its users
+func (c *EmployeesClient) Delete(ctx context.Context, id int) error {
+	return c.client.do(ctx, http.MethodDelete, "/employees/"+segment(id), nil, nil, nil)
+}
+
+func (c *EmployeesClient) Users(ctx context.Context, id int) ([]models.EmployeeUser, error) {
+	var users []models.EmployeeUser
+	err := c.client.do(ctx, http.MethodGet, "/employees/"+segment(id)+"/users", nil, nil, &users)
+	return users, err
+}
+
+// AddUser assigns the user of email to an employee. It fails with ErrUserNotFound if there is no user with email
+func (c *EmployeesClient) AddUser(ctx context.Context, id int, email string) error {
+	return c.client.do(ctx, http.MethodPost, "/employees/"+segment(id)+"/users", nil, models.AssociateUserRequest{Email: email}, nil)
+}
+
+// RemoveUser deletes the user of an employee
+func (c *EmployeesClient) RemoveUser(ctx context.Context, id int, uid string) error {
+	return c.client.do(ctx, http.MethodDelete, "/employees/"+segment(id)+"/users/"+segment(uid), nil, nil, nil)
+}
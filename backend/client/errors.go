@@ -0,0 +1,118 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Codes of the errors of the API (see the apierror package of the backend)
+const (
+	CodeInvalidRequest          = "invalid_request"
+	CodeValidationFailed        = "validation_failed"
+	CodeUnauthenticated         = "unauthenticated"
+	CodeForbidden               = "forbidden"
+	CodeNotFound                = "not_found"
+	CodeAlreadyExists           = "already_exists"
+	CodeReferenceViolation      = "reference_violation"
+	CodeInternal                = "internal"
+	CodeEmailExists             = "email_exists"
+	CodeUserNotFound            = "user_not_found"
+	CodeAssociationExists       = "association_exists"
+	CodeRoleInUse               = "role_in_use"
+	CodeOutboxOperationNotFound = "outbox_operation_not_found"
+	CodeFirebaseUnavailable     = "firebase_unavailable"
+	CodeImportInvalid           = "import_invalid"
+	CodePolicyInvalid           = "policy_invalid"
+)
+
+// Errors of each code, to test the errors of the client with errors.Is, like errors.Is(err, client.ErrRoleInUse)
+var (
+	ErrInvalidRequest          = &Error{Code: CodeInvalidRequest}
+	ErrValidationFailed        = &Error{Code: CodeValidationFailed}
+	ErrUnauthenticated         = &Error{Code: CodeUnauthenticated}
+	ErrForbidden               = &Error{Code: CodeForbidden}
+	ErrNotFound                = &Error{Code: CodeNotFound}
+	ErrAlreadyExists           = &Error{Code: CodeAlreadyExists}
+	ErrReferenceViolation      = &Error{Code: CodeReferenceViolation}
+	ErrInternal                = &Error{Code: CodeInternal}
+	ErrEmailExists             = &Error{Code: CodeEmailExists}
+	ErrUserNotFound            = &Error{Code: CodeUserNotFound}
+	ErrAssociationExists       = &Error{Code: CodeAssociationExists}
+	ErrRoleInUse               = &Error{Code: CodeRoleInUse}
+	ErrOutboxOperationNotFound = &Error{Code: CodeOutboxOperationNotFound}
+	ErrFirebaseUnavailable     = &Error{Code: CodeFirebaseUnavailable}
+	ErrImportInvalid           = &Error{Code: CodeImportInvalid}
+	ErrPolicyInvalid           = &Error{Code: CodePolicyInvalid}
+)
+
+// Error is an error response of the API, decoded from its error envelope
+type Error struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	// Type is "warning" for the mistakes the caller can fix, "error" otherwise
+	Type string `json:"type"`
+	// Details depend on the code, e.g. the errors of the fields for validation_failed (see Fields)
+	Details   json.RawMessage `json:"details,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("rbac api: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("rbac api: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Is reports whether target is the error of the same code (one of the Err variables)
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// FieldError is the error of a field of an invalid request
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Fields returns the errors of the fields of a validation_failed error (nil for other errors)
+func (e *Error) Fields() []FieldError {
+	if e.Code != CodeValidationFailed {
+		return nil
+	}
+	var fields []FieldError
+	if err := json.Unmarshal(e.Details, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// TokenError is returned when the TokenSource fails to give a token (the request is not sent)
+type TokenError struct {
+	Err error
+}
+
+func (e *TokenError) Error() string {
+	return "rbac api: failed to get a token: " + e.Err.Error()
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+// decodeError decodes the error envelope of response. A response without one (e.g. from a proxy) gets no code
+func decodeError(response *http.Response) *Error {
+	apiErr := &Error{}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Message == "" {
+		apiErr = &Error{Message: http.StatusText(response.StatusCode)}
+	}
+	apiErr.StatusCode = response.StatusCode
+	return apiErr
+}
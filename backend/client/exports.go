@@ -0,0 +1,40 @@
+package client
+
+import (
+	"backend/models"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ExportsClient calls the routes of /api/v2/exports, the exports of customers, employees and the role-permission matrix
+type ExportsClient struct {
+	client *Client
+}
+
+// Customers returns every customer with its users, and their access to its data
+func (c *ExportsClient) Customers(ctx context.Context) ([]models.CustomerExport, error) {
+	var customers []models.CustomerExport
+	err := c.client.do(ctx, http.MethodGet, "/exports/"+models.ExportCustomers, nil, nil, &customers)
+	return customers, err
+}
+
+// Employees returns every employee with its users, and their roles
+func (c *ExportsClient) Employees(ctx context.Context) ([]models.EmployeeExport, error) {
+	var employees []models.EmployeeExport
+	err := c.client.do(ctx, http.MethodGet, "/exports/"+models.ExportEmployees, nil, nil, &employees)
+	return employees, err
+}
+
+// Permissions returns every permission with the roles having it
+func (c *ExportsClient) Permissions(ctx context.Context) (models.PermissionMatrix, error) {
+	var matrix models.PermissionMatrix
+	err := c.client.do(ctx, http.MethodGet, "/exports/"+models.ExportPermissions, nil, nil, &matrix)
+	return matrix, err
+}
+
+// Download writes the export name (models.ExportCustomers, ...) to w, in format (models.ExportJSON, ExportCSV or ExportXLSX)
+func (c *ExportsClient) Download(ctx context.Context, name string, format string, w io.Writer) error {
+	return c.client.do(ctx, http.MethodGet, "/exports/"+name, url.Values{"format": {format}}, nil, w)
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"backend/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+// ImportsClient calls the routes of /api/v2/imports, the bulk imports of customers, employees and customer users
+type ImportsClient struct {
+	client *Client
+}
+
+// ImportFile is the file of an import (CSV or XLSX, or a policy file), its format given by the extension of its name
+type ImportFile struct {
+	Name    string
+	Content io.Reader
+}
+
+// ImportFiles are the files of an import: a workbook with a sheet of each kind, and/or a file of each kind (nil if absent)
+type ImportFiles struct {
+	Workbook      *ImportFile
+	Customers     *ImportFile
+	Employees     *ImportFile
+	CustomerUsers *ImportFile
+}
+
+// Import imports the rows of files, or only reports what would change with options.DryRun.
+// If rows are invalid, nothing is imported: it fails with ErrImportInvalid, and returns the report of each row
+func (c *ImportsClient) Import(ctx context.Context, files ImportFiles, options models.ImportOptions) (models.ImportReport, error) {
+	var report models.ImportReport
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		field string
+		file  *ImportFile
+	}{
+		{"workbook", files.Workbook},
+		{models.ImportCustomers, files.Customers},
+		{models.ImportEmployees, files.Employees},
+		{models.ImportCustomerUsers, files.CustomerUsers},
+	} {
+		if part.file == nil {
+			continue
+		}
+		writer, err := form.CreateFormFile(part.field, part.file.Name)
+		if err != nil {
+			return report, err
+		}
+		if _, err = io.Copy(writer, part.file.Content); err != nil {
+			return report, err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return report, err
+	}
+
+	query := url.Values{}
+	if options.DryRun {
+		query.Set("dry_run", "true")
+	}
+	if options.CreateUsers {
+		query.Set("create_users", "true")
+	}
+
+	err := c.client.doRaw(ctx, http.MethodPost, "/imports", query, form.FormDataContentType(), body.Bytes(), &report)
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.Code == CodeImportInvalid {
+		_ = json.Unmarshal(apiErr.Details, &report)
+	}
+	return report, err
+}
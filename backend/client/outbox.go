@@ -0,0 +1,29 @@
+package client
+
+import (
+	"backend/models"
+	"context"
+	"net/http"
+)
+
+// OutboxClient calls the routes of /api/v2/outbox, the firebase operations queued by the backend
+type OutboxClient struct {
+	client *Client
+}
+
+// List returns a page of the operations of the outbox, of status (pending, processing, done or failed) if not empty
+func (c *OutboxClient) List(ctx context.Context, status string, filter models.Filter) (models.Page[models.OutboxOperation], error) {
+	query := filterQuery(filter)
+	if status != "" {
+		query.Set("status", status)
+	}
+	var page models.Page[models.OutboxOperation]
+	err := c.client.do(ctx, http.MethodGet, "/outbox", query, nil, &page)
+	return page, err
+}
+
+// Retry queues a failed (or stuck) operation again.
+// It fails with ErrOutboxOperationNotFound if the operation does not exist, or is already done
+func (c *OutboxClient) Retry(ctx context.Context, id uint) error {
+	return c.client.do(ctx, http.MethodPost, "/outbox/"+segment(id)+"/retry", nil, nil, nil)
+}
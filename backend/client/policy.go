@@ -0,0 +1,67 @@
+package client
+
+import (
+	"backend/models"
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+// PolicyClient calls the routes of /api/v2/policy, the export and import of the casbin rules
+type PolicyClient struct {
+	client *Client
+}
+
+// Rules returns the rules matching filter, by type
+func (c *PolicyClient) Rules(ctx context.Context, filter models.PolicyFilter) (models.PolicyRules, error) {
+	var rules models.PolicyRules
+	err := c.client.do(ctx, http.MethodGet, "/policy", policyFilterQuery(filter), nil, &rules)
+	return rules, err
+}
+
+// Export writes the rules matching filter to w, in format (models.PolicyCSV, PolicyJSON or PolicyYAML)
+func (c *PolicyClient) Export(ctx context.Context, filter models.PolicyFilter, format string, w io.Writer) error {
+	query := policyFilterQuery(filter)
+	query.Set("format", format)
+	return c.client.do(ctx, http.MethodGet, "/policy", query, nil, w)
+}
+
+// Import replaces the rules matching the filter of options with those of file, or only computes the diff with options.DryRun.
+// If rules are invalid, nothing is imported: it fails with ErrPolicyInvalid, the problems being its Details
+func (c *PolicyClient) Import(ctx context.Context, file ImportFile, options models.PolicyImportOptions) (models.PolicyDiff, error) {
+	var diff models.PolicyDiff
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	writer, err := form.CreateFormFile("file", file.Name)
+	if err != nil {
+		return diff, err
+	}
+	if _, err = io.Copy(writer, file.Content); err != nil {
+		return diff, err
+	}
+	if err = form.Close(); err != nil {
+		return diff, err
+	}
+
+	query := policyFilterQuery(options.Filter())
+	if options.DryRun {
+		query.Set("dry_run", "true")
+	}
+	err = c.client.doRaw(ctx, http.MethodPost, "/policy/import", query, form.FormDataContentType(), body.Bytes(), &diff)
+	return diff, err
+}
+
+func policyFilterQuery(filter models.PolicyFilter) url.Values {
+	query := url.Values{}
+	if filter.Subject != "" {
+		query.Set("subject", filter.Subject)
+	}
+	if filter.ResourcePrefix != "" {
+		query.Set("resource_prefix", filter.ResourcePrefix)
+	}
+	return query
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"backend/models"
+	"context"
+	"net/http"
+)
+
+// RolesClient calls the routes of /api/v2/roles
+type RolesClient struct {
+	client *Client
+}
+
+func (c *RolesClient) List(ctx context.Context) ([]models.Role, error) {
+	var roles []models.Role
+	err := c.client.do(ctx, http.MethodGet, "/roles", nil, nil, &roles)
+	return roles, err
+}
+
+// Create adds a role, or updates its description
+func (c *RolesClient) Create(ctx context.Context, role string, description string) (models.Role, error) {
+	var created models.Role
+	err := c.client.do(ctx, http.MethodPost, "/roles", nil, models.AddRoleRequest{Role: role, Description: description}, &created)
+	return created, err
+}
+
+// Delete deletes a role and its permissions. It fails with ErrRoleInUse if users still have the role
+func (c *RolesClient) Delete(ctx context.Context, role string) error {
+	return c.client.do(ctx, http.MethodDelete, "/roles/"+segment(role), nil, nil, nil)
+}
+
+// Assignments returns a page of the users assigned to an employee, with their role
+func (c *RolesClient) Assignments(ctx context.Context, filter models.Filter) (models.Page[models.FrontendUser], error) {
+	var page models.Page[models.FrontendUser]
+	err := c.client.do(ctx, http.MethodGet, "/roles/assignments", filterQuery(filter), nil, &page)
+	return page, err
+}
+
+// Permissions returns every permission by category, and whether role has it
+func (c *RolesClient) Permissions(ctx context.Context, role string) (models.FrontendPolicy, error) {
+	var permissions models.FrontendPolicy
+	err := c.client.do(ctx, http.MethodGet, "/roles/"+segment(role)+"/permissions", nil, nil, &permissions)
+	return permissions, err
+}
+
+// AddPermission gives role the permission of action ("read" or "write") on resource, like "portal::data::1996::finance"
+func (c *RolesClient) AddPermission(ctx context.Context, role string, resource string, action string) error {
+	return c.client.do(ctx, http.MethodPut, permissionPath(role, resource, action), nil, nil, nil)
+}
+
+// RemovePermission takes the permission of action on resource from role
+func (c *RolesClient) RemovePermission(ctx context.Context, role string, resource string, action string) error {
+	return c.client.do(ctx, http.MethodDelete, permissionPath(role, resource, action), nil, nil, nil)
+}
+
+func permissionPath(role string, resource string, action string) string {
+	return "/roles/" + segment(role) + "/permissions/" + segment(resource) + "/" + segment(action)
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"firebase.google.com/go/auth"
+)
+
+// TokenSource gives the bearer token of the requests: a Firebase ID token of a user with the permissions needed
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a Firebase ID token obtained elsewhere (it expires after an hour)
+type StaticToken string
+
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// TokenSourceFunc adapts a function to a TokenSource
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// PasswordTokenSource signs in the firebase user of email and password,
+// with apiKey, the web API key of the firebase project
+func PasswordTokenSource(apiKey string, email string, password string) TokenSource {
+	return &firebaseTokenSource{apiKey: apiKey, signIn: func(ctx context.Context, s *firebaseTokenSource) (*signInResponse, error) {
+		return s.post(ctx, identityToolkitURL()+"/accounts:signInWithPassword",
+			map[string]interface{}{"email": email, "password": password, "returnSecureToken": true})
+	}}
+}
+
+// ServiceTokenSource signs in as the firebase user uid with a service credential: a custom token of uid is minted with
+// firebaseAuth (a client of the admin SDK, initialised with the service account of the project)
+// and exchanged for an ID token, with apiKey, the web API key of the project.
+// uid must be given the permissions the service needs, like any user
+func ServiceTokenSource(firebaseAuth *auth.Client, apiKey string, uid string) TokenSource {
+	return &firebaseTokenSource{apiKey: apiKey, signIn: func(ctx context.Context, s *firebaseTokenSource) (*signInResponse, error) {
+		customToken, err := firebaseAuth.CustomToken(ctx, uid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mint a custom token: %w", err)
+		}
+		return s.post(ctx, identityToolkitURL()+"/accounts:signInWithCustomToken",
+			map[string]interface{}{"token": customToken, "returnSecureToken": true})
+	}}
+}
+
+// firebaseTokenSource caches the ID token of a firebase user until shortly before it expires,
+// then refreshes it with its refresh token (or signs in again)
+type firebaseTokenSource struct {
+	apiKey string
+	signIn func(ctx context.Context, s *firebaseTokenSource) (*signInResponse, error)
+
+	mu           sync.Mutex
+	idToken      string
+	refreshToken string
+	expiry       time.Time
+}
+
+// expiryMargin is how long before its expiry a token is renewed
+const expiryMargin = time.Minute
+
+type signInResponse struct {
+	IdToken      string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+func (s *firebaseTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.idToken != "" && time.Now().Add(expiryMargin).Before(s.expiry) {
+		return s.idToken, nil
+	}
+
+	var response *signInResponse
+	var err error
+	if s.refreshToken != "" {
+		response, err = s.refresh(ctx)
+	}
+	if response == nil {
+		response, err = s.signIn(ctx, s)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	s.idToken, s.refreshToken = response.IdToken, response.RefreshToken
+	s.expiry = time.Now().Add(response.ExpiresIn)
+	return s.idToken, nil
+}
+
+// refresh exchanges the refresh token for a new ID token
+func (s *firebaseTokenSource) refresh(ctx context.Context) (*signInResponse, error) {
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {s.refreshToken}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, secureTokenURL()+"/token?key="+url.QueryEscape(s.apiKey), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var body struct {
+		IdToken      string `json:"id_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    string `json:"expires_in"`
+	}
+	if err = s.call(request, &body); err != nil {
+		return nil, err
+	}
+	return newSignInResponse(body.IdToken, body.RefreshToken, body.ExpiresIn), nil
+}
+
+// post posts payload to an endpoint of the identity toolkit, signing in
+func (s *firebaseTokenSource) post(ctx context.Context, endpoint string, payload map[string]interface{}) (*signInResponse, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"?key="+url.QueryEscape(s.apiKey), bytes.NewReader(encoded))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	var body struct {
+		IdToken      string `json:"idToken"`
+		RefreshToken string `json:"refreshToken"`
+		ExpiresIn    string `json:"expiresIn"`
+	}
+	if err = s.call(request, &body); err != nil {
+		return nil, err
+	}
+	return newSignInResponse(body.IdToken, body.RefreshToken, body.ExpiresIn), nil
+}
+
+// call sends a request to firebase, and decodes its response (or its error) in result
+func (s *firebaseTokenSource) call(request *http.Request, result interface{}) error {
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		var failure struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(response.Body).Decode(&failure)
+		return fmt.Errorf("firebase sign in failed: %d %s", response.StatusCode, failure.Error.Message)
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+func newSignInResponse(idToken string, refreshToken string, expiresIn string) *signInResponse {
+	seconds, err := strconv.Atoi(expiresIn)
+	if err != nil {
+		seconds = 3600
+	}
+	return &signInResponse{IdToken: idToken, RefreshToken: refreshToken, ExpiresIn: time.Duration(seconds) * time.Second}
+}
+
+// The endpoints of firebase's REST API, or of the emulator at FIREBASE_AUTH_EMULATOR_HOST (like the admin SDK)
+func identityToolkitURL() string {
+	if host := os.Getenv("FIREBASE_AUTH_EMULATOR_HOST"); host != "" {
+		return "http://" + host + "/identitytoolkit.googleapis.com/v1"
+	}
+	return "https://identitytoolkit.googleapis.com/v1"
+}
+
+func secureTokenURL() string {
+	if host := os.Getenv("FIREBASE_AUTH_EMULATOR_HOST"); host != "" {
+		return "http://" + host + "/securetoken.googleapis.com/v1"
+	}
+	return "https://securetoken.googleapis.com/v1"
+}
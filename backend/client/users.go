@@ -0,0 +1,55 @@
+package client
+
+import (
+	"backend/models"
+	"context"
+	"net/http"
+)
+
+// UsersClient calls the routes of /api/v2/users
+type UsersClient struct {
+	client *Client
+}
+
+// List returns a page of users. See the documentation of the API for the fields of filter.Expression
+func (c *UsersClient) List(ctx context.Context, filter models.Filter) (models.Page[models.User], error) {
+	var page models.Page[models.User]
+	err := c.client.do(ctx, http.MethodGet, "/users", filterQuery(filter), nil, &page)
+	return page, err
+}
+
+// UnassignedEmails returns the emails of the users assigned to neither an employee nor a customer
+func (c *UsersClient) UnassignedEmails(ctx context.Context) ([]string, error) {
+	var emails []string
+	err := c.client.do(ctx, http.MethodGet, "/users/unassigned", nil, nil, &emails)
+	return emails, err
+}
+
+// CustomerEmails returns the emails of the users that can be associated with a customer
+func (c *UsersClient) CustomerEmails(ctx context.Context) ([]string, error) {
+	var emails []string
+	err := c.client.do(ctx, http.MethodGet, "/users/emails", nil, nil, &emails)
+	return emails, err
+}
+
+// Create adds a user. It is created in firebase shortly after, by the outbox worker of the backend
+func (c *UsersClient) Create(ctx context.Context, email string, password string) (models.User, error) {
+	var user models.User
+	err := c.client.do(ctx, http.MethodPost, "/users", nil, models.AddFirebaseUserRequest{Email: email, Password: password}, &user)
+	return user, err
+}
+
+// Delete deletes a user, with its permissions and associations
+func (c *UsersClient) Delete(ctx context.Context, uid string) error {
+	return c.client.do(ctx, http.MethodDelete, "/users/"+segment(uid), nil, nil, nil)
+}
+
+// Sync copies the users of firebase to the database of the backend
+func (c *UsersClient) Sync(ctx context.Context) error {
+	return c.client.do(ctx, http.MethodPost, "/users/sync", nil, nil, nil)
+}
+
+// AssignRole sets the role of a user, replacing its previous role
+func (c *UsersClient) AssignRole(ctx context.Context, uid string, role string) error {
+	return c.client.do(ctx, http.MethodPut, "/users/"+segment(uid)+"/role", nil, models.AssignRoleRequest{Role: role}, nil)
+}
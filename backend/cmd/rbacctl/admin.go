@@ -0,0 +1,219 @@
+package main
+
+import (
+	"backend/client"
+	"backend/models"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// admin is what rbacctl manages, directly (directAdmin) or through the API (apiAdmin)
+type admin interface {
+	ListCustomers(ctx context.Context, filter models.Filter) (models.Page[models.Customer], error)
+	AddCustomer(ctx context.Context, id int, name string) error
+	RenameCustomer(ctx context.Context, id int, name string) error
+	DeleteCustomer(ctx context.Context, id int) error
+	CustomerUsers(ctx context.Context, id int) ([]models.CustomerUser, error)
+	AssociateCustomerUser(ctx context.Context, id int, email string) error
+	DissociateCustomerUser(ctx context.Context, id int, uid string) error
+	SetCustomerAccess(ctx context.Context, id int, uid string, object string, hasAccess bool) error
+
+	ListEmployees(ctx context.Context, filter models.Filter) (models.Page[models.Employee], error)
+	AddEmployee(ctx context.Context, name string) (models.Employee, error)
+	RenameEmployee(ctx context.Context, id int, name string) error
+	DeleteEmployee(ctx context.Context, id int) error
+	EmployeeUsers(ctx context.Context, id int) ([]models.EmployeeUser, error)
+	AssociateEmployeeUser(ctx context.Context, id int, email string) error
+	DissociateEmployeeUser(ctx context.Context, id int, uid string) error
+
+	ListUsers(ctx context.Context, filter models.Filter) (models.Page[models.User], error)
+	SyncUsers(ctx context.Context) error
+
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	ListAssignments(ctx context.Context, filter models.Filter) (models.Page[models.FrontendUser], error)
+	AssignRole(ctx context.Context, uid string, role string) error
+
+	// Import returns the report of the import, even if it fails with invalid rows
+	Import(ctx context.Context, paths importPaths, options models.ImportOptions) (models.ImportReport, error)
+	// Export writes the export name to w, in format
+	Export(ctx context.Context, name string, format string, w io.Writer) error
+
+	// ExportPolicy writes the casbin rules matching filter to w, in format
+	ExportPolicy(ctx context.Context, filter models.PolicyFilter, format string, w io.Writer) error
+	// ImportPolicy replaces the casbin rules matching the filter of options with those of the file at path
+	ImportPolicy(ctx context.Context, path string, options models.PolicyImportOptions) (models.PolicyDiff, error)
+
+	// Check decides whether a subject can do an action on an object, and explains the decision
+	Check(ctx context.Context, check models.AuthzCheck) (models.AuthzDecision, error)
+	CheckAll(ctx context.Context, checks []models.AuthzCheck) ([]models.AuthzDecision, error)
+
+	Close() error
+}
+
+// apiAdmin manages the backend through its API
+type apiAdmin struct {
+	api *client.Client
+}
+
+// newAPIAdmin returns an admin using the API of the backend at baseURL,
+// authenticated with RBACCTL_TOKEN, or by signing in with RBACCTL_EMAIL and RBACCTL_PASSWORD
+func newAPIAdmin(baseURL string) (*apiAdmin, error) {
+	var tokens client.TokenSource
+	switch {
+	case os.Getenv("RBACCTL_TOKEN") != "":
+		tokens = client.StaticToken(os.Getenv("RBACCTL_TOKEN"))
+	case os.Getenv("RBACCTL_EMAIL") != "":
+		if os.Getenv("FIREBASE_API_KEY") == "" {
+			return nil, errors.New("FIREBASE_API_KEY is required to sign in with RBACCTL_EMAIL")
+		}
+		tokens = client.PasswordTokenSource(os.Getenv("FIREBASE_API_KEY"), os.Getenv("RBACCTL_EMAIL"), os.Getenv("RBACCTL_PASSWORD"))
+	default:
+		return nil, errors.New("RBACCTL_TOKEN, or RBACCTL_EMAIL and RBACCTL_PASSWORD, are required with -api")
+	}
+
+	api := client.New(baseURL, tokens)
+	api.UserAgent = "rbacctl"
+	return &apiAdmin{api: api}, nil
+}
+
+func (a *apiAdmin) ListCustomers(ctx context.Context, filter models.Filter) (models.Page[models.Customer], error) {
+	return a.api.Customers.List(ctx, filter)
+}
+
+func (a *apiAdmin) AddCustomer(ctx context.Context, id int, name string) error {
+	_, err := a.api.Customers.Create(ctx, id, name)
+	return err
+}
+
+func (a *apiAdmin) RenameCustomer(ctx context.Context, id int, name string) error {
+	_, err := a.api.Customers.Rename(ctx, id, name)
+	return err
+}
+
+func (a *apiAdmin) DeleteCustomer(ctx context.Context, id int) error {
+	return a.api.Customers.Delete(ctx, id)
+}
+
+func (a *apiAdmin) CustomerUsers(ctx context.Context, id int) ([]models.CustomerUser, error) {
+	return a.api.Customers.Users(ctx, id)
+}
+
+func (a *apiAdmin) AssociateCustomerUser(ctx context.Context, id int, email string) error {
+	return a.api.Customers.AddUser(ctx, id, email)
+}
+
+func (a *apiAdmin) DissociateCustomerUser(ctx context.Context, id int, uid string) error {
+	return a.api.Customers.RemoveUser(ctx, id, uid)
+}
+
+func (a *apiAdmin) SetCustomerAccess(ctx context.Context, id int, uid string, object string, hasAccess bool) error {
+	if hasAccess {
+		return a.api.Customers.GrantAccess(ctx, id, uid, object)
+	}
+	return a.api.Customers.RevokeAccess(ctx, id, uid, object)
+}
+
+func (a *apiAdmin) ListEmployees(ctx context.Context, filter models.Filter) (models.Page[models.Employee], error) {
+	return a.api.Employees.List(ctx, filter)
+}
+
+func (a *apiAdmin) AddEmployee(ctx context.Context, name string) (models.Employee, error) {
+	return a.api.Employees.Create(ctx, name)
+}
+
+func (a *apiAdmin) RenameEmployee(ctx context.Context, id int, name string) error {
+	_, err := a.api.Employees.Rename(ctx, id, name)
+	return err
+}
+
+func (a *apiAdmin) DeleteEmployee(ctx context.Context, id int) error {
+	return a.api.Employees.Delete(ctx, id)
+}
+
+func (a *apiAdmin) EmployeeUsers(ctx context.Context, id int) ([]models.EmployeeUser, error) {
+	return a.api.Employees.Users(ctx, id)
+}
+
+func (a *apiAdmin) AssociateEmployeeUser(ctx context.Context, id int, email string) error {
+	return a.api.Employees.AddUser(ctx, id, email)
+}
+
+func (a *apiAdmin) DissociateEmployeeUser(ctx context.Context, id int, uid string) error {
+	return a.api.Employees.RemoveUser(ctx, id, uid)
+}
+
+func (a *apiAdmin) ListUsers(ctx context.Context, filter models.Filter) (models.Page[models.User], error) {
+	return a.api.Users.List(ctx, filter)
+}
+
+func (a *apiAdmin) SyncUsers(ctx context.Context) error {
+	return a.api.Users.Sync(ctx)
+}
+
+func (a *apiAdmin) ListRoles(ctx context.Context) ([]models.Role, error) {
+	return a.api.Roles.List(ctx)
+}
+
+func (a *apiAdmin) ListAssignments(ctx context.Context, filter models.Filter) (models.Page[models.FrontendUser], error) {
+	return a.api.Roles.Assignments(ctx, filter)
+}
+
+func (a *apiAdmin) AssignRole(ctx context.Context, uid string, role string) error {
+	return a.api.Users.AssignRole(ctx, uid, role)
+}
+
+func (a *apiAdmin) Import(ctx context.Context, paths importPaths, options models.ImportOptions) (models.ImportReport, error) {
+	var files client.ImportFiles
+	for _, part := range []struct {
+		path string
+		file **client.ImportFile
+	}{
+		{paths.workbook, &files.Workbook},
+		{paths.customers, &files.Customers},
+		{paths.employees, &files.Employees},
+		{paths.customerUsers, &files.CustomerUsers},
+	} {
+		if part.path == "" {
+			continue
+		}
+		content, err := os.Open(part.path)
+		if err != nil {
+			return models.ImportReport{}, err
+		}
+		defer content.Close()
+		*part.file = &client.ImportFile{Name: filepath.Base(part.path), Content: content}
+	}
+	return a.api.Imports.Import(ctx, files, options)
+}
+
+func (a *apiAdmin) Export(ctx context.Context, name string, format string, w io.Writer) error {
+	return a.api.Exports.Download(ctx, name, format, w)
+}
+
+func (a *apiAdmin) ExportPolicy(ctx context.Context, filter models.PolicyFilter, format string, w io.Writer) error {
+	return a.api.Policy.Export(ctx, filter, format, w)
+}
+
+func (a *apiAdmin) ImportPolicy(ctx context.Context, path string, options models.PolicyImportOptions) (models.PolicyDiff, error) {
+	content, err := os.Open(path)
+	if err != nil {
+		return models.PolicyDiff{}, err
+	}
+	defer content.Close()
+	return a.api.Policy.Import(ctx, client.ImportFile{Name: filepath.Base(path), Content: content}, options)
+}
+
+func (a *apiAdmin) Check(ctx context.Context, check models.AuthzCheck) (models.AuthzDecision, error) {
+	return a.api.Check(ctx, check)
+}
+
+func (a *apiAdmin) CheckAll(ctx context.Context, checks []models.AuthzCheck) ([]models.AuthzDecision, error) {
+	return a.api.CheckAll(ctx, checks)
+}
+
+func (a *apiAdmin) Close() error {
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"backend/models"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// runCheck runs "check <subject> <object> <action>", or "check -batch file" with a line "subject, object, action" of each check
+func runCheck(ctx context.Context, backend admin, out output, args []string) error {
+	var batch string
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&batch, "batch", "", `file of checks, a line "subject, object, action" of each ("-" for stdin)`)
+	if err := flags.Parse(args); err != nil {
+		return errUsage(err.Error())
+	}
+
+	if batch == "" {
+		if flags.NArg() != 3 {
+			return errUsage("check needs a subject, an object and an action")
+		}
+		decision, err := backend.Check(ctx, models.AuthzCheck{Subject: flags.Arg(0), Object: flags.Arg(1), Action: flags.Arg(2)})
+		if err != nil {
+			return err
+		}
+		return printDecision(out, decision)
+	}
+
+	if flags.NArg() > 0 {
+		return errUsage(fmt.Sprintf("unexpected argument %q", flags.Arg(0)))
+	}
+	checks, err := readChecks(batch)
+	if err != nil {
+		return err
+	}
+	decisions, err := backend.CheckAll(ctx, checks)
+	if err != nil {
+		return err
+	}
+	return printTable(out, decisions, []string{"SUBJECT", "OBJECT", "ACTION", "ALLOWED", "VIA"}, func(decision models.AuthzDecision) []string {
+		return []string{decision.Subject, decision.Object, decision.Action, strconv.FormatBool(decision.Allowed), strings.Join(decision.RolePath, " -> ")}
+	})
+}
+
+// readChecks reads the checks of the file at path, skipping the empty lines and the comments (#)
+func readChecks(path string) ([]models.AuthzCheck, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	var checks []models.AuthzCheck
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return checks, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		checks = append(checks, models.AuthzCheck{Subject: strings.TrimSpace(record[0]), Object: strings.TrimSpace(record[1]), Action: strings.TrimSpace(record[2])})
+	}
+}
+
+// printDecision prints a decision with its explanation: the policy and the roles allowing it, or who is allowed instead
+func printDecision(out output, decision models.AuthzDecision) error {
+	if out.json {
+		return out.printJSON(decision)
+	}
+
+	if decision.Allowed {
+		fmt.Printf("allowed: %s can %s %s\n", decision.Subject, decision.Action, decision.Object)
+		fmt.Printf("  policy:     p, %s\n", strings.Join(decision.Policy, ", "))
+		fmt.Printf("  role path:  %s\n", strings.Join(decision.RolePath, " -> "))
+		return nil
+	}
+
+	fmt.Printf("denied: %s cannot %s %s\n", decision.Subject, decision.Action, decision.Object)
+	fmt.Printf("  roles:      %s\n", orNone(strings.Join(decision.Roles, ", ")))
+	var subjects []string
+	for _, policy := range decision.ObjectPolicies {
+		subjects = append(subjects, policy[0])
+	}
+	fmt.Printf("  allowed to: %s\n", orNone(strings.Join(subjects, ", ")))
+	return nil
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
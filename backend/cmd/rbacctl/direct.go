@@ -0,0 +1,333 @@
+package main
+
+import (
+	"backend/apierror"
+	"backend/config"
+	"backend/database/migrate"
+	"backend/logging"
+	"backend/models"
+	"backend/policyfile"
+	"backend/service"
+	"backend/spreadsheet"
+	"backend/store"
+	"backend/validation"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// directAdmin manages the database and the casbin policy directly, through the same services as the API.
+// Policy changes are logged for the running backends, which also execute the firebase operations queued in the outbox
+type directAdmin struct {
+	store    *store.Store
+	services *service.Service
+}
+
+// newDirectAdmin opens the database of the configuration of the backend, and loads the policy.
+// Firebase is only set up when the command needs it
+func newDirectAdmin(withFirebase bool) (*directAdmin, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if withFirebase {
+		if err = cfg.ValidateFirebase(); err != nil {
+			return nil, err
+		}
+	}
+
+	st, err := store.Open(cfg.Database, logging.GormLogger{SQLDebug: cfg.Logging.SQLDebug, SlowThreshold: cfg.Logging.SlowQueryThreshold})
+	if err != nil {
+		return nil, err
+	}
+	a := &directAdmin{store: st}
+
+	if err = a.setup(cfg, withFirebase); err != nil {
+		st.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *directAdmin) setup(cfg *config.Config, withFirebase bool) error {
+	// Never work on an outdated schema
+	if err := migrate.Check(a.store.DB()); err != nil {
+		return err
+	}
+
+	// The arguments are validated with the rules of the requests of the API
+	if err := validation.Register(); err != nil {
+		return err
+	}
+
+	enforcer, err := a.store.NewEnforcer(cfg.Casbin.ModelFile)
+	if err != nil {
+		return err
+	}
+	// The watcher loads the policy. It is not run: rbacctl does not stay up, and its changes are logged by its units of work
+	if _, err = a.store.NewPolicyWatcher(enforcer, cfg.Casbin.PolicySyncInterval); err != nil {
+		return fmt.Errorf("failed to create policy watcher: %w", err)
+	}
+
+	if withFirebase {
+		a.services = service.New(a.store, enforcer, config.SetupFirebase(cfg.Firebase))
+	} else {
+		a.services = service.New(a.store, enforcer, nil)
+	}
+	return nil
+}
+
+// validate checks request against its binding rules, like gin does for the requests of the API
+func validate(request interface{}) error {
+	if err := binding.Validator.ValidateStruct(request); err != nil {
+		return apierror.InvalidBody(err)
+	}
+	return nil
+}
+
+func (a *directAdmin) ListCustomers(ctx context.Context, filter models.Filter) (models.Page[models.Customer], error) {
+	if err := validate(&filter); err != nil {
+		return models.Page[models.Customer]{}, err
+	}
+	customers, total, err := a.services.Customers.List(ctx, filter)
+	return models.NewPage(customers, total, filter), err
+}
+
+func (a *directAdmin) AddCustomer(ctx context.Context, id int, name string) error {
+	if err := validate(&models.AddCustomerRequest{Id: id, FullName: name}); err != nil {
+		return err
+	}
+	return a.services.Customers.Create(ctx, &models.Customer{Id: id, FullName: name})
+}
+
+func (a *directAdmin) RenameCustomer(ctx context.Context, id int, name string) error {
+	if err := validate(&models.UpdateCustomerRequest{Id: id, FullName: name}); err != nil {
+		return err
+	}
+	return a.services.Customers.Rename(ctx, &models.Customer{Id: id, FullName: name})
+}
+
+func (a *directAdmin) DeleteCustomer(ctx context.Context, id int) error {
+	if err := validate(&models.CustomerPath{Id: id}); err != nil {
+		return err
+	}
+	return a.services.Customers.Delete(ctx, id)
+}
+
+func (a *directAdmin) CustomerUsers(ctx context.Context, id int) ([]models.CustomerUser, error) {
+	if err := validate(&models.CustomerPath{Id: id}); err != nil {
+		return nil, err
+	}
+	return a.services.Customers.Users(ctx, id)
+}
+
+func (a *directAdmin) AssociateCustomerUser(ctx context.Context, id int, email string) error {
+	if err := validate(&models.CustomerPath{Id: id}); err != nil {
+		return err
+	}
+	if err := validate(&models.AssociateUserRequest{Email: email}); err != nil {
+		return err
+	}
+	return a.services.Customers.AddUser(ctx, id, email)
+}
+
+func (a *directAdmin) DissociateCustomerUser(ctx context.Context, id int, uid string) error {
+	if err := validate(&models.CustomerUserPath{Id: id, UserId: uid}); err != nil {
+		return err
+	}
+	return a.services.Customers.RemoveUser(ctx, id, uid)
+}
+
+func (a *directAdmin) SetCustomerAccess(ctx context.Context, id int, uid string, object string, hasAccess bool) error {
+	if err := validate(&models.CustomerAccessPath{Id: id, UserId: uid, Object: object}); err != nil {
+		return err
+	}
+	return a.services.Customers.SetAccess(ctx, id, uid, object, hasAccess)
+}
+
+func (a *directAdmin) ListEmployees(ctx context.Context, filter models.Filter) (models.Page[models.Employee], error) {
+	if err := validate(&filter); err != nil {
+		return models.Page[models.Employee]{}, err
+	}
+	employees, total, err := a.services.Employees.List(ctx, filter)
+	return models.NewPage(employees, total, filter), err
+}
+
+func (a *directAdmin) AddEmployee(ctx context.Context, name string) (models.Employee, error) {
+	var employee = models.Employee{FullName: name}
+	if err := validate(&models.AddEmployeeRequest{FullName: name}); err != nil {
+		return employee, err
+	}
+	err := a.services.Employees.Create(ctx, &employee)
+	return employee, err
+}
+
+func (a *directAdmin) RenameEmployee(ctx context.Context, id int, name string) error {
+	if err := validate(&models.UpdateEmployeeRequest{Id: id, FullName: name}); err != nil {
+		return err
+	}
+	return a.services.Employees.Rename(ctx, &models.Employee{Id: id, FullName: name})
+}
+
+func (a *directAdmin) DeleteEmployee(ctx context.Context, id int) error {
+	if err := validate(&models.EmployeePath{Id: id}); err != nil {
+		return err
+	}
+	return a.services.Employees.Delete(ctx, id)
+}
+
+func (a *directAdmin) EmployeeUsers(ctx context.Context, id int) ([]models.EmployeeUser, error) {
+	if err := validate(&models.EmployeePath{Id: id}); err != nil {
+		return nil, err
+	}
+	return a.services.Employees.Users(ctx, id)
+}
+
+func (a *directAdmin) AssociateEmployeeUser(ctx context.Context, id int, email string) error {
+	if err := validate(&models.EmployeePath{Id: id}); err != nil {
+		return err
+	}
+	if err := validate(&models.AssociateUserRequest{Email: email}); err != nil {
+		return err
+	}
+	return a.services.Employees.AddUser(ctx, id, email)
+}
+
+func (a *directAdmin) DissociateEmployeeUser(ctx context.Context, id int, uid string) error {
+	if err := validate(&models.EmployeeUserPath{Id: id, UserId: uid}); err != nil {
+		return err
+	}
+	return a.services.Employees.RemoveUser(ctx, id, uid)
+}
+
+func (a *directAdmin) ListUsers(ctx context.Context, filter models.Filter) (models.Page[models.User], error) {
+	if err := validate(&filter); err != nil {
+		return models.Page[models.User]{}, err
+	}
+	users, total, err := a.services.Users.List(ctx, filter)
+	return models.NewPage(users, total, filter), err
+}
+
+func (a *directAdmin) SyncUsers(ctx context.Context) error {
+	return a.services.Users.SyncWithFirebase(ctx)
+}
+
+func (a *directAdmin) ListRoles(ctx context.Context) ([]models.Role, error) {
+	return a.services.Roles.List(ctx)
+}
+
+func (a *directAdmin) ListAssignments(ctx context.Context, filter models.Filter) (models.Page[models.FrontendUser], error) {
+	if err := validate(&filter); err != nil {
+		return models.Page[models.FrontendUser]{}, err
+	}
+	users, total, err := a.services.Users.ListWithRoles(ctx, filter)
+	return models.NewPage(users, total, filter), err
+}
+
+func (a *directAdmin) AssignRole(ctx context.Context, uid string, role string) error {
+	if err := validate(&models.UserPath{UserId: uid}); err != nil {
+		return err
+	}
+	if err := validate(&models.AssignRoleRequest{Role: role}); err != nil {
+		return err
+	}
+	return a.services.Roles.Assign(ctx, uid, role)
+}
+
+func (a *directAdmin) Import(ctx context.Context, paths importPaths, options models.ImportOptions) (models.ImportReport, error) {
+	var sheets []spreadsheet.Sheet
+	if paths.workbook != "" {
+		file, err := os.Open(paths.workbook)
+		if err != nil {
+			return models.ImportReport{}, err
+		}
+		defer file.Close()
+		if sheets, err = spreadsheet.ReadWorkbook(file, paths.workbook); err != nil {
+			return models.ImportReport{}, err
+		}
+	}
+	for name, path := range map[string]string{
+		models.ImportCustomers:     paths.customers,
+		models.ImportEmployees:     paths.employees,
+		models.ImportCustomerUsers: paths.customerUsers,
+	} {
+		if path == "" {
+			continue
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return models.ImportReport{}, err
+		}
+		defer file.Close()
+		sheet, err := spreadsheet.ReadSheet(file, path, name)
+		if err != nil {
+			return models.ImportReport{}, err
+		}
+		sheets = append(sheets, sheet)
+	}
+	return a.services.Imports.Import(ctx, sheets, options)
+}
+
+func (a *directAdmin) Export(ctx context.Context, name string, format string, w io.Writer) error {
+	if err := validate(&models.ExportOptions{Format: format}); err != nil {
+		return err
+	}
+	export, err := a.services.Exports.Export(ctx, name)
+	if err != nil {
+		return err
+	}
+	if format == models.ExportJSON {
+		return json.NewEncoder(w).Encode(export.Data)
+	}
+	return spreadsheet.Write(w, format, name, export.Rows)
+}
+
+func (a *directAdmin) ExportPolicy(ctx context.Context, filter models.PolicyFilter, format string, w io.Writer) error {
+	if err := validate(&models.PolicyExportOptions{Format: format}); err != nil {
+		return err
+	}
+	rules, err := a.services.Policies.Rules(filter)
+	if err != nil {
+		return err
+	}
+	return policyfile.Write(w, format, rules)
+}
+
+func (a *directAdmin) ImportPolicy(ctx context.Context, path string, options models.PolicyImportOptions) (models.PolicyDiff, error) {
+	format, err := policyfile.FormatOf(path)
+	if err != nil {
+		return models.PolicyDiff{}, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return models.PolicyDiff{}, err
+	}
+	defer file.Close()
+	rules, err := policyfile.Read(file, format)
+	if err != nil {
+		return models.PolicyDiff{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return a.services.Policies.Import(ctx, rules, options)
+}
+
+func (a *directAdmin) Check(ctx context.Context, check models.AuthzCheck) (models.AuthzDecision, error) {
+	if err := validate(&check); err != nil {
+		return models.AuthzDecision{}, err
+	}
+	return a.services.Authorization.Check(check)
+}
+
+func (a *directAdmin) CheckAll(ctx context.Context, checks []models.AuthzCheck) ([]models.AuthzDecision, error) {
+	if err := validate(&models.AuthzChecks{Checks: checks}); err != nil {
+		return nil, err
+	}
+	return a.services.Authorization.CheckAll(checks)
+}
+
+func (a *directAdmin) Close() error {
+	return a.store.Close()
+}
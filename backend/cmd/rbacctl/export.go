@@ -0,0 +1,62 @@
+package main
+
+import (
+	"backend/models"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runExport runs "export customers|employees|permissions [export flags]", to stdout or to the file of -o
+func runExport(ctx context.Context, backend admin, out output, args []string) error {
+	if len(args) == 0 {
+		return errUsage("missing export")
+	}
+	name, args := args[0], args[1:]
+	switch name {
+	case models.ExportCustomers, models.ExportEmployees, models.ExportPermissions:
+	default:
+		return errUsage(fmt.Sprintf("unknown export %q", name))
+	}
+
+	var format, path string
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&format, "format", "", "csv, xlsx or json")
+	flags.StringVar(&path, "o", "", "file to write")
+	if err := flags.Parse(args); err != nil {
+		return errUsage(err.Error())
+	}
+	if flags.NArg() > 0 {
+		return errUsage(fmt.Sprintf("unexpected argument %q", flags.Arg(0)))
+	}
+
+	// The format defaults to the extension of the file, or to -json, or to CSV
+	switch {
+	case format != "":
+	case path != "":
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	case out.json:
+		format = models.ExportJSON
+	default:
+		format = models.ExportCSV
+	}
+	if path == "" && format == models.ExportXLSX {
+		return errUsage("an XLSX export needs a file (-o)")
+	}
+
+	// Written once complete, so that a failed export leaves no partial file
+	var content bytes.Buffer
+	if err := backend.Export(ctx, name, format, &content); err != nil {
+		return err
+	}
+	if path == "" {
+		_, err := os.Stdout.Write(content.Bytes())
+		return err
+	}
+	return os.WriteFile(path, content.Bytes(), 0o644)
+}
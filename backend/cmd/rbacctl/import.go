@@ -0,0 +1,83 @@
+package main
+
+import (
+	"backend/models"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// importPaths are the paths of the files of an import (empty if absent)
+type importPaths struct {
+	workbook      string
+	customers     string
+	employees     string
+	customerUsers string
+}
+
+// runImport runs "import [import flags] [workbook.xlsx]". The report is printed even if the import is invalid
+func runImport(ctx context.Context, backend admin, out output, args []string) error {
+	var paths importPaths
+	var options models.ImportOptions
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&paths.customers, "customers", "", "CSV or XLSX file of customers")
+	flags.StringVar(&paths.employees, "employees", "", "CSV or XLSX file of employees")
+	flags.StringVar(&paths.customerUsers, "customer-users", "", "CSV or XLSX file of customer users")
+	flags.BoolVar(&options.DryRun, "dry-run", false, "only validate the rows, and report what would change")
+	flags.BoolVar(&options.CreateUsers, "create-users", false, "add the users of the emails missing from the database")
+	if err := flags.Parse(args); err != nil {
+		return errUsage(err.Error())
+	}
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		paths.workbook = flags.Arg(0)
+	default:
+		return errUsage(fmt.Sprintf("unexpected argument %q", flags.Arg(1)))
+	}
+	if paths == (importPaths{}) {
+		return errUsage("nothing to import")
+	}
+
+	report, err := backend.Import(ctx, paths, options)
+	if len(report.Rows) > 0 {
+		if printErr := printReport(out, report); printErr != nil {
+			return printErr
+		}
+	}
+	return err
+}
+
+// printReport prints the action of each row, and the summary
+func printReport(out output, report models.ImportReport) error {
+	if out.json {
+		return out.printJSON(report)
+	}
+
+	err := printTable(out, report.Rows, []string{"SHEET", "LINE", "ACTION", "DETAILS"}, func(row models.ImportRow) []string {
+		details := row.Changes
+		if len(row.Errors) > 0 {
+			details = row.Errors
+		}
+		return []string{row.Sheet, strconv.Itoa(row.Line), row.Action, strings.Join(details, "; ")}
+	})
+	if err != nil {
+		return err
+	}
+
+	summary := report.Summary
+	fmt.Printf("\n%d created, %d updated, %d unchanged, %d invalid, %d users created in firebase", summary.Created, summary.Updated, summary.Unchanged, summary.Invalid, summary.UsersCreated)
+	switch {
+	case report.Applied:
+		fmt.Println(": imported")
+	case report.DryRun && summary.Invalid == 0:
+		fmt.Println(": dry run, nothing was imported")
+	default:
+		fmt.Println()
+	}
+	return nil
+}
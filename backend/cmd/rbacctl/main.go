@@ -0,0 +1,365 @@
+// Command rbacctl administers the RBAC backend from a terminal: customers, employees, their users,
+// roles and access to the data of the customers.
+// It works directly on the database and the casbin policy, or through the HTTP API of a running backend (-api).
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"backend/models"
+)
+
+const usage = `usage: rbacctl [-api URL] [-json] <command> [arguments]
+
+By default rbacctl works directly on the database and the casbin policy, configured like the backend
+(./info/config.yaml, ./info/.env and the environment). The running backends pick the changes up through
+the policy change log, and execute the firebase operations queued in the outbox.
+
+commands:
+  customers list [list flags]
+  customers add <id> <name>
+  customers rename <id> <name>
+  customers delete <id>
+  customers users <id>
+  customers associate <id> <email>
+  customers dissociate <id> <uid>
+  customers grant <id> <uid> finance|performance
+  customers revoke <id> <uid> finance|performance
+  employees list [list flags]
+  employees add <name>
+  employees rename <id> <name>
+  employees delete <id>
+  employees users <id>
+  employees associate <id> <email>
+  employees dissociate <id> <uid>
+  users list [list flags]
+  users sync                  copy the users of firebase to the database
+  roles list
+  roles assignments [list flags]
+  roles assign <uid> <role>   set the role of a user, replacing its previous role
+  import [import flags] [workbook.xlsx]
+                              import customers, employees and customer users from CSV or XLSX files
+  export customers|employees|permissions [export flags]
+                              export the customers with their users and access, the employees with
+                              their users and roles, or the role-permission matrix
+  policy export [policy flags] [-format csv|json|yaml] [-o file]
+                              export the casbin rules (csv like the policy files of casbin)
+  policy import [policy flags] [-dry-run] [-yes] <file>
+                              replace the casbin rules with those of a CSV, JSON or YAML file,
+                              showing the diff and asking before applying it (unless -yes)
+  check <subject> <object> <action>
+                              whether a user (or role) can do an action on an object, and why:
+                              the policy rule and the roles allowing it, or who is allowed instead
+  check -batch file           the checks of a file with a line "subject, object, action" of each (- for stdin)
+
+import flags:
+  -customers file  -employees file  -customer-users file
+             CSV or XLSX files of each kind (instead of, or with, the sheets of a workbook)
+  -dry-run   only validate the rows, and report what would change
+  -create-users
+             add the users of the emails missing from the database, from firebase or created in firebase
+
+export flags:
+  -format csv|xlsx|json
+             the format, by default the extension of the file of -o, or json with -json, or csv
+  -o file    write to file instead of stdout (required for xlsx)
+
+policy flags:
+  -subject s  -resource-prefix p
+             only the rules of the user or role s, and/or the policy rules of the resources starting with p
+             (an import only replaces those rules)
+
+list flags:
+  -filter expr  -keyword text  -sort columns  -page n  -page-size n
+
+flags:
+  -api URL   use the HTTP API of the backend at URL (or RBACCTL_API), authenticated with RBACCTL_TOKEN
+             (a Firebase ID token), or with RBACCTL_EMAIL, RBACCTL_PASSWORD and FIREBASE_API_KEY
+  -json      print JSON instead of tables`
+
+func main() {
+	flags := flag.NewFlagSet("rbacctl", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	apiURL := flags.String("api", os.Getenv("RBACCTL_API"), "")
+	asJSON := flags.Bool("json", false, "")
+	flags.Parse(os.Args[1:])
+	args := flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var backend admin
+	var err error
+	if *apiURL != "" {
+		backend, err = newAPIAdmin(*apiURL)
+	} else {
+		backend, err = newDirectAdmin(needsFirebase(args))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rbacctl:", err)
+		os.Exit(1)
+	}
+	defer backend.Close()
+
+	out := output{json: *asJSON}
+	if err = run(ctx, backend, out, args); err != nil {
+		report(err)
+		backend.Close()
+		os.Exit(1)
+	}
+}
+
+// errUsage is returned for unknown commands and wrong arguments
+type errUsage string
+
+func (e errUsage) Error() string {
+	return string(e) + "\n\n" + usage
+}
+
+// needsFirebase reports whether the command of args uses firebase (only set up when needed in direct mode)
+func needsFirebase(args []string) bool {
+	switch args[0] {
+	case "users":
+		return len(args) > 1 && args[1] == "sync"
+	case "import":
+		for _, arg := range args[1:] {
+			if strings.TrimLeft(arg, "-") == "create-users" || strings.HasPrefix(strings.TrimLeft(arg, "-"), "create-users=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// run runs the command of args, like "customers list"
+func run(ctx context.Context, backend admin, out output, args []string) error {
+	switch args[0] {
+	case "import":
+		return runImport(ctx, backend, out, args[1:])
+	case "export":
+		return runExport(ctx, backend, out, args[1:])
+	case "policy":
+		return runPolicy(ctx, backend, out, args[1:])
+	case "check":
+		return runCheck(ctx, backend, out, args[1:])
+	}
+	if len(args) < 2 {
+		return errUsage("missing command")
+	}
+
+	resource, command, args := args[0], args[1], args[2:]
+	switch resource + " " + command {
+	case "customers list":
+		filter, err := listFlags(args)
+		if err != nil {
+			return err
+		}
+		page, err := backend.ListCustomers(ctx, filter)
+		if err != nil {
+			return err
+		}
+		return printPage(out, page, []string{"ID", "NAME"}, func(c models.Customer) []string {
+			return []string{strconv.Itoa(c.Id), c.FullName}
+		})
+	case "customers add":
+		id, name, err := idAndName(args)
+		if err != nil {
+			return err
+		}
+		return out.done(backend.AddCustomer(ctx, id, name), "added customer %d", id)
+	case "customers rename":
+		id, name, err := idAndName(args)
+		if err != nil {
+			return err
+		}
+		return out.done(backend.RenameCustomer(ctx, id, name), "renamed customer %d", id)
+	case "customers delete":
+		id, err := idArg(args, 1)
+		if err != nil {
+			return err
+		}
+		return out.done(backend.DeleteCustomer(ctx, id), "deleted customer %d", id)
+	case "customers users":
+		id, err := idArg(args, 1)
+		if err != nil {
+			return err
+		}
+		users, err := backend.CustomerUsers(ctx, id)
+		if err != nil {
+			return err
+		}
+		return printTable(out, users, []string{"UID", "EMAIL", "FINANCE", "PERFORMANCE"}, func(u models.CustomerUser) []string {
+			return []string{u.Id, u.Email, strconv.FormatBool(u.HasFinancialAccess), strconv.FormatBool(u.HasPerformanceAccess)}
+		})
+	case "customers associate":
+		id, err := idArg(args, 2)
+		if err != nil {
+			return err
+		}
+		return out.done(backend.AssociateCustomerUser(ctx, id, args[1]), "associated %s with customer %d", args[1], id)
+	case "customers dissociate":
+		id, err := idArg(args, 2)
+		if err != nil {
+			return err
+		}
+		return out.done(backend.DissociateCustomerUser(ctx, id, args[1]), "dissociated %s from customer %d", args[1], id)
+	case "customers grant", "customers revoke":
+		id, err := idArg(args, 3)
+		if err != nil {
+			return err
+		}
+		grant := command == "grant"
+		return out.done(backend.SetCustomerAccess(ctx, id, args[1], args[2], grant), "%sed %s access of %s to customer %d", command, args[2], args[1], id)
+
+	case "employees list":
+		filter, err := listFlags(args)
+		if err != nil {
+			return err
+		}
+		page, err := backend.ListEmployees(ctx, filter)
+		if err != nil {
+			return err
+		}
+		return printPage(out, page, []string{"ID", "NAME"}, func(e models.Employee) []string {
+			return []string{strconv.Itoa(e.Id), e.FullName}
+		})
+	case "employees add":
+		if len(args) == 0 {
+			return errUsage("missing name")
+		}
+		employee, err := backend.AddEmployee(ctx, strings.Join(args, " "))
+		return out.done(err, "added employee %d", employee.Id)
+	case "employees rename":
+		id, name, err := idAndName(args)
+		if err != nil {
+			return err
+		}
+		return out.done(backend.RenameEmployee(ctx, id, name), "renamed employee %d", id)
+	case "employees delete":
+		id, err := idArg(args, 1)
+		if err != nil {
+			return err
+		}
+		return out.done(backend.DeleteEmployee(ctx, id), "deleted employee %d", id)
+	case "employees users":
+		id, err := idArg(args, 1)
+		if err != nil {
+			return err
+		}
+		users, err := backend.EmployeeUsers(ctx, id)
+		if err != nil {
+			return err
+		}
+		return printTable(out, users, []string{"UID", "EMAIL"}, func(u models.EmployeeUser) []string {
+			return []string{u.Id, u.Email}
+		})
+	case "employees associate":
+		id, err := idArg(args, 2)
+		if err != nil {
+			return err
+		}
+		return out.done(backend.AssociateEmployeeUser(ctx, id, args[1]), "assigned %s to employee %d", args[1], id)
+	case "employees dissociate":
+		id, err := idArg(args, 2)
+		if err != nil {
+			return err
+		}
+		return out.done(backend.DissociateEmployeeUser(ctx, id, args[1]), "deleted user %s of employee %d", args[1], id)
+
+	case "users list":
+		filter, err := listFlags(args)
+		if err != nil {
+			return err
+		}
+		page, err := backend.ListUsers(ctx, filter)
+		if err != nil {
+			return err
+		}
+		return printPage(out, page, []string{"UID", "EMAIL", "EMPLOYEE", "LAST LOGIN"}, func(u models.User) []string {
+			employee := ""
+			if u.EmployeeID != nil {
+				employee = strconv.Itoa(*u.EmployeeID)
+			}
+			return []string{u.Id, u.Email, employee, millis(u.LastLoginTimestamp)}
+		})
+	case "users sync":
+		return out.done(backend.SyncUsers(ctx), "synced users with firebase")
+
+	case "roles list":
+		roles, err := backend.ListRoles(ctx)
+		if err != nil {
+			return err
+		}
+		return printTable(out, roles, []string{"ROLE", "DESCRIPTION"}, func(r models.Role) []string {
+			return []string{r.Role, r.Description}
+		})
+	case "roles assignments":
+		filter, err := listFlags(args)
+		if err != nil {
+			return err
+		}
+		page, err := backend.ListAssignments(ctx, filter)
+		if err != nil {
+			return err
+		}
+		return printPage(out, page, []string{"UID", "EMAIL", "EMPLOYEE", "ROLE"}, func(u models.FrontendUser) []string {
+			return []string{u.Id, u.Email, u.FullName, u.Role}
+		})
+	case "roles assign":
+		if len(args) != 2 {
+			return errUsage("expected <uid> <role>")
+		}
+		return out.done(backend.AssignRole(ctx, args[0], args[1]), "assigned role %s to %s", args[1], args[0])
+	}
+	return errUsage(fmt.Sprintf("unknown command %q", resource+" "+command))
+}
+
+// listFlags parses the flags of the list commands
+func listFlags(args []string) (models.Filter, error) {
+	var filter models.Filter
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&filter.Expression, "filter", "", "conditions on the fields, like \"has_users=false\"")
+	flags.StringVar(&filter.Keyword, "keyword", "", "rows containing the keyword in any column")
+	flags.StringVar(&filter.Sort, "sort", "", "comma separated columns, descending when prefixed by \"-\"")
+	flags.IntVar(&filter.Page, "page", 1, "page number, from 1")
+	flags.IntVar(&filter.PageSize, "page-size", models.DefaultPageSize, "rows of a page")
+	if err := flags.Parse(args); err != nil {
+		return filter, errUsage(err.Error())
+	}
+	if flags.NArg() > 0 {
+		return filter, errUsage(fmt.Sprintf("unexpected argument %q", flags.Arg(0)))
+	}
+	return filter, nil
+}
+
+// idArg parses the id of the first of the n arguments of a command
+func idArg(args []string, n int) (int, error) {
+	if len(args) != n {
+		return 0, errUsage(fmt.Sprintf("expected %d arguments", n))
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, errUsage(fmt.Sprintf("invalid id %q", args[0]))
+	}
+	return id, nil
+}
+
+// idAndName parses the arguments <id> <name...>
+func idAndName(args []string) (int, string, error) {
+	if len(args) < 2 {
+		return 0, "", errUsage("expected <id> <name>")
+	}
+	id, err := idArg(args[:1], 1)
+	return id, strings.Join(args[1:], " "), err
+}
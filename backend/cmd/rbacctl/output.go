@@ -0,0 +1,122 @@
+package main
+
+import (
+	"backend/apierror"
+	"backend/client"
+	"backend/models"
+	"backend/validation"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// output prints the results of the commands to stdout, as tables or as JSON
+type output struct {
+	json bool
+}
+
+// printTable prints items as a table with a column of each header, each item as the row given by row
+func printTable[T any](out output, items []T, header []string, row func(T) []string) error {
+	if out.json {
+		if items == nil {
+			items = []T{}
+		}
+		return out.printJSON(items)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, item := range items {
+		fmt.Fprintln(w, strings.Join(row(item), "\t"))
+	}
+	return w.Flush()
+}
+
+// printPage prints the items of a page as a table, followed by the position of the page
+func printPage[T any](out output, page models.Page[T], header []string, row func(T) []string) error {
+	if out.json {
+		return out.printJSON(page)
+	}
+
+	if err := printTable(out, page.Items, header, row); err != nil {
+		return err
+	}
+	// Only when there are more rows than shown
+	if page.Total > int64(len(page.Items)) {
+		first := int64((page.Page - 1) * page.PageSize)
+		fmt.Printf("\n%d-%d of %d (page %d)\n", first+1, first+int64(len(page.Items)), page.Total, page.Page)
+	}
+	return nil
+}
+
+// done prints the message of a command changing something, unless it failed
+func (out output) done(err error, format string, args ...interface{}) error {
+	if err != nil {
+		return err
+	}
+	if out.json {
+		return out.printJSON(map[string]string{"message": fmt.Sprintf(format, args...)})
+	}
+	fmt.Printf(format+"\n", args...)
+	return nil
+}
+
+func (out output) printJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// report prints err to stderr: the code and message of the API errors (direct or not), with the errors of each field
+func report(err error) {
+	var usageErr errUsage
+	var apiErr *client.Error
+	switch {
+	case errors.As(err, &usageErr):
+		fmt.Fprintln(os.Stderr, "rbacctl:", err)
+	case errors.As(err, &apiErr):
+		fmt.Fprintf(os.Stderr, "rbacctl: %s\n", apiErr)
+		for _, field := range apiErr.Fields() {
+			fmt.Fprintf(os.Stderr, "  %s: %s\n", field.Field, field.Message)
+		}
+		// Like the invalid rules of a policy import
+		var problems []string
+		if json.Unmarshal(apiErr.Details, &problems) == nil {
+			printProblems(problems)
+		}
+	default:
+		direct := apierror.From(err)
+		if direct.Code == apierror.CodeInternal {
+			// Internal errors are not hidden from the operator
+			fmt.Fprintln(os.Stderr, "rbacctl:", err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "rbacctl: %s: %s\n", direct.Code, direct.Message)
+		if fields, ok := direct.Details.([]validation.FieldError); ok {
+			for _, field := range fields {
+				fmt.Fprintf(os.Stderr, "  %s: %s\n", field.Field, field.Message)
+			}
+		}
+		if problems, ok := direct.Details.([]string); ok {
+			printProblems(problems)
+		}
+	}
+}
+
+func printProblems(problems []string) {
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "  %s\n", problem)
+	}
+}
+
+// millis formats a timestamp in milliseconds (empty if zero)
+func millis(timestamp int) string {
+	if timestamp == 0 {
+		return ""
+	}
+	return time.UnixMilli(int64(timestamp)).Format(time.RFC3339)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"backend/models"
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runPolicy runs "policy export [policy flags]" and "policy import [policy flags] <file>"
+func runPolicy(ctx context.Context, backend admin, out output, args []string) error {
+	if len(args) == 0 {
+		return errUsage("missing command")
+	}
+	command, args := args[0], args[1:]
+
+	var filter models.PolicyFilter
+	var format, path string
+	var dryRun, yes bool
+	flags := flag.NewFlagSet("policy "+command, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&filter.Subject, "subject", "", "only the rules of this user or role")
+	flags.StringVar(&filter.ResourcePrefix, "resource-prefix", "", "only the policy rules of the resources starting with this prefix")
+	switch command {
+	case "export":
+		flags.StringVar(&format, "format", "", "csv, json or yaml")
+		flags.StringVar(&path, "o", "", "file to write")
+	case "import":
+		flags.BoolVar(&dryRun, "dry-run", false, "only show the diff")
+		flags.BoolVar(&yes, "yes", false, "apply the diff without asking")
+	default:
+		return errUsage(fmt.Sprintf("unknown command %q", "policy "+command))
+	}
+	if err := flags.Parse(args); err != nil {
+		return errUsage(err.Error())
+	}
+
+	if command == "export" {
+		if flags.NArg() > 0 {
+			return errUsage(fmt.Sprintf("unexpected argument %q", flags.Arg(0)))
+		}
+		return exportPolicy(ctx, backend, filter, format, path)
+	}
+	if flags.NArg() != 1 {
+		return errUsage("policy import needs one file")
+	}
+	return importPolicy(ctx, backend, out, flags.Arg(0), models.PolicyImportOptions{Subject: filter.Subject, ResourcePrefix: filter.ResourcePrefix, DryRun: dryRun}, yes)
+}
+
+// exportPolicy writes the rules to path, or to stdout. The format defaults to the extension of the file, or to CSV
+func exportPolicy(ctx context.Context, backend admin, filter models.PolicyFilter, format string, path string) error {
+	switch {
+	case format != "":
+	case path != "":
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if format == "yml" {
+			format = models.PolicyYAML
+		}
+	default:
+		format = models.PolicyCSV
+	}
+
+	var content bytes.Buffer
+	if err := backend.ExportPolicy(ctx, filter, format, &content); err != nil {
+		return err
+	}
+	if path == "" {
+		_, err := os.Stdout.Write(content.Bytes())
+		return err
+	}
+	return os.WriteFile(path, content.Bytes(), 0o644)
+}
+
+// importPolicy shows the diff of the import of path, and applies it once confirmed (unless a dry run)
+func importPolicy(ctx context.Context, backend admin, out output, path string, options models.PolicyImportOptions, yes bool) error {
+	apply := !options.DryRun
+	options.DryRun = true
+	diff, err := backend.ImportPolicy(ctx, path, options)
+	if err != nil {
+		return err
+	}
+	if err = printDiff(out, diff); err != nil {
+		return err
+	}
+	if !apply || len(diff.Added)+len(diff.Removed) == 0 {
+		return nil
+	}
+
+	if !yes {
+		fmt.Fprint(os.Stderr, "Apply these changes? [y/N] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			return out.done(nil, "Nothing was imported.")
+		}
+	}
+
+	// The policy may have changed since the diff: the diff applied is printed if it differs
+	options.DryRun = false
+	applied, err := backend.ImportPolicy(ctx, path, options)
+	if err != nil {
+		return err
+	}
+	if out.json {
+		return out.printJSON(applied)
+	}
+	if len(applied.Added) != len(diff.Added) || len(applied.Removed) != len(diff.Removed) {
+		fmt.Fprintln(os.Stderr, "The policy changed since the diff above, the changes applied are:")
+		if err = printDiff(out, applied); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Imported: %d rules added, %d removed.\n", len(applied.Added), len(applied.Removed))
+	return nil
+}
+
+// printDiff prints the rules added (+) and removed (-), and their count
+func printDiff(out output, diff models.PolicyDiff) error {
+	if out.json {
+		return out.printJSON(diff)
+	}
+	for _, rule := range diff.Removed {
+		fmt.Println("- " + strings.Join(append([]string{rule.Ptype}, rule.Rule...), ", "))
+	}
+	for _, rule := range diff.Added {
+		fmt.Println("+ " + strings.Join(append([]string{rule.Ptype}, rule.Rule...), ", "))
+	}
+	fmt.Printf("%d added, %d removed, %d unchanged\n", len(diff.Added), len(diff.Removed), diff.Unchanged)
+	return nil
+}
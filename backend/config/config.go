@@ -1,54 +1,28 @@
 package config
 
 import (
-	"log"
+	"backend/logging"
+	"io"
 	//"net/http"
-	"os"
-	"time"
-
-	"github.com/joho/godotenv"
 )
 
 //LOGGER
-func InitLogging() {
-	if os.Getenv("APP_ENV") == "prod" {
-		if _, err := os.Stat("./logs/"); os.IsNotExist(err) {
-			os.MkdirAll("./logs", 0700)
-		}
-		dt := time.Now()
-		file, err := os.OpenFile("./logs/logs."+dt.Format("2006-01-02")+".log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
-		if err != nil {
-			log.Fatal(err)
-		}
-		log.SetOutput(file)
-		log.Println("Set log to file")
-	} else {
-		log.SetOutput(os.Stdout)
-		log.Println("Set log to stdout")
-	}
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-}
-
-func Port() (string, error) {
-	var envs map[string]string
-	envs, err := godotenv.Read("./info/.env")
+// Structured (JSON) logs go to a rotated file in ./logs in prod, and to stdout otherwise.
+// The returned Closer closes the log file, and should be closed last.
+func InitLogging(cfg *Config) (io.Closer, error) {
+	level, err := cfg.Logging.SlogLevel()
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		return nil, err
 	}
-	port := envs["PORT"]
-	return ":" + port, nil
-}
 
-func DB() string {
-	dbuser := os.Getenv("DBUSER")
-	dbpass := os.Getenv("DBPASS")
-	dbhost := os.Getenv("DBHOST")
-	dbport := os.Getenv("DBPORT")
-	dbname := os.Getenv("DBNAME")
-
-	return dbuser + ":" + dbpass + "@tcp(" + dbhost + ":" + dbport + ")/" + dbname
-}
-
-func ENV(key string) string {
-	return os.Getenv(key)
+	options := logging.Options{
+		Level:      level,
+		MaxSizeMB:  cfg.Logging.MaxSizeMB,
+		MaxAgeDays: cfg.Logging.MaxAgeDays,
+		MaxBackups: cfg.Logging.MaxBackups,
+	}
+	if cfg.Env == "prod" {
+		options.Dir = cfg.Logging.Dir
+	}
+	return logging.Init(options)
 }
@@ -2,21 +2,15 @@ package config
 
 import (
 	"context"
-	"path/filepath"
 
 	firebase "firebase.google.com/go"
 	"firebase.google.com/go/auth"
 	"google.golang.org/api/option"
 )
 
-func SetupFirebase() *auth.Client {
+func SetupFirebase(cfg FirebaseConfig) *auth.Client {
 
-	serviceAccountKeyFilePath, err := filepath.Abs("../backend/credentials/firebase-service-account-key.json")
-	if err != nil {
-		panic("Unable to load serviceAccountKeys.json file")
-	}
-
-	opt := option.WithCredentialsFile(serviceAccountKeyFilePath)
+	opt := option.WithCredentialsFile(cfg.CredentialsFile)
 
 	//Firebase admin SDK initialization
 	app, err := firebase.NewApp(context.Background(), nil, opt)
@@ -31,4 +25,4 @@ func SetupFirebase() *auth.Client {
 	}
 
 	return auth
-}
\ No newline at end of file
+}
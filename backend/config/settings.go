@@ -0,0 +1,368 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Files the configuration is read from. Both can be moved with the matching env variable
+const (
+	defaultEnvFile    = "./info/.env"
+	defaultConfigFile = "./info/config.yaml"
+)
+
+// Config is the whole configuration of the backend. It is loaded once at startup by Load
+// and passed explicitly to the parts that need it.
+//
+// Every setting has a default, can be set in the optional YAML file (yaml key),
+// and overridden by an env variable (env key), either in ./info/.env or in the process environment.
+type Config struct {
+	// "prod" serves the frontend and logs to files
+	Env      string         `yaml:"env" env:"APP_ENV"`
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Casbin   CasbinConfig   `yaml:"casbin"`
+	Firebase FirebaseConfig `yaml:"firebase"`
+	Logging  LoggingConfig  `yaml:"logging"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+}
+
+type ServerConfig struct {
+	Port int `yaml:"port" env:"APIPORT"`
+	// Build of the frontend, served in prod
+	FrontendDir string `yaml:"frontend_dir" env:"FRONTEND_DIR"`
+
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	// How long in-flight requests are given to finish on SIGINT/SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+}
+
+type DatabaseConfig struct {
+	// mysql, postgres or sqlite
+	Driver   string `yaml:"driver" env:"DBDRIVER"`
+	Host     string `yaml:"host" env:"DBHOST"`
+	Port     int    `yaml:"port" env:"DBPORT"`
+	User     string `yaml:"user" env:"DBUSER"`
+	Password string `yaml:"password" env:"DBPASS"`
+	Name     string `yaml:"name" env:"DBNAME"`
+	// postgres only
+	SSLMode string `yaml:"sslmode" env:"DBSSLMODE"`
+	// sqlite only: path of the database file
+	Path string `yaml:"path" env:"DBPATH"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DBMAXOPENCONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DBMAXIDLECONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DBCONNMAXLIFETIME"`
+}
+
+type CasbinConfig struct {
+	ModelFile string `yaml:"model_file" env:"CASBIN_MODEL"`
+	// How often the policy revision is polled, to apply the changes of other instances
+	PolicySyncInterval time.Duration `yaml:"policy_sync_interval" env:"POLICYSYNCINTERVAL"`
+	// Max number of cached authorization decisions
+	DecisionCacheSize int `yaml:"decision_cache_size" env:"AUTHZCACHESIZE"`
+}
+
+type FirebaseConfig struct {
+	CredentialsFile string `yaml:"credentials_file" env:"FIREBASE_CREDENTIALS"`
+}
+
+type LoggingConfig struct {
+	// debug, info, warn or error
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+	// Directory of the log file, in prod (other environments log to stdout)
+	Dir string `yaml:"dir" env:"LOG_DIR"`
+	// The log file is rotated daily, and when it reaches max_size_mb.
+	// Rotated files are deleted after max_age_days, or when there are more than max_backups (0 keeps them all)
+	MaxSizeMB  int `yaml:"max_size_mb" env:"LOG_MAX_SIZE_MB"`
+	MaxAgeDays int `yaml:"max_age_days" env:"LOG_MAX_AGE_DAYS"`
+	MaxBackups int `yaml:"max_backups" env:"LOG_MAX_BACKUPS"`
+	// Log every SQL query (at info level)
+	SQLDebug bool `yaml:"sql_debug" env:"LOG_SQL"`
+	// Queries slower than this are logged as warnings (0 disables it)
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" env:"LOG_SLOW_QUERY"`
+}
+
+type TracingConfig struct {
+	// none, otlp, stdout or file
+	Exporter    string `yaml:"exporter" env:"TRACING_EXPORTER"`
+	ServiceName string `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+	// otlp only: host:port of the collector (OTLP over HTTP), and whether to use plain HTTP
+	Endpoint string `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+	Insecure bool   `yaml:"insecure" env:"TRACING_INSECURE"`
+	// file only: spans are appended to this file as JSON
+	File string `yaml:"file" env:"TRACING_FILE"`
+	// Fraction of the requests traced, between 0 and 1
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+// SlogLevel parses Level
+func (c LoggingConfig) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(c.Level))
+	return level, err
+}
+
+// Default returns the configuration used when nothing is set
+func Default() *Config {
+	return &Config{
+		Env: "dev",
+		Server: ServerConfig{
+			Port:              3031,
+			FrontendDir:       "../frontend/build",
+			ReadTimeout:       30 * time.Second,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:          "mysql",
+			Host:            "localhost",
+			Port:            3306,
+			SSLMode:         "disable",
+			Path:            "./rbac.db",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Casbin: CasbinConfig{
+			ModelFile:          "./config/rbac_model.conf",
+			PolicySyncInterval: 2 * time.Second,
+			DecisionCacheSize:  10000,
+		},
+		Firebase: FirebaseConfig{
+			CredentialsFile: "./credentials/firebase-service-account-key.json",
+		},
+		Logging: LoggingConfig{
+			Level:              "info",
+			Dir:                "./logs",
+			MaxSizeMB:          100,
+			MaxAgeDays:         30,
+			SlowQueryThreshold: 200 * time.Millisecond,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "rbac-backend",
+			Endpoint:    "localhost:4318",
+			File:        "./logs/traces.json",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load reads the configuration (defaults, then the YAML file, then .env and the environment) and validates it.
+// The YAML file is optional, unless CONFIG_FILE points to it explicitly.
+func Load() (*Config, error) {
+	cfg := Default()
+
+	configFile, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		configFile = defaultConfigFile
+	}
+	content, err := ioutil.ReadFile(configFile)
+	switch {
+	case err == nil:
+		if err = yaml.Unmarshal(content, cfg); err != nil {
+			return nil, fmt.Errorf("config: invalid %s: %w", configFile, err)
+		}
+	case explicit || !os.IsNotExist(err):
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	// Variables already in the environment win over .env
+	envFile, explicit := os.LookupEnv("ENV_FILE")
+	if !explicit {
+		envFile = defaultEnvFile
+	}
+	if err = godotenv.Load(envFile); err != nil && (explicit || !os.IsNotExist(err)) {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	if err = applyEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, err
+	}
+	if err = cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ValidationError lists every invalid setting
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks every setting but those of Firebase (see ValidateFirebase),
+// and returns a ValidationError listing all the problems found
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port (APIPORT) must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "server.read_timeout (HTTP_READ_TIMEOUT) must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "server.read_header_timeout (HTTP_READ_HEADER_TIMEOUT) must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout (HTTP_WRITE_TIMEOUT) must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout (HTTP_IDLE_TIMEOUT) must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout (HTTP_SHUTDOWN_TIMEOUT) must be positive")
+
+	db := c.Database
+	switch db.Driver {
+	case "mysql", "postgres":
+		check(db.Host != "", "database.host (DBHOST) is required for %s", db.Driver)
+		check(db.Port > 0 && db.Port < 65536, "database.port (DBPORT) must be between 1 and 65535, got %d", db.Port)
+		check(db.User != "", "database.user (DBUSER) is required for %s", db.Driver)
+		check(db.Name != "", "database.name (DBNAME) is required for %s", db.Driver)
+	case "sqlite":
+		check(db.Path != "", "database.path (DBPATH) is required for sqlite")
+	default:
+		problems = append(problems, fmt.Sprintf("database.driver (DBDRIVER) must be mysql, postgres or sqlite, got %q", db.Driver))
+	}
+	check(db.MaxOpenConns > 0, "database.max_open_conns (DBMAXOPENCONNS) must be positive")
+	check(db.MaxIdleConns >= 0 && db.MaxIdleConns <= db.MaxOpenConns, "database.max_idle_conns (DBMAXIDLECONNS) must be between 0 and max_open_conns")
+	check(db.ConnMaxLifetime > 0, "database.conn_max_lifetime (DBCONNMAXLIFETIME) must be positive")
+
+	check(fileExists(c.Casbin.ModelFile), "casbin.model_file (CASBIN_MODEL): %s does not exist", c.Casbin.ModelFile)
+	check(c.Casbin.PolicySyncInterval > 0, "casbin.policy_sync_interval (POLICYSYNCINTERVAL) must be positive")
+	check(c.Casbin.DecisionCacheSize > 0, "casbin.decision_cache_size (AUTHZCACHESIZE) must be positive")
+
+	_, err := c.Logging.SlogLevel()
+	check(err == nil, "logging.level (LOG_LEVEL) must be debug, info, warn or error, got %q", c.Logging.Level)
+	check(c.Logging.MaxSizeMB > 0, "logging.max_size_mb (LOG_MAX_SIZE_MB) must be positive")
+	check(c.Logging.MaxAgeDays >= 0, "logging.max_age_days (LOG_MAX_AGE_DAYS) must not be negative")
+	check(c.Logging.MaxBackups >= 0, "logging.max_backups (LOG_MAX_BACKUPS) must not be negative")
+	check(c.Logging.SlowQueryThreshold >= 0, "logging.slow_query_threshold (LOG_SLOW_QUERY) must not be negative")
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		check(c.Tracing.Endpoint != "", "tracing.endpoint (TRACING_ENDPOINT) is required for otlp")
+	case "file":
+		check(c.Tracing.File != "", "tracing.file (TRACING_FILE) is required for file")
+	default:
+		problems = append(problems, fmt.Sprintf("tracing.exporter (TRACING_EXPORTER) must be none, otlp, stdout or file, got %q", c.Tracing.Exporter))
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1")
+
+	if problems != nil {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// ValidateFirebase checks the settings of Firebase, which only the commands using it need (not migrate, for instance)
+func (c *Config) ValidateFirebase() error {
+	if !fileExists(c.Firebase.CredentialsFile) {
+		return &ValidationError{Problems: []string{
+			fmt.Sprintf("firebase.credentials_file (FIREBASE_CREDENTIALS): %s does not exist", c.Firebase.CredentialsFile),
+		}}
+	}
+	return nil
+}
+
+// DSN returns the data source name of the configured database, for its driver
+func (c DatabaseConfig) DSN() string {
+	switch c.Driver {
+	case "postgres":
+		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
+			c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode)
+	case "sqlite":
+		// Wait for locks instead of failing, take the write lock when a transaction begins
+		// (so two transactions never deadlock upgrading their read locks), and enforce foreign keys
+		return c.Path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate"
+	default:
+		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=UTC",
+			c.User, c.Password, c.Host, c.Port, c.Name)
+	}
+}
+
+// applyEnv overrides the fields of the struct v with the env variables named by their env tag
+func applyEnv(v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		fieldType := v.Type().Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := fieldType.Tag.Get("env")
+		value, ok := os.LookupEnv(key)
+		if key == "" || !ok {
+			continue
+		}
+
+		switch {
+		case field.Type() == reflect.TypeOf(time.Duration(0)):
+			duration, err := parseDuration(value)
+			if err != nil {
+				return fmt.Errorf("config: %s: %w", key, err)
+			}
+			field.SetInt(int64(duration))
+		case field.Kind() == reflect.Int:
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("config: %s must be a number, got %q", key, value)
+			}
+			field.SetInt(int64(number))
+		case field.Kind() == reflect.Float64:
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("config: %s must be a number, got %q", key, value)
+			}
+			field.SetFloat(number)
+		case field.Kind() == reflect.Bool:
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("config: %s must be true or false, got %q", key, value)
+			}
+			field.SetBool(enabled)
+		case field.Kind() == reflect.String:
+			field.SetString(value)
+		default:
+			return fmt.Errorf("config: unsupported type for %s", key)
+		}
+	}
+	return nil
+}
+
+// parseDuration accepts a number of seconds (as the env variables always did) or a Go duration like "5m"
+func parseDuration(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.New("must be a number of seconds or a duration like 5m, got " + strconv.Quote(value))
+	}
+	return duration, nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -1,10 +0,0 @@
-package database
-
-import (
-	"gorm.io/gorm"
-)
-
-func CloseDBConnectionGorm(db *gorm.DB) {
-	dbConn, _ := db.DB()
-	dbConn.Close()
-}
@@ -0,0 +1,255 @@
+package migrate
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migrations are numbered sql files, one directory per database dialect:
+// migrations/<dialect>/<version>_<name>.up.sql and the matching .down.sql
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a migration and when it was applied (nil if it is pending)
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// schemaMigration is a row of "schema_migrations", recording an applied migration
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// ErrSchemaBehind is returned by Check when some migrations have not been applied
+var ErrSchemaBehind = errors.New("database schema is behind, run \"migrate up\" first")
+
+// Load returns the migrations of the dialect of db, ordered by version
+func Load(db *gorm.DB) ([]Migration, error) {
+	dir := path.Join("migrations", db.Dialector.Name())
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database %q: %w", db.Dialector.Name(), err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		// <version>_<name>.<up|down>.sql
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		direction := path.Ext(name)
+		name = strings.TrimSuffix(name, direction)
+		parts := strings.SplitN(name, "_", 2)
+		if len(parts) != 2 || (direction != ".up" && direction != ".down") {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name %q: %w", entry.Name(), err)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = migration
+		}
+		if direction == ".up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Status returns every migration, with the time it was applied
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	migrations, err := Load(db)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, migration := range migrations {
+		statuses[i] = MigrationStatus{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// Up applies all pending migrations in order and returns them.
+// Each migration runs in a transaction on postgres and sqlite. MySQL commits DDL statements implicitly,
+// so there a migration failing halfway is not rolled back: it is reported with the failing statement,
+// and must be fixed by hand before running Up again.
+func Up(db *gorm.DB) ([]Migration, error) {
+	statuses, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			continue
+		}
+		err = inTransaction(db, func(tx *gorm.DB) error {
+			if err := execute(tx, status.Version, status.Name, status.Up); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: status.Version, Name: status.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return done, err
+		}
+		done = append(done, status.Migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first, and returns them
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	statuses, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(statuses) - 1; i >= 0 && len(done) < steps; i-- {
+		status := statuses[i]
+		if status.AppliedAt == nil {
+			continue
+		}
+		err = inTransaction(db, func(tx *gorm.DB) error {
+			if err := execute(tx, status.Version, status.Name, status.Down); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, status.Version).Error
+		})
+		if err != nil {
+			return done, err
+		}
+		done = append(done, status.Migration)
+	}
+	return done, nil
+}
+
+// Check returns ErrSchemaBehind if some migrations have not been applied
+func Check(db *gorm.DB) error {
+	statuses, err := Status(db)
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, fmt.Sprintf("%d_%s", status.Version, status.Name))
+		}
+	}
+	if pending != nil {
+		return fmt.Errorf("%w (pending: %s)", ErrSchemaBehind, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+func appliedMigrations(db *gorm.DB) (map[int64]schemaMigration, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		if err := db.Migrator().CreateTable(&schemaMigration{}); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// inTransaction runs fn in a transaction, unless the database cannot roll back DDL statements (mysql)
+func inTransaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if db.Dialector.Name() == "mysql" {
+		return fn(db)
+	}
+	return db.Transaction(fn)
+}
+
+// execute runs the statements of a migration file one by one
+func execute(db *gorm.DB, version int64, name string, script string) error {
+	for _, statement := range statements(script) {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("migration %d_%s failed on %q: %w", version, name, statement, err)
+		}
+	}
+	return nil
+}
+
+// statements splits a migration file on the semicolons ending a line, skipping "--" comments
+func statements(script string) []string {
+	var result []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			result = append(result, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		result = append(result, rest)
+	}
+	return result
+}
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS firebase_outbox;
+DROP TABLE IF EXISTS casbin_rule;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS customer_user;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS employees;
+DROP TABLE IF EXISTS customers;
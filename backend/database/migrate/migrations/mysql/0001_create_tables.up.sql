@@ -0,0 +1,78 @@
+-- Tables as previously created by gorm's AutoMigrate and the casbin adapter.
+-- IF NOT EXISTS lets databases created before versioned migrations adopt this schema as is.
+
+CREATE TABLE IF NOT EXISTS customers (
+    id bigint NOT NULL AUTO_INCREMENT,
+    full_name longtext,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS employees (
+    id bigint NOT NULL AUTO_INCREMENT,
+    full_name longtext,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id varchar(191) NOT NULL,
+    email longtext,
+    creation_timestamp bigint,
+    last_login_timestamp bigint,
+    employee_id bigint NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS customer_user (
+    customer_id bigint NOT NULL,
+    user_id varchar(191) NOT NULL,
+    PRIMARY KEY (customer_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+    role varchar(191) NOT NULL,
+    description longtext,
+    PRIMARY KEY (role)
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id bigint NOT NULL AUTO_INCREMENT,
+    action longtext,
+    resource longtext,
+    description longtext,
+    category longtext,
+    category_no bigint,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS casbin_rule (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    ptype varchar(100),
+    v0 varchar(100),
+    v1 varchar(100),
+    v2 varchar(100),
+    v3 varchar(100),
+    v4 varchar(100),
+    v5 varchar(100),
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_casbin_rule (ptype, v0, v1, v2, v3, v4, v5)
+);
+
+CREATE TABLE IF NOT EXISTS firebase_outbox (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    operation varchar(32),
+    uid varchar(128),
+    email longtext,
+    password_hash longtext,
+    idempotency_key varchar(191),
+    status varchar(16),
+    attempts bigint,
+    last_error longtext,
+    next_attempt_at datetime(3) NULL,
+    created_at datetime(3) NULL,
+    updated_at datetime(3) NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_firebase_outbox_idempotency_key (idempotency_key),
+    INDEX idx_firebase_outbox_uid (uid),
+    INDEX idx_firebase_outbox_status (status),
+    INDEX idx_firebase_outbox_next_attempt_at (next_attempt_at)
+);
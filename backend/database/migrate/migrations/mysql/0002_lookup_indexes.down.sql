@@ -0,0 +1,4 @@
+DROP INDEX idx_casbin_rule_v1 ON casbin_rule;
+DROP INDEX idx_users_email ON users;
+DROP INDEX idx_users_employee_id ON users;
+DROP INDEX idx_customer_user_user_id ON customer_user;
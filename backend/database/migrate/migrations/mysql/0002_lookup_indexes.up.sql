@@ -0,0 +1,11 @@
+-- Customers of a user (the primary key only covers lookups by customer)
+CREATE INDEX idx_customer_user_user_id ON customer_user (user_id);
+
+-- Users of an employee
+CREATE INDEX idx_users_employee_id ON users (employee_id);
+
+-- Users by email (firebase sync, unassigned users)
+CREATE INDEX idx_users_email ON users (email(191));
+
+-- Users of a role and rules of an object (the unique index only covers lookups by subject)
+CREATE INDEX idx_casbin_rule_v1 ON casbin_rule (ptype, v1);
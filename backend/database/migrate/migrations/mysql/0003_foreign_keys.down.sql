@@ -0,0 +1,6 @@
+ALTER TABLE customer_user
+    DROP FOREIGN KEY fk_customer_user_user_id,
+    DROP FOREIGN KEY fk_customer_user_customer_id;
+
+ALTER TABLE users
+    DROP FOREIGN KEY fk_users_employee;
@@ -0,0 +1,11 @@
+-- Remove rows left behind by deletes that were not atomic, so that the constraints can be added
+DELETE FROM customer_user WHERE customer_id NOT IN (SELECT id FROM customers);
+DELETE FROM customer_user WHERE user_id NOT IN (SELECT id FROM users);
+UPDATE users SET employee_id = NULL WHERE employee_id IS NOT NULL AND employee_id NOT IN (SELECT id FROM employees);
+
+ALTER TABLE users
+    ADD CONSTRAINT fk_users_employee FOREIGN KEY (employee_id) REFERENCES employees (id) ON DELETE SET NULL;
+
+ALTER TABLE customer_user
+    ADD CONSTRAINT fk_customer_user_customer_id FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_customer_user_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
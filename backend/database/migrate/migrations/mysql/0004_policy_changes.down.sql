@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS casbin_policy_changes;
+DROP TABLE IF EXISTS casbin_policy_revision;
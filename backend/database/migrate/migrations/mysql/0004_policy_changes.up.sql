@@ -0,0 +1,22 @@
+-- Policy revision counter: bumped (and so locked) by every transaction changing casbin rules,
+-- which makes changes commit in revision order
+CREATE TABLE casbin_policy_revision (
+    id int NOT NULL,
+    revision bigint NOT NULL,
+    PRIMARY KEY (id)
+);
+INSERT INTO casbin_policy_revision (id, revision) VALUES (1, 0);
+
+-- Log of the changes, replayed by the other instances
+CREATE TABLE casbin_policy_changes (
+    revision bigint NOT NULL,
+    instance varchar(64),
+    operation varchar(32),
+    sec varchar(8),
+    ptype varchar(8),
+    field_index bigint,
+    rules longtext,
+    created_at datetime(3) NULL,
+    PRIMARY KEY (revision),
+    INDEX idx_casbin_policy_changes_created_at (created_at)
+);
@@ -0,0 +1,2 @@
+-- Emails are kept in lower case, so that lookups by email compare them with "=" and use idx_users_email
+UPDATE users SET email = LOWER(email);
@@ -0,0 +1,4 @@
+DROP INDEX idx_casbin_rule_v1;
+DROP INDEX idx_users_email;
+DROP INDEX idx_users_employee_id;
+DROP INDEX idx_customer_user_user_id;
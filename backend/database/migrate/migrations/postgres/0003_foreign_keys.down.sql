@@ -0,0 +1,6 @@
+ALTER TABLE customer_user
+    DROP CONSTRAINT fk_customer_user_user_id,
+    DROP CONSTRAINT fk_customer_user_customer_id;
+
+ALTER TABLE users
+    DROP CONSTRAINT fk_users_employee;
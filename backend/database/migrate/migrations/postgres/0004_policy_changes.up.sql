@@ -0,0 +1,20 @@
+-- Policy revision counter: bumped (and so locked) by every transaction changing casbin rules,
+-- which makes changes commit in revision order
+CREATE TABLE casbin_policy_revision (
+    id integer PRIMARY KEY,
+    revision bigint NOT NULL
+);
+INSERT INTO casbin_policy_revision (id, revision) VALUES (1, 0);
+
+-- Log of the changes, replayed by the other instances
+CREATE TABLE casbin_policy_changes (
+    revision bigint PRIMARY KEY,
+    instance varchar(64),
+    operation varchar(32),
+    sec varchar(8),
+    ptype varchar(8),
+    field_index bigint,
+    rules text,
+    created_at timestamptz
+);
+CREATE INDEX idx_casbin_policy_changes_created_at ON casbin_policy_changes (created_at);
@@ -0,0 +1,2 @@
+-- The original case of the emails is lost: nothing to roll back
+SELECT 1;
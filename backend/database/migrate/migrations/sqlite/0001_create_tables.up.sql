@@ -0,0 +1,68 @@
+CREATE TABLE IF NOT EXISTS customers (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    full_name text
+);
+
+CREATE TABLE IF NOT EXISTS employees (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    full_name text
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id text PRIMARY KEY,
+    email text,
+    creation_timestamp integer,
+    last_login_timestamp integer,
+    employee_id integer NULL
+);
+
+CREATE TABLE IF NOT EXISTS customer_user (
+    customer_id integer NOT NULL,
+    user_id text NOT NULL,
+    PRIMARY KEY (customer_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+    role text PRIMARY KEY,
+    description text
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    action text,
+    resource text,
+    description text,
+    category text,
+    category_no integer
+);
+
+CREATE TABLE IF NOT EXISTS casbin_rule (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    ptype varchar(100),
+    v0 varchar(100),
+    v1 varchar(100),
+    v2 varchar(100),
+    v3 varchar(100),
+    v4 varchar(100),
+    v5 varchar(100)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_casbin_rule ON casbin_rule (ptype, v0, v1, v2, v3, v4, v5);
+
+CREATE TABLE IF NOT EXISTS firebase_outbox (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    operation varchar(32),
+    uid varchar(128),
+    email text,
+    password_hash text,
+    idempotency_key varchar(191),
+    status varchar(16),
+    attempts integer,
+    last_error text,
+    next_attempt_at datetime,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_firebase_outbox_idempotency_key ON firebase_outbox (idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_firebase_outbox_uid ON firebase_outbox (uid);
+CREATE INDEX IF NOT EXISTS idx_firebase_outbox_status ON firebase_outbox (status);
+CREATE INDEX IF NOT EXISTS idx_firebase_outbox_next_attempt_at ON firebase_outbox (next_attempt_at);
@@ -0,0 +1,22 @@
+CREATE TABLE customer_user_new (
+    customer_id integer NOT NULL,
+    user_id text NOT NULL,
+    PRIMARY KEY (customer_id, user_id)
+);
+INSERT INTO customer_user_new SELECT customer_id, user_id FROM customer_user;
+DROP TABLE customer_user;
+ALTER TABLE customer_user_new RENAME TO customer_user;
+CREATE INDEX idx_customer_user_user_id ON customer_user (user_id);
+
+CREATE TABLE users_new (
+    id text PRIMARY KEY,
+    email text,
+    creation_timestamp integer,
+    last_login_timestamp integer,
+    employee_id integer NULL
+);
+INSERT INTO users_new SELECT id, email, creation_timestamp, last_login_timestamp, employee_id FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+CREATE INDEX idx_users_employee_id ON users (employee_id);
+CREATE INDEX idx_users_email ON users (email);
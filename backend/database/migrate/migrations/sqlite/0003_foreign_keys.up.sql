@@ -0,0 +1,32 @@
+-- Remove rows left behind by deletes that were not atomic, so that the constraints can be added
+DELETE FROM customer_user WHERE customer_id NOT IN (SELECT id FROM customers);
+DELETE FROM customer_user WHERE user_id NOT IN (SELECT id FROM users);
+UPDATE users SET employee_id = NULL WHERE employee_id IS NOT NULL AND employee_id NOT IN (SELECT id FROM employees);
+
+-- SQLite cannot add constraints to a table, so the tables are rebuilt with them
+
+CREATE TABLE users_new (
+    id text PRIMARY KEY,
+    email text,
+    creation_timestamp integer,
+    last_login_timestamp integer,
+    employee_id integer NULL,
+    CONSTRAINT fk_users_employee FOREIGN KEY (employee_id) REFERENCES employees (id) ON DELETE SET NULL
+);
+INSERT INTO users_new SELECT id, email, creation_timestamp, last_login_timestamp, employee_id FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+CREATE INDEX idx_users_employee_id ON users (employee_id);
+CREATE INDEX idx_users_email ON users (email);
+
+CREATE TABLE customer_user_new (
+    customer_id integer NOT NULL,
+    user_id text NOT NULL,
+    PRIMARY KEY (customer_id, user_id),
+    CONSTRAINT fk_customer_user_customer_id FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE CASCADE,
+    CONSTRAINT fk_customer_user_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+INSERT INTO customer_user_new SELECT customer_id, user_id FROM customer_user;
+DROP TABLE customer_user;
+ALTER TABLE customer_user_new RENAME TO customer_user;
+CREATE INDEX idx_customer_user_user_id ON customer_user (user_id);
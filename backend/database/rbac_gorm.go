@@ -1,20 +0,0 @@
-package database
-
-import (
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
-	"os"
-)
-
-func ConnectToRBACGorm() (*gorm.DB, error) {
-	dbHost := os.Getenv("DBHOST")
-	dbPort := os.Getenv("DBPORT")
-	dbName := os.Getenv("DBNAME")
-	dbUser := os.Getenv("DBUSER")
-	dbPass := os.Getenv("DBPASS")
-
-	dsn := dbUser + ":" + dbPass + "@tcp(" + dbHost + ":" + dbPort + ")/" + dbName + "?charset=utf8mb4&parseTime=True&loc=UTC"
-	db, err := gorm.Open(mysql.Open(dsn))
-
-	return db, err
-}
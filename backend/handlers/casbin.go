@@ -1,28 +1,36 @@
 package handlers
 
 import (
-	"github.com/casbin/casbin/v2"
+	"backend/apierror"
+	"backend/authz"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
 
-func GetFrontendPermission(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
+func GetFrontendPermission(decisions *authz.DecisionCache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		// Get current user/subject
 		firebaseUUID, existed := c.Get("UUID")
 
 		if !existed {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "No subject found"})
+			apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "No subject found"))
 			return
 		}
 
-		permissions, err := enforcer.GetImplicitPermissionsForUser(firebaseUUID.(string))
+		permissions, err := decisions.ImplicitPermissions(c.Request.Context(), firebaseUUID.(string))
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Could not find permissions for user"})
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
 
 		c.JSON(http.StatusOK, permissions)
 	}
 }
+
+// GetDecisionCacheStats returns the hit/miss counters of the authorization decision cache
+func GetDecisionCacheStats(decisions *authz.DecisionCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, decisions.Stats())
+	}
+}
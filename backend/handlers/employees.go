@@ -1,85 +1,50 @@
 package handlers
 
 import (
-	db "backend/database"
+	"backend/apierror"
 	"backend/models"
-	"context"
-	"firebase.google.com/go/auth"
-	"fmt"
-	"github.com/casbin/casbin/v2"
+	"backend/service"
 	"github.com/gin-gonic/gin"
-	"log"
 	"net/http"
-	"strings"
 )
 
-func GetAllEmployees() gin.HandlerFunc {
+// GetAllEmployees returns a page of employees.
+// Query: keyword, page, page_size, sort (id, full_name) and filter, on the fields of store.EmployeeFilters:
+// id, full_name and has_users (e.g. "has_users=false")
+func GetAllEmployees(employees *service.Employees) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		//Get filters from frontend
+		//Get filters, page and sort from frontend
 		var filters models.Filter
-		if err := c.Bind(&filters); err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+		if err := c.ShouldBindQuery(&filters); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
+		// Select the page of employees
+		result, total, err := employees.List(c.Request.Context(), filters)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, err)
 			return
 		}
-		defer db.CloseDBConnectionGorm(database)
 
-		var employees []models.Employee
-
-		// Select all employees
-		err = database.Debug().Select("id", "full_name").Where("id LIKE ? OR full_name LIKE ?", "%"+filters.Keyword+"%", "%"+filters.Keyword+"%").Find(&employees).Error
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-
-		c.JSON(http.StatusOK, employees)
+		c.JSON(http.StatusOK, models.NewPage(result, total, filters))
 	}
 }
 
-func AddEmployee() gin.HandlerFunc {
+func AddEmployee(employees *service.Employees) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var employee models.Employee
-		if err := c.ShouldBindJSON(&employee); err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-
-		// Make sure given employee name is not empty
-		if strings.TrimSpace(employee.FullName) == "" {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Make sure all fields are filled in correctly!", "type": "warning"})
+		var requestBody models.AddEmployeeRequest
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-		defer db.CloseDBConnectionGorm(database)
+		var employee = models.Employee{FullName: requestBody.FullName}
 
-		//var employee = models.Employee{FullName: requestBody.FullName}
 		// Add a new employee to employees table in database
-		err = database.Debug().Create(&employee).Error
+		err := employees.Create(c.Request.Context(), &employee)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-			log.Println(err.Error())
+			apierror.Abort(c, err)
 			return
 		}
 
@@ -87,92 +52,19 @@ func AddEmployee() gin.HandlerFunc {
 	}
 }
 
-func DeleteEmployee(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
+func DeleteEmployee(employees *service.Employees) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var employee models.Employee
 		err := c.ShouldBindUri(&employee)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-		defer db.CloseDBConnectionGorm(database)
-
-		var users []models.User
-		// Fetch all users associated with this employee id in "users" table
-		err = database.Debug().Model(&employee).Association("Users").Find(&users)
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		// If employee was associated with a user
-		if len(users) > 0 {
-			// Clear employee's associations from "users" table (sets employee_id as null)
-			err = database.Debug().Model(&employee).Association("Users").Clear()
-			if err != nil {
-				c.AbortWithError(http.StatusInternalServerError, err)
-				log.Println(err)
-				return
-			}
-			// Delete users from "users" table
-			err = database.Debug().Delete(&users).Error
-			if err != nil {
-				c.AbortWithError(http.StatusInternalServerError, err)
-				log.Println(err)
-				return
-			}
-
-			//
-			// Delete user's (or users') permissions from "casbin_rule" table in DB, using casbin
-			// Also delete user's (or users') from firebase
-			//
-			// Initialize firebaseAuth
-			firebaseAuth := c.MustGet("firebaseAuth").(*auth.Client)
-
-			// Foreach user of deleted customer
-			for _, user := range users {
-				// Delete user from "casbin_rule"
-				_, err = enforcer.DeleteUser(user.Id)
-				if err != nil {
-					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-					log.Println(err)
-					return
-				}
-
-				// Delete user from firebase
-				err = firebaseAuth.DeleteUser(context.Background(), user.Id)
-				if err != nil {
-					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-					log.Println(err)
-					return
-				}
-			}
-
-		}
-
-		// Delete employee with id = employeeId, from "employees" table
-		// First way
-		//err = database.Debug().Delete(&models.Employee{Id: employeeId}).Error
-		err = database.Debug().Delete(&employee).Error
-		// Second way
-		//err = database.Debug().Delete(&models.Employee{}, employeeId).Error
-		// Third way
-		//err = database.Debug().Where("id = ?", employee.Id).Delete(&models.Employee{}).Error
+		// Delete employee with its users
+		err = employees.Delete(c.Request.Context(), employee.Id)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, err)
 			return
 		}
 
@@ -180,34 +72,20 @@ func DeleteEmployee(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
 	}
 }
 
-func UpdateEmployee() gin.HandlerFunc {
+func UpdateEmployee(employees *service.Employees) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var employee models.Employee
-		if err := c.ShouldBindJSON(&employee); err != nil {
-			log.Println(err.Error())
-			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
-				"error":   true,
-				"message": fmt.Sprintf("Invalid request body: %s", err.Error()),
-			})
+		var requestBody models.UpdateEmployeeRequest
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-		defer db.CloseDBConnectionGorm(database)
+		var employee = models.Employee{Id: requestBody.Id, FullName: requestBody.FullName}
 
-		// Update customer's name
-		err = database.Debug().Save(&employee).Error
+		// Update employee's name
+		err := employees.Rename(c.Request.Context(), &employee)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-			log.Println(err.Error())
+			apierror.Abort(c, err)
 			return
 		}
 
@@ -215,42 +93,19 @@ func UpdateEmployee() gin.HandlerFunc {
 	}
 }
 
-func GetEmployeeUsers() gin.HandlerFunc {
+func GetEmployeeUsers(employees *service.Employees) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var employee models.Employee
 		err := c.ShouldBindUri(&employee)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-		defer db.CloseDBConnectionGorm(database)
-
-		// Initialize return data
-		var associatedUsers []struct {
-			ID    string `json:"id"`
-			Email string `json:"email"`
-		}
-
 		// Get all users associated with this employee
-		err = database.Debug().Table("users").
-			Joins("JOIN employees ON users.employee_id = employees.id").
-			Select("users.id, users.email").
-			Where("employees.id = ?", employee.Id).
-			Find(&associatedUsers).Error
+		associatedUsers, err := employees.Users(c.Request.Context(), employee.Id)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, err)
 			return
 		}
 
@@ -1,98 +1,50 @@
 package handlers
 
 import (
-	db "backend/database"
+	"backend/apierror"
 	"backend/models"
-	"context"
-	"firebase.google.com/go/auth"
-	"github.com/casbin/casbin/v2"
+	"backend/service"
 	"github.com/gin-gonic/gin"
-	"log"
 	"net/http"
 )
 
-func GetAllFirebaseUsers() gin.HandlerFunc {
+// GetAllFirebaseUsers returns a page of users.
+// Query: keyword, page, page_size, sort (id, email) and filter, on the fields of store.UserFilters:
+// id, email, email_domain, role, has_employee, employee_id, customer_id, created_after, created_before,
+// last_login_after and last_login_before (e.g. "customer_id=1996 and last_login_before=2023-01-01")
+func GetAllFirebaseUsers(users *service.Users) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		//Get filters from frontend
+		//Get filters, page and sort from frontend
 		var filters models.Filter
-		if err := c.Bind(&filters); err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+		if err := c.ShouldBindQuery(&filters); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
+		// Select the page of users
+		result, total, err := users.List(c.Request.Context(), filters)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, err)
 			return
 		}
-		defer db.CloseDBConnectionGorm(database)
 
-		var users []models.User
-
-		// Select all customers
-		err = database.Debug().Select("id", "email").Where("id LIKE ? OR email LIKE ?", "%"+filters.Keyword+"%", "%"+filters.Keyword+"%").Find(&users).Error
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-
-		c.JSON(http.StatusOK, users)
+		c.JSON(http.StatusOK, models.NewPage(result, total, filters))
 	}
 }
 
-func AddFirebaseUser() gin.HandlerFunc {
+func AddFirebaseUser(users *service.Users) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Initialize user to add with given email and password
-		var requestBody struct {
-			Email    string `json:"email"`
-			Password string `json:"password"`
-		}
+		var requestBody models.AddFirebaseUserRequest
 		if err := c.ShouldBindJSON(&requestBody); err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
+		// Add user to users table in database, and queue its creation in firebase
+		_, err := users.Create(c.Request.Context(), requestBody.Email, requestBody.Password)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-		defer db.CloseDBConnectionGorm(database)
-
-		// Initialize firebaseAuth
-		firebaseAuth := c.MustGet("firebaseAuth").(*auth.Client)
-
-		// Create new user in firebase with the following parameters
-		params := (&auth.UserToCreate{}).
-			Email(requestBody.Email).
-			Password(requestBody.Password).
-			Disabled(false)
-		// If email already exists in firebase, return error
-		firebaseUser, err := firebaseAuth.CreateUser(context.Background(), params)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": "User already exists in Firebase! Please use another email."})
-			log.Println(err)
-			return
-		}
-
-		// Add user to users table in database
-		var user = models.User{Id: firebaseUser.UserInfo.UID, Email: requestBody.Email}
-
-		err = database.Debug().Create(&user).Error
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-			log.Println(err.Error())
+			apierror.Abort(c, err)
 			return
 		}
 
@@ -100,61 +52,20 @@ func AddFirebaseUser() gin.HandlerFunc {
 	}
 }
 
-func DeleteFirebaseUser(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
+func DeleteFirebaseUser(users *service.Users) gin.HandlerFunc {
 	return func(c *gin.Context) {
-
 		// Bind from url, passing url's passed id directly to a user object with id = url's passed id
 		var user models.User
 		err := c.ShouldBindUri(&user)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-		defer db.CloseDBConnectionGorm(database)
-
-		// Remove all user's permissions from casbin rule
-		_, err = enforcer.DeleteUser(user.Id)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-			log.Println(err.Error())
-			return
-		}
-
-		//Delete all user's associations, if exists, from "customer_user" table in DB
-		err = database.Debug().Model(&user).Association("Customers").Clear()
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-
-		// Delete user from "users" table
-		// In this way employee-user association is deleted too, if exists
-		err = database.Debug().Delete(&user).Error
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		// Initialize firebaseAuth
-		firebaseAuth := c.MustGet("firebaseAuth").(*auth.Client)
-		// Delete user from firebase
-		err = firebaseAuth.DeleteUser(context.Background(), user.Id)
+		// Delete user, and queue its deletion from firebase
+		err = users.Delete(c.Request.Context(), user.Id)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-			log.Println(err)
+			apierror.Abort(c, err)
 			return
 		}
 
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// healthCheckTimeout bounds each dependency check of the readiness probe
+const healthCheckTimeout = 3 * time.Second
+
+// HealthCheck is a dependency checked by the readiness probe
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// DependencyStatus is the outcome of one HealthCheck
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Readiness is the report of the readiness probe: "ready" or "not_ready", and the status of each dependency
+type Readiness struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyStatus `json:"checks"`
+}
+
+// Healthz reports that the process is alive (for the systemd watchdog)
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz runs every check concurrently, and reports each dependency with its latency.
+// It answers 503 if any dependency is down, so the load balancer stops sending traffic.
+func Readyz(checks ...HealthCheck) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statuses := make(map[string]DependencyStatus, len(checks))
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+
+		for _, check := range checks {
+			wg.Add(1)
+			go func(check HealthCheck) {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+				defer cancel()
+
+				start := time.Now()
+				err := check.Check(ctx)
+				status := DependencyStatus{Status: "up", LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+				if err != nil {
+					status.Status = "down"
+					status.Error = err.Error()
+				}
+
+				mu.Lock()
+				statuses[check.Name] = status
+				mu.Unlock()
+			}(check)
+		}
+		wg.Wait()
+
+		code, overall := http.StatusOK, "ready"
+		for _, status := range statuses {
+			if status.Status != "up" {
+				code, overall = http.StatusServiceUnavailable, "not_ready"
+			}
+		}
+		c.JSON(code, Readiness{Status: overall, Checks: statuses})
+	}
+}
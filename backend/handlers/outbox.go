@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// GetOutboxOperations returns a page of the firebase operations of the outbox, optionally filtered by ?status=
+// (pending, processing, done or failed), so admins can see stuck operations.
+// Query: page, page_size, sort (id, status, operation, attempts, created_at; newest first by default)
+// and filter, on the fields of store.OutboxFilters: status, operation, created_after and created_before
+func GetOutboxOperations(outbox *service.Outbox) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filters models.OutboxFilter
+		if err := c.ShouldBindQuery(&filters); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		operations, total, err := outbox.List(c.Request.Context(), filters.Status, filters.Filter)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.NewPage(operations, total, filters.Filter))
+	}
+}
+
+// RetryOutboxOperation queues a failed (or stuck) operation again, with a fresh set of attempts
+func RetryOutboxOperation(outbox *service.Outbox) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			apierror.Abort(c, apierror.BadRequest("Invalid operation id.").Wrap(err))
+			return
+		}
+
+		err = outbox.Retry(c.Request.Context(), uint(id))
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, nil)
+	}
+}
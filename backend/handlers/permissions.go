@@ -1,16 +1,14 @@
 package handlers
 
 import (
-	db "backend/database"
+	"backend/apierror"
 	"backend/models"
-	"fmt"
-	"github.com/casbin/casbin/v2"
+	"backend/service"
 	"github.com/gin-gonic/gin"
-	"log"
 	"net/http"
 )
 
-func GetPermissionsForRole(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
+func GetPermissionsForRole(permissions *service.Permissions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		//
 		// ** SOS **
@@ -28,106 +26,54 @@ func GetPermissionsForRole(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
 		//
 		var role models.Role
 		if err := c.Bind(&role); err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
+		userPermissionsObject, err := permissions.ForRole(c.Request.Context(), role.Role)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, err)
 			return
 		}
-		defer db.CloseDBConnectionGorm(database)
-
-		permissionsForUser := enforcer.GetPermissionsForUser(role.Role)
-		fmt.Println(permissionsForUser)
-
-		var permissions []models.Permission
-
-		// Select all permissions (order by category)
-		err = database.Debug().Order("category").Find(&permissions).Error
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-
-		//fmt.Println(permissions)
-		var userPermissionsObject models.FrontendPolicy
-		userPermissionsObject = make(map[string][]models.PermissionInfo)
-		for _, globalPerm := range permissions {
-
-			var userHasPermission bool
-			for _, userPerm := range permissionsForUser {
-				if globalPerm.Action == userPerm[2] && globalPerm.Resource == userPerm[1] {
-					userHasPermission = true
-					break
-				} else {
-					userHasPermission = false
-				}
-			}
-
-			userPermissionsObject[globalPerm.Category] = append(userPermissionsObject[globalPerm.Category],
-				models.PermissionInfo{
-					PermissionId:          globalPerm.Id,
-					PermissionDescription: globalPerm.Description,
-					PermissionAction:      globalPerm.Action,
-					PermissionResource:    globalPerm.Resource,
-					HasPermission:         userHasPermission,
-				},
-			)
-		}
 
 		c.JSON(http.StatusOK, userPermissionsObject)
 	}
 }
 
-func AddPermission(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
+func AddPermission(permissions *service.Permissions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		//From postman's Body/form-data
-		var requestBody struct {
-			NewRole      string `json:"newRole"`
-			NewData      string `json:"newData"`
-			NewPrivilege string `json:"newPrivilege"`
-		}
+		var requestBody models.AddPermissionRequest
 		if err := c.ShouldBindJSON(&requestBody); err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
 		//Add policy
-		_, err := enforcer.AddPolicy(requestBody.NewRole, requestBody.NewData, requestBody.NewPrivilege)
+		err := permissions.Add(c.Request.Context(), requestBody.NewRole, requestBody.NewData, requestBody.NewPrivilege)
 		if err != nil {
-			log.Println(err.Error())
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to create casbin enforcer"})
+			apierror.Abort(c, err)
 			return
 		}
 
 	}
 }
 
-func DeletePermission(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
+func DeletePermission(permissions *service.Permissions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		//From postman's Body/form-data
-		var requestBody struct {
-			Role      string `json:"role"`
-			Data      string `json:"data"`
-			Privilege string `json:"privilege"`
-		}
+		var requestBody models.DeletePermissionRequest
 		if err := c.ShouldBindJSON(&requestBody); err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
 		//Remove policy
-		enforcer.RemovePolicy(requestBody.Role, requestBody.Data, requestBody.Privilege)
+		err := permissions.Remove(c.Request.Context(), requestBody.Role, requestBody.Data, requestBody.Privilege)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
 
 	}
 }
@@ -1,97 +1,59 @@
 package handlers
 
 import (
-	db "backend/database"
+	"backend/apierror"
 	"backend/models"
-	"fmt"
-	"github.com/casbin/casbin/v2"
+	"backend/service"
 	"github.com/gin-gonic/gin"
-	"log"
 	"net/http"
 )
 
-func UpdateRole(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
+func UpdateRole(roles *service.Roles) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		//From postman's Body/form-data
-		var requestBody struct {
-			UserId  string `json:"id"`
-			NewRole string `json:"role"`
-		}
+		var requestBody models.UpdateRoleRequest
 		if err := c.ShouldBindJSON(&requestBody); err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		//From db
-		oldRole, err := enforcer.GetRolesForUser(requestBody.UserId)
-		if err != nil {
-			panic(fmt.Sprintf("failed to get roles for user %s: %v", requestBody.UserId, err))
-		}
-
-		if len(oldRole) == 0 {
-			enforcer.AddGroupingPolicy(requestBody.UserId, requestBody.NewRole)
-		} else {
-			enforcer.UpdateGroupingPolicy([]string{requestBody.UserId, oldRole[0]}, []string{requestBody.UserId, requestBody.NewRole})
+		if err := roles.Assign(c.Request.Context(), requestBody.UserId, requestBody.NewRole); err != nil {
+			apierror.Abort(c, err)
+			return
 		}
 	}
 }
 
-func GetAllRoles() gin.HandlerFunc {
+func GetAllRoles(roles *service.Roles) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-		defer db.CloseDBConnectionGorm(database)
-
-		var roles []models.Role
-
 		// Select all roles
-		err = database.Debug().Find(&roles).Error
+		result, err := roles.List(c.Request.Context())
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, err)
 			return
 		}
 
-		c.JSON(http.StatusOK, roles)
+		c.JSON(http.StatusOK, result)
 	}
 }
 
-func AddRole() gin.HandlerFunc {
+func AddRole(roles *service.Roles) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		//Initialize role with given parameters from postman's Body/form-data
-		var role models.Role
-		if err := c.ShouldBindJSON(&role); err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+		var requestBody models.AddRoleRequest
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-		defer db.CloseDBConnectionGorm(database)
+		var role = models.Role{Role: requestBody.Role, Description: requestBody.Description}
 
 		// Add new role or update customer's name
-		err = database.Debug().Save(&role).Error
+		err := roles.Save(c.Request.Context(), &role)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-			log.Println(err.Error())
+			apierror.Abort(c, err)
 			return
 		}
 
@@ -99,59 +61,19 @@ func AddRole() gin.HandlerFunc {
 	}
 }
 
-func DeleteRole(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
+func DeleteRole(roles *service.Roles) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		//From postman's Body/form-data
-		var requestBody struct {
-			Role string `json:"role"`
-		}
+		var requestBody models.DeleteRoleRequest
 		if err := c.ShouldBindJSON(&requestBody); err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-
-		var role = models.Role{Role: requestBody.Role}
-
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-		defer db.CloseDBConnectionGorm(database)
-
-		//Get number of users with this role from "cabin_rule" table in DB
-		var countUsersWithRole int64
-
-		err = database.Model(&models.CasbinRule{}).Where(&models.CasbinRule{V1: role.Role}).Count(&countUsersWithRole).Error
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-
-		if countUsersWithRole != 0 {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Please remove this role from all users before deleting it"})
-			return
-		}
-
-		//Delete from "role" table in DB
-		err = database.Delete(&role).Error
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-			log.Println(err)
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		//Delete from "casbin_rule" table in DB
-		_, err = enforcer.RemoveFilteredPolicy(0, role.Role)
+		// Delete the role and its permissions, unless users still have it
+		err := roles.Delete(c.Request.Context(), requestBody.Role)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-			log.Println(err)
+			apierror.Abort(c, err)
 			return
 		}
 
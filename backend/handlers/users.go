@@ -1,41 +1,20 @@
 package handlers
 
 import (
-	db "backend/database"
+	"backend/apierror"
 	"backend/models"
-	"firebase.google.com/go/auth"
+	"backend/service"
 	"github.com/gin-gonic/gin"
-	"google.golang.org/api/iterator"
-	"log"
 	"net/http"
 )
 
-func GetUnassignedUsers() gin.HandlerFunc {
+func GetUnassignedUsers(users *service.Users) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-		defer db.CloseDBConnectionGorm(database)
-
 		// Initialize return data
-		var unassignedUserEmails []string
-
-		err = database.
-			Debug().
-			Table("users").
-			Where("employee_id IS NULL AND users.id NOT IN ( SELECT user_id FROM customer_user )").
-			Select("email").
-			Scan(&unassignedUserEmails).Error
+		unassignedUserEmails, err := users.UnassignedEmails(c.Request.Context())
 		if err != nil {
-			log.Println(err.Error())
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			apierror.Abort(c, err)
 			return
 		}
 
@@ -43,32 +22,13 @@ func GetUnassignedUsers() gin.HandlerFunc {
 	}
 }
 
-func GetUsersEmails() gin.HandlerFunc {
+func GetUsersEmails(users *service.Users) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-		defer db.CloseDBConnectionGorm(database)
-
 		// Initialize return data
-		var userEmails []string
-
-		err = database.
-			Debug().
-			Table("users").
-			Where("users.employee_id IS NULL AND users.email NOT LIKE '%@digitalminds.com%'").
-			Select("email").
-			Scan(&userEmails).Error
+		userEmails, err := users.CustomerEmails(c.Request.Context())
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
-			log.Println(err.Error())
+			apierror.Abort(c, err)
 			return
 		}
 
@@ -76,86 +36,38 @@ func GetUsersEmails() gin.HandlerFunc {
 	}
 }
 
-func GetAllUsers() gin.HandlerFunc {
+// GetAllUsers returns a page of the users assigned to an employee, with their role.
+// Query: keyword, page, page_size, sort (id, full_name, email, role) and filter, on the fields of store.UserFilters
+// (e.g. "role=admin and email_domain=example.com")
+func GetAllUsers(users *service.Users) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		//Get filters from frontend
+		//Get filters, page and sort from frontend
 		var filters models.Filter
-		if err := c.Bind(&filters); err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+		if err := c.ShouldBindQuery(&filters); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
 			return
 		}
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
-		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
-			return
-		}
-		defer db.CloseDBConnectionGorm(database)
-
-		var users []struct {
-			ID       string `json:"id"`
-			FullName string `json:"full_name"`
-			Email    string `json:"email"`
-			Role     string `json:"role"`
-		}
-
 		// Get all employees and their roles (excluding those who haven't been assigned a firebase user yet)
-		err = database.Debug().Table("employees").Joins("JOIN users ON users.employee_id = employees.id").
-			Joins("LEFT JOIN casbin_rule ON casbin_rule.v0 = users.id").
-			Select("users.id, employees.full_name, users.email, casbin_rule.v1 AS role").
-			Where("users.id LIKE ? OR employees.full_name LIKE ? OR users.email LIKE ? OR casbin_rule.v1 LIKE ?", "%"+filters.Keyword+"%", "%"+filters.Keyword+"%", "%"+filters.Keyword+"%", "%"+filters.Keyword+"%").
-			Find(&users).Error
+		result, total, err := users.ListWithRoles(c.Request.Context(), filters)
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, err)
 			return
 		}
 
-		c.JSON(http.StatusOK, users)
+		c.JSON(http.StatusOK, models.NewPage(result, total, filters))
 	}
 }
 
-func SyncUsersWithFirebase() gin.HandlerFunc {
+func SyncUsersWithFirebase(users *service.Users) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
-		//
-		// Connect to RBAC Database (for gorm queries)
-		//
-		database, err := db.ConnectToRBACGorm()
+		// Copy firebase users to the database
+		err := users.SyncWithFirebase(c.Request.Context())
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
-			log.Println(err)
+			apierror.Abort(c, err)
 			return
 		}
-		defer db.CloseDBConnectionGorm(database)
-
-		// iterate firebase users
-		// Note, behind the scenes, the Roles() iterator will retrieve 1000 Roles at a time through the API
-		firebaseAuth := c.MustGet("firebaseAuth").(*auth.Client)
-
-		iter := firebaseAuth.Users(c, "")
-		for {
-			user, err := iter.Next()
-			if err == iterator.Done {
-				break
-			}
-			if err != nil {
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch users from Firebase"})
-				return
-			}
-
-			// Update users
-			err = database.Debug().Omit("EmployeeID").Save(&models.User{Id: user.UID, Email: user.Email, CreationTimestamp: int(user.UserMetadata.CreationTimestamp), LastLoginTimestamp: int(user.UserMetadata.LastLogInTimestamp)}).Error
-			if err != nil {
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch users from Firebase"})
-				return
-			}
-		}
 
 		c.JSON(http.StatusOK, nil)
 
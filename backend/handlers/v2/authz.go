@@ -0,0 +1,47 @@
+package v2
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// CheckAuthorization decides whether a subject can do an action on an object, and explains the decision
+func CheckAuthorization(authorization *service.Authorization) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var check models.AuthzCheck
+		if err := c.ShouldBindJSON(&check); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		decision, err := authorization.Check(check)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, decision)
+	}
+}
+
+// CheckAuthorizations decides a batch of checks, in order
+func CheckAuthorizations(authorization *service.Authorization) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var checks models.AuthzChecks
+		if err := c.ShouldBindJSON(&checks); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		decisions, err := authorization.CheckAll(checks.Checks)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, decisions)
+	}
+}
@@ -0,0 +1,165 @@
+package v2
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func ListCustomers(customers *service.Customers) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filters models.Filter
+		if err := c.ShouldBindQuery(&filters); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		result, total, err := customers.List(c.Request.Context(), filters)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.NewPage(result, total, filters))
+	}
+}
+
+func CreateCustomer(customers *service.Customers) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var requestBody models.AddCustomerRequest
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		var customer = models.Customer{Id: requestBody.Id, FullName: requestBody.FullName}
+		if err := customers.Create(c.Request.Context(), &customer); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, customer)
+	}
+}
+
+func RenameCustomer(customers *service.Customers) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.CustomerPath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+		var requestBody models.RenameRequest
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		var customer = models.Customer{Id: path.Id, FullName: requestBody.FullName}
+		if err := customers.Rename(c.Request.Context(), &customer); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, customer)
+	}
+}
+
+// DeleteCustomer deletes the customer, and the users associated only with it
+func DeleteCustomer(customers *service.Customers) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.CustomerPath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := customers.Delete(c.Request.Context(), path.Id); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// ListCustomerUsers returns the users of the customer, with their access to its data
+func ListCustomerUsers(customers *service.Customers) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.CustomerPath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		users, err := customers.Users(c.Request.Context(), path.Id)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, users)
+	}
+}
+
+// AddCustomerUser associates the user of the email of the body with the customer
+func AddCustomerUser(customers *service.Customers) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.CustomerPath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+		var requestBody models.AssociateUserRequest
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := customers.AddUser(c.Request.Context(), path.Id, requestBody.Email); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// DeleteCustomerUser dissociates the user from the customer (deleting the user if it has no other customer)
+func DeleteCustomerUser(customers *service.Customers) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.CustomerUserPath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := customers.RemoveUser(c.Request.Context(), path.Id, path.UserId); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// SetCustomerUserAccess gives (PUT, hasAccess) or takes (DELETE) the access of the user
+// to the finance or performance data of the customer
+func SetCustomerUserAccess(customers *service.Customers, hasAccess bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.CustomerAccessPath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := customers.SetAccess(c.Request.Context(), path.Id, path.UserId, path.Object, hasAccess); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
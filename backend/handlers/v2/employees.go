@@ -0,0 +1,146 @@
+package v2
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func ListEmployees(employees *service.Employees) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filters models.Filter
+		if err := c.ShouldBindQuery(&filters); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		result, total, err := employees.List(c.Request.Context(), filters)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.NewPage(result, total, filters))
+	}
+}
+
+// CreateEmployee adds an employee, and returns it with the id given by the database
+func CreateEmployee(employees *service.Employees) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var requestBody models.AddEmployeeRequest
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		var employee = models.Employee{FullName: requestBody.FullName}
+		if err := employees.Create(c.Request.Context(), &employee); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, employee)
+	}
+}
+
+func RenameEmployee(employees *service.Employees) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.EmployeePath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+		var requestBody models.RenameRequest
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		var employee = models.Employee{Id: path.Id, FullName: requestBody.FullName}
+		if err := employees.Rename(c.Request.Context(), &employee); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, employee)
+	}
+}
+
+// DeleteEmployee deletes the employee with its users
+func DeleteEmployee(employees *service.Employees) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.EmployeePath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := employees.Delete(c.Request.Context(), path.Id); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func ListEmployeeUsers(employees *service.Employees) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.EmployeePath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		users, err := employees.Users(c.Request.Context(), path.Id)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, users)
+	}
+}
+
+// AddEmployeeUser assigns the user of the email of the body to the employee
+func AddEmployeeUser(employees *service.Employees) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.EmployeePath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+		var requestBody models.AssociateUserRequest
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := employees.AddUser(c.Request.Context(), path.Id, requestBody.Email); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// DeleteEmployeeUser deletes the user of the employee
+func DeleteEmployeeUser(employees *service.Employees) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.EmployeeUserPath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := employees.RemoveUser(c.Request.Context(), path.Id, path.UserId); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,44 @@
+package v2
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/service"
+	"backend/spreadsheet"
+	"bytes"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// Export renders the export name as JSON, or as the attachment of a CSV or XLSX file (?format=csv|xlsx)
+func Export(exports *service.Exports, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var options models.ExportOptions
+		if err := c.ShouldBindQuery(&options); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		export, err := exports.Export(c.Request.Context(), name)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		if options.Format == "" || options.Format == models.ExportJSON {
+			c.JSON(http.StatusOK, export.Data)
+			return
+		}
+
+		// The file is written before the response, so that an error can still be rendered
+		var file bytes.Buffer
+		if err = spreadsheet.Write(&file, options.Format, name, export.Rows); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, name, time.Now().Format("2006-01-02"), options.Format))
+		c.Data(http.StatusOK, spreadsheet.ContentType(options.Format), file.Bytes())
+	}
+}
@@ -0,0 +1,78 @@
+package v2
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/service"
+	"backend/spreadsheet"
+	"github.com/gin-gonic/gin"
+	"mime/multipart"
+	"net/http"
+)
+
+// Import imports customers, employees and customer users from the CSV or XLSX files of a multipart form.
+// The report of a dry run (or of an invalid import, in the details of its error) shows what would change
+func Import(imports *service.Imports) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var options models.ImportOptions
+		if err := c.ShouldBindQuery(&options); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+		var files models.ImportFiles
+		if err := c.ShouldBind(&files); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		sheets, err := readImportFiles(files)
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		report, err := imports.Import(c.Request.Context(), sheets, options)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// readImportFiles reads the sheets of the workbook, and the file of each sheet
+func readImportFiles(files models.ImportFiles) ([]spreadsheet.Sheet, error) {
+	var sheets []spreadsheet.Sheet
+	if files.Workbook != nil {
+		file, err := files.Workbook.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		if sheets, err = spreadsheet.ReadWorkbook(file, files.Workbook.Filename); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, header := range map[string]*multipart.FileHeader{
+		models.ImportCustomers:     files.Customers,
+		models.ImportEmployees:     files.Employees,
+		models.ImportCustomerUsers: files.CustomerUsers,
+	} {
+		if header == nil {
+			continue
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		sheet, err := spreadsheet.ReadSheet(file, header.Filename, name)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets, nil
+}
@@ -0,0 +1,45 @@
+package v2
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func ListOutboxOperations(outbox *service.Outbox) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filters models.OutboxFilter
+		if err := c.ShouldBindQuery(&filters); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		operations, total, err := outbox.List(c.Request.Context(), filters.Status, filters.Filter)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.NewPage(operations, total, filters.Filter))
+	}
+}
+
+// RetryOutboxOperation queues a failed (or stuck) operation again, with a fresh set of attempts
+func RetryOutboxOperation(outbox *service.Outbox) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.OutboxOperationPath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := outbox.Retry(c.Request.Context(), path.Id); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
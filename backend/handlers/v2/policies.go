@@ -0,0 +1,91 @@
+package v2
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/policyfile"
+	"backend/service"
+	"bytes"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// ExportPolicy renders the casbin rules as JSON, or as the attachment of a CSV (casbin format) or YAML file
+func ExportPolicy(policies *service.Policies) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var options models.PolicyExportOptions
+		if err := c.ShouldBindQuery(&options); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+		if options.Format == "" {
+			options.Format = models.PolicyJSON
+		}
+
+		rules, err := policies.Rules(options.Filter())
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		var file bytes.Buffer
+		if err = policyfile.Write(&file, options.Format, rules); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+		if options.Format != models.PolicyJSON {
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="policy-%s.%s"`, time.Now().Format("2006-01-02"), options.Format))
+		}
+		c.Data(http.StatusOK, policyfile.ContentType(options.Format), file.Bytes())
+	}
+}
+
+// ImportPolicy replaces the casbin rules (those matching the filter of the query) with the rules of the file of a multipart form,
+// and renders the diff. With dry_run, only the diff is computed
+func ImportPolicy(policies *service.Policies) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var options models.PolicyImportOptions
+		if err := c.ShouldBindQuery(&options); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+		var form models.PolicyFile
+		if err := c.ShouldBind(&form); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		rules, err := readPolicyFile(form)
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		diff, err := policies.Import(c.Request.Context(), rules, options)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, diff)
+	}
+}
+
+func readPolicyFile(form models.PolicyFile) ([]models.PolicyRule, error) {
+	format, err := policyfile.FormatOf(form.File.Filename)
+	if err != nil {
+		return nil, err
+	}
+	file, err := form.File.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	rules, err := policyfile.Read(file, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", form.File.Filename, err)
+	}
+	return rules, nil
+}
@@ -0,0 +1,157 @@
+// Package v2 holds the handlers of /api/v2, whose routes identify the resources by their path
+// (e.g. DELETE /roles/{role} instead of DELETE /roles/ with a JSON body).
+// They share the service layer with the handlers of v1.
+package v2
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func ListRoles(roles *service.Roles) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := roles.List(c.Request.Context())
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// CreateRole adds a role, or updates its description
+func CreateRole(roles *service.Roles) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var requestBody models.AddRoleRequest
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		var role = models.Role{Role: requestBody.Role, Description: requestBody.Description}
+		if err := roles.Save(c.Request.Context(), &role); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, role)
+	}
+}
+
+func DeleteRole(roles *service.Roles) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.RolePath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := roles.Delete(c.Request.Context(), path.Role); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// ListRoleAssignments returns a page of the users assigned to an employee, with their role
+func ListRoleAssignments(users *service.Users) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filters models.Filter
+		if err := c.ShouldBindQuery(&filters); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		result, total, err := users.ListWithRoles(c.Request.Context(), filters)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.NewPage(result, total, filters))
+	}
+}
+
+// AssignRole sets the role of the user of the path
+func AssignRole(roles *service.Roles) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.UserPath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+		var requestBody models.AssignRoleRequest
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := roles.Assign(c.Request.Context(), path.UserId, requestBody.Role); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// GetRolePermissions returns every permission by category, and whether the role of the path has it
+func GetRolePermissions(permissions *service.Permissions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.RolePath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		result, err := permissions.ForRole(c.Request.Context(), path.Role)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// AddRolePermission gives the permission of the path to its role. Giving it twice is harmless
+func AddRolePermission(permissions *service.Permissions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.RolePermissionPath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := permissions.Add(c.Request.Context(), path.Role, path.Resource, path.Action); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// DeleteRolePermission takes the permission of the path from its role
+func DeleteRolePermission(permissions *service.Permissions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.RolePermissionPath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := permissions.Remove(c.Request.Context(), path.Role, path.Resource, path.Action); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,102 @@
+package v2
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func ListUsers(users *service.Users) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filters models.Filter
+		if err := c.ShouldBindQuery(&filters); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		result, total, err := users.List(c.Request.Context(), filters)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.NewPage(result, total, filters))
+	}
+}
+
+// ListUnassignedEmails returns the emails of the users assigned to neither an employee nor a customer
+func ListUnassignedEmails(users *service.Users) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		emails, err := users.UnassignedEmails(c.Request.Context())
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, emails)
+	}
+}
+
+// ListCustomerEmails returns the emails of the users that can be associated with a customer
+func ListCustomerEmails(users *service.Users) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		emails, err := users.CustomerEmails(c.Request.Context())
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, emails)
+	}
+}
+
+// CreateUser saves the user, and answers 202: it is created in firebase by the outbox worker
+func CreateUser(users *service.Users) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var requestBody models.AddFirebaseUserRequest
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		user, err := users.Create(c.Request.Context(), requestBody.Email, requestBody.Password)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, user)
+	}
+}
+
+// DeleteUser deletes the user (it is deleted from firebase by the outbox worker)
+func DeleteUser(users *service.Users) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var path models.UserPath
+		if err := c.ShouldBindUri(&path); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+			return
+		}
+
+		if err := users.Delete(c.Request.Context(), path.UserId); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// SyncUsers copies the users of firebase to the database
+func SyncUsers(users *service.Users) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := users.SyncWithFirebase(c.Request.Context()); err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
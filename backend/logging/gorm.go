@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// GormLogger logs gorm's queries through slog, with the attributes of the request running them.
+// Failed and slow queries are always logged. Every query is logged only if SQL debugging is on.
+type GormLogger struct {
+	SQLDebug bool
+	// Queries slower than SlowThreshold are logged as warnings. 0 disables it
+	SlowThreshold time.Duration
+}
+
+// LogMode is part of gorm's logger.Interface. An Info level (like db.Debug()) turns SQL debugging on
+func (l GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	l.SQLDebug = level >= gormlogger.Info
+	return l
+}
+
+func (l GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	slog.InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	slog.WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	slog.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	slow := l.SlowThreshold > 0 && elapsed > l.SlowThreshold
+
+	var level slog.Level
+	var msg string
+	switch {
+	// A missing record is an expected outcome, not a failure
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelError, "query failed"
+	case slow:
+		level, msg = slog.LevelWarn, "slow query"
+	case l.SQLDebug:
+		// Logged at info level, so turning SQL debugging on is enough to see the queries
+		level, msg = slog.LevelInfo, "query"
+	default:
+		return
+	}
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+
+	// The source is the code running the query, not this logger
+	sql, rows := fc()
+	record := slog.NewRecord(time.Now(), level, msg, 0)
+	record.AddAttrs(
+		slog.String("caller", utils.FileWithLineNum()),
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Duration("elapsed", elapsed),
+	)
+	if err != nil {
+		record.AddAttrs(slog.String("error", err.Error()))
+	}
+	_ = slog.Default().Handler().Handle(ctx, record)
+}
@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Options of the structured logger set up by Init
+type Options struct {
+	Level slog.Level
+	// Directory of the log file. When empty, logs go to stdout
+	Dir string
+	// The file is rotated when it reaches MaxSizeMB, and every day.
+	// Rotated files are deleted after MaxAgeDays, and when there are more than MaxBackups (0 keeps them all)
+	MaxSizeMB  int
+	MaxAgeDays int
+	MaxBackups int
+}
+
+// Init sets the default slog logger (also used by the standard "log" package) to a JSON logger,
+// which adds the attributes of the request (see With) to every line logged with a context
+func Init(options Options) (io.Closer, error) {
+	var out io.WriteCloser = nopCloser{os.Stdout}
+	if options.Dir != "" {
+		if err := os.MkdirAll(options.Dir, 0700); err != nil {
+			return nil, err
+		}
+		out = newDailyRotator(&lumberjack.Logger{
+			Filename:   filepath.Join(options.Dir, "rbac.log"),
+			MaxSize:    options.MaxSizeMB,
+			MaxAge:     options.MaxAgeDays,
+			MaxBackups: options.MaxBackups,
+			LocalTime:  true,
+		})
+	}
+
+	handler := slog.NewJSONHandler(out, &slog.HandlerOptions{AddSource: true, Level: options.Level})
+	slog.SetDefault(slog.New(contextHandler{handler}))
+	return out, nil
+}
+
+type attrsKey struct{}
+
+// With returns a copy of ctx carrying attrs, which are added to every line logged with it
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	// Never append in place: the parent context keeps its own attributes
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+// contextHandler adds the attributes carried by the context to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+		record.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Error logs err for the request of c, with the request's attributes, and the handler's file and line as source
+func Error(c *gin.Context, err error) {
+	ErrorSkip(c, err, 1)
+}
+
+// ErrorSkip is Error for helpers logging on behalf of their caller: the source is skip more frames up the stack
+func ErrorSkip(c *gin.Context, err error, skip int) {
+	ctx := c.Request.Context()
+	logger := slog.Default()
+	if !logger.Enabled(ctx, slog.LevelError) {
+		return
+	}
+
+	// Skip runtime.Callers, ErrorSkip itself, and skip callers
+	var pcs [1]uintptr
+	runtime.Callers(2+skip, pcs[:])
+	record := slog.NewRecord(time.Now(), slog.LevelError, err.Error(), pcs[0])
+	record.AddAttrs(slog.String("route", c.FullPath()))
+	_ = logger.Handler().Handle(ctx, record)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID, from the client (or a proxy) if it sets one, and back in the response
+const RequestIDHeader = "X-Request-ID"
+
+// Middleware tags the request with a request ID, and logs it once it has been handled
+func Middleware(c *gin.Context) {
+	start := time.Now()
+
+	requestID := c.GetHeader(RequestIDHeader)
+	if requestID == "" || len(requestID) > 64 {
+		requestID = newRequestID()
+	}
+	c.Header(RequestIDHeader, requestID)
+	c.Request = c.Request.WithContext(With(c.Request.Context(), slog.String("request_id", requestID)))
+
+	c.Next()
+
+	// c.Request also carries the attributes added by the next handlers (like the user's uid)
+	level := slog.LevelInfo
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	slog.Log(c.Request.Context(), level, "request",
+		slog.String("method", c.Request.Method),
+		slog.String("path", c.Request.URL.Path),
+		slog.String("route", c.FullPath()),
+		slog.Int("status", c.Writer.Status()),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("client_ip", c.ClientIP()),
+		slog.Int("size", c.Writer.Size()),
+	)
+}
+
+// SetUser attaches the uid of the authenticated Firebase user to every line logged for the request of c
+func SetUser(c *gin.Context, uid string) {
+	c.Request = c.Request.WithContext(With(c.Request.Context(), slog.String("uid", uid)))
+}
+
+func newRequestID() string {
+	id := make([]byte, 8)
+	// crypto/rand does not fail in practice. A zero id only makes the request harder to trace
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,36 @@
+package logging
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// dailyRotator rotates the file of a lumberjack logger (rotated by size) at midnight too,
+// so that every file covers a single day at most
+type dailyRotator struct {
+	*lumberjack.Logger
+
+	mu  sync.Mutex
+	day string
+}
+
+func newDailyRotator(logger *lumberjack.Logger) *dailyRotator {
+	return &dailyRotator{Logger: logger, day: today()}
+}
+
+func (r *dailyRotator) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	if day := today(); day != r.day {
+		r.day = day
+		// On failure, keep writing to the current file
+		_ = r.Logger.Rotate()
+	}
+	r.mu.Unlock()
+	return r.Logger.Write(p)
+}
+
+func today() string {
+	return time.Now().Format("2006-01-02")
+}
@@ -2,26 +2,74 @@ package main
 
 import (
 	"backend/config"
-	"backend/database/migrate"
-	"backend/models"
-	"backend/routes"
-	"github.com/joho/godotenv"
+	"backend/logging"
+	"backend/store"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"os"
 )
 
+const usage = `usage:
+  backend [serve]             start the server (until SIGINT/SIGTERM)
+  backend migrate up          apply all pending migrations
+  backend migrate down [n]    roll back the last n migrations (default 1)
+  backend migrate status      list migrations and when they were applied`
+
 func main() {
+	// Configuration, from ./info/config.yaml (optional), ./info/.env and the environment
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
 	//     LOGGING
-	config.InitLogging()
+	logs, err := config.InitLogging(cfg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	command := "serve"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+	if command != "serve" && command != "migrate" {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	// Only the server uses Firebase
+	if command == "serve" {
+		if err = cfg.ValidateFirebase(); err != nil {
+			exit(logs, err)
+		}
+	}
 
-	//ENV
-	err := godotenv.Load("./info/.env")
+	// Open the one pooled connection shared by the whole backend
+	st, err := store.Open(cfg.Database, logging.GormLogger{SQLDebug: cfg.Logging.SQLDebug, SlowThreshold: cfg.Logging.SlowQueryThreshold})
 	if err != nil {
-		log.Println("Error loading env in main")
+		exit(logs, err)
+	}
+
+	if command == "migrate" {
+		err = runMigrate(st, os.Args[2:])
+	} else {
+		err = serve(cfg, st)
 	}
 
-	// migrate db model to updated (only when gorm is used)
-	migrate.MigrateDBGorm()
+	// The pool is closed last, once the server and the background workers have stopped
+	if closeErr := st.Close(); closeErr != nil {
+		slog.Error("failed to close the database", slog.String("error", closeErr.Error()))
+	}
+	if err != nil {
+		exit(logs, err)
+	}
+	logs.Close()
+}
 
-	db, _ := models.DBConnection()
-	routes.SetupRoutes(db)
+// exit logs err, closes the log file and exits with status 1
+func exit(logs io.Closer, err error) {
+	slog.Error(err.Error())
+	logs.Close()
+	os.Exit(1)
 }
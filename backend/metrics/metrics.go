@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"backend/tracing"
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// All metrics are prefixed with namespace, and registered in prometheus' default registry
+const namespace = "rbac"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests, by route group, method and status code.",
+	}, []string{"group", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the HTTP requests, by route group and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"group", "method"})
+
+	authzDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "authz_decisions_total",
+		Help:      "Authorization decisions of the Authorize middleware, by object, action and decision (allow, deny or error).",
+	}, []string{"obj", "act", "decision"})
+
+	policyReloadDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "casbin_policy_reload_duration_seconds",
+		Help:      "Duration of the full reloads of the casbin policy.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+
+	firebaseDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "firebase_call_duration_seconds",
+		Help:      "Latency of the Firebase Admin calls, by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	firebaseErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "firebase_call_errors_total",
+		Help:      "Failed Firebase Admin calls, by operation.",
+	}, []string{"operation"})
+)
+
+// Handler serves the metrics in prometheus' text format
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// HTTP is a middleware counting the requests, and timing them, per route group.
+// The group is the segment after "/api" of the matched route ("/api/users/emails" is "users").
+func HTTP(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	group := routeGroup(c.FullPath())
+	httpRequests.WithLabelValues(group, c.Request.Method, statusClass(c.Writer.Status())).Inc()
+	httpDuration.WithLabelValues(group, c.Request.Method).Observe(time.Since(start).Seconds())
+}
+
+// routeGroup returns the group of route. Unmatched routes (mostly the frontend) are grouped together,
+// so that arbitrary paths cannot create new series
+func routeGroup(route string) string {
+	if route == "" {
+		return "unmatched"
+	}
+	segments := strings.Split(strings.Trim(route, "/"), "/")
+	if segments[0] == "api" && len(segments) > 1 && segments[1] != "" {
+		return segments[1]
+	}
+	return segments[0]
+}
+
+func statusClass(status int) string {
+	switch {
+	case status < 200:
+		return "1xx"
+	case status < 300:
+		return "2xx"
+	case status < 400:
+		return "3xx"
+	case status < 500:
+		return "4xx"
+	default:
+		return "5xx"
+	}
+}
+
+// AuthzDecision counts a decision of the Authorize middleware
+func AuthzDecision(obj string, act string, allowed bool, err error) {
+	decision := "deny"
+	if err != nil {
+		decision = "error"
+	} else if allowed {
+		decision = "allow"
+	}
+	authzDecisions.WithLabelValues(obj, act, decision).Inc()
+}
+
+// PolicyReloaded records the duration of a full policy reload which started at start
+func PolicyReloaded(start time.Time) {
+	policyReloadDuration.Observe(time.Since(start).Seconds())
+}
+
+// Firebase times call, a Firebase Admin operation, counts its failure, and traces it (as a child of the span in ctx).
+// Errors for which expected returns true (e.g. a user not found, when checking whether it exists) are not failures.
+func Firebase(ctx context.Context, operation string, call func() error, expected ...func(error) bool) error {
+	_, span := tracing.Start(ctx, "firebase."+operation)
+	start := time.Now()
+	err := call()
+	firebaseDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	failure := err
+	for _, isExpected := range expected {
+		if err != nil && isExpected(err) {
+			failure = nil
+		}
+	}
+	if failure != nil {
+		firebaseErrors.WithLabelValues(operation).Inc()
+	}
+	tracing.End(span, failure)
+	return err
+}
+
+// RegisterEnforcer exports the number of rules in the policy of enforcer, by type ("p" for permissions, "g" for roles)
+func RegisterEnforcer(enforcer *casbin.SyncedEnforcer) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "casbin_policy_rules",
+		Help:        "Rules in the in-memory casbin policy, by type.",
+		ConstLabels: prometheus.Labels{"ptype": "p"},
+	}, func() float64 {
+		rules, _ := enforcer.GetPolicy()
+		return float64(len(rules))
+	})
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "casbin_policy_rules",
+		Help:        "Rules in the in-memory casbin policy, by type.",
+		ConstLabels: prometheus.Labels{"ptype": "g"},
+	}, func() float64 {
+		rules, _ := enforcer.GetGroupingPolicy()
+		return float64(len(rules))
+	})
+}
+
+// RegisterDB exports the statistics of the connection pool of db
+func RegisterDB(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "rbac"))
+}
@@ -1,41 +1,39 @@
 package middleware
 
 import (
+	"backend/apierror"
+	"backend/authz"
+	"backend/metrics"
 	"fmt"
-	"github.com/casbin/casbin/v2"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
 
 // Authorize determines if current user has been authorized to take an action on an object.
-func Authorize(obj string, act string, enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
+func Authorize(obj string, act string, decisions *authz.DecisionCache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get current user/subject
 		firebaseUUID, existed := c.Get("UUID")
 
 		if !existed {
 			// Always return a "message"!
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "User hasn't logged in yet"})
+			apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "User hasn't logged in yet"))
 			return
 		}
 
-		// Load policy from Database
-		err := enforcer.LoadPolicy()
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to load policy from DB"})
-			return
-		}
-
-		// Casbin enforces policy
-		ok, err := enforcer.Enforce(fmt.Sprint(firebaseUUID), obj, act)
+		// Casbin enforces policy (through the decision cache)
+		// (the in-memory policy is kept up to date by the policy watcher, see store.PolicyWatcher)
+		ok, err := decisions.Enforce(c.Request.Context(), fmt.Sprint(firebaseUUID), obj, act)
+		metrics.AuthzDecision(obj, act, ok, err)
 
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Error occurred when authorizing user"})
+			apierror.Abort(c, fmt.Errorf("error occurred when authorizing user: %w", err))
 			return
 		}
 
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "You are not authorized"})
+			apierror.Abort(c, apierror.New(http.StatusForbidden, apierror.CodeForbidden, "You are not authorized").
+				WithDetails(gin.H{"obj": obj, "act": act}))
 			return
 		}
 		c.Next()
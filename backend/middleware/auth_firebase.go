@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"backend/apierror"
+	"backend/logging"
+	"backend/metrics"
 	"context"
 	"firebase.google.com/go/auth"
 	"github.com/gin-gonic/gin"
@@ -16,19 +19,22 @@ func AuthMiddleware(c *gin.Context) {
 	idToken := strings.TrimSpace(strings.Replace(authorizationToken, "Bearer", "", 1))
 
 	if idToken == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Id token not available"})
-		c.Abort()
+		apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "Id token not available"))
 		return
 	}
 
 	//verify token
-	token, err := firebaseAuth.VerifyIDToken(context.Background(), idToken)
+	var token *auth.Token
+	err := metrics.Firebase(c.Request.Context(), "verify_id_token", func() (err error) {
+		token, err = firebaseAuth.VerifyIDToken(context.Background(), idToken)
+		return err
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
-		c.Abort()
+		apierror.Abort(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "invalid token").Wrap(err))
 		return
 	}
 
 	c.Set("UUID", token.UID)
+	logging.SetUser(c, token.UID)
 	c.Next()
 }
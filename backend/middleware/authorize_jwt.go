@@ -1,8 +1,9 @@
 package middleware
 
 import (
+	"backend/apierror"
 	"backend/utils"
-	"fmt"
+	"log/slog"
 	"net/http"
 
 	"github.com/dgrijalva/jwt-go"
@@ -14,29 +15,27 @@ func AuthorizeJWT() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		const BearerSchema string = "Bearer "
 		authHeader := ctx.GetHeader("Authorization")
-		if authHeader == "" {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "No Authorization header found"})
-
+		if len(authHeader) < len(BearerSchema) {
+			apierror.Abort(ctx, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "No Authorization header found"))
+			return
 		}
 		tokenString := authHeader[len(BearerSchema):]
 
 		if token, err := utils.ValidateToken(tokenString); err != nil {
 
-			fmt.Println("token", tokenString, err.Error())
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Not Valid Token"})
+			slog.WarnContext(ctx.Request.Context(), "invalid JWT", slog.Any("error", err))
+			apierror.Abort(ctx, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "Not Valid Token"))
 
 		} else {
 
 			if claims, ok := token.Claims.(jwt.MapClaims); !ok {
-				ctx.AbortWithStatus(http.StatusUnauthorized)
+				apierror.Abort(ctx, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "Not Valid Token"))
 
 			} else {
 				if token.Valid {
 					ctx.Set("userID", claims["userID"])
 				} else {
-					ctx.AbortWithStatus(http.StatusUnauthorized)
+					apierror.Abort(ctx, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "Not Valid Token"))
 				}
 
 			}
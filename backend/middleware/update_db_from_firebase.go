@@ -1,23 +1,18 @@
 package middleware
 
 import (
-	db "backend/database"
+	"backend/apierror"
+	"backend/models"
+	"backend/store"
 	"firebase.google.com/go/auth"
 	"github.com/gin-gonic/gin"
 	"google.golang.org/api/iterator"
-	"log"
 	"net/http"
 )
 
-func UpdateUsersDB() gin.HandlerFunc {
+func UpdateUsersDB(users store.UserStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
-		db, err := db.Connect()
-		if err != nil {
-			log.Fatalln(err.Error())
-		}
-		defer db.Close()
-
 		// iterate firebase users
 		// Note, behind the scenes, the Roles() iterator will retrieve 1000 Roles at a time through the API
 		firebaseAuth := c.MustGet("firebaseAuth").(*auth.Client)
@@ -30,17 +25,16 @@ func UpdateUsersDB() gin.HandlerFunc {
 			}
 			if err != nil {
 				//log.Fatalf("error listing users: %s\n", err)
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch users from Firebase"})
+				apierror.Abort(c, apierror.New(http.StatusBadGateway, apierror.CodeFirebaseUnavailable, "Failed to fetch users from Firebase").Wrap(err))
 				return
 			}
 			//log.Printf("read user user: %v\n", user.DisplayName)
 
-			_, err = db.Exec("REPLACE INTO users (id, email, creation_timestamp, last_login_timestamp) VALUES (?, ?, ?, ?)", user.UID, user.Email, user.UserMetadata.CreationTimestamp, user.UserMetadata.LastLogInTimestamp)
+			err = users.Upsert(c.Request.Context(), &models.User{Id: user.UID, Email: user.Email, CreationTimestamp: int(user.UserMetadata.CreationTimestamp), LastLoginTimestamp: int(user.UserMetadata.LastLogInTimestamp)})
 			if err != nil {
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to fetch users from Firebase"})
+				apierror.Abort(c, err)
 				return
 			}
-			//log.Println(result)
 		}
 
 	}
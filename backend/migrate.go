@@ -0,0 +1,56 @@
+package main
+
+import (
+	"backend/database/migrate"
+	"backend/store"
+	"fmt"
+	"strconv"
+)
+
+// runMigrate runs the "migrate" subcommand
+func runMigrate(st *store.Store, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n%s", usage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrate.Up(st.DB())
+		for _, migration := range applied {
+			fmt.Printf("applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		rolledBack, err := migrate.Down(st.DB(), steps)
+		for _, migration := range rolledBack {
+			fmt.Printf("rolled back %d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrate.Status(st.DB())
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d_%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], usage)
+	}
+}
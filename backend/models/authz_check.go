@@ -0,0 +1,29 @@
+package models
+
+// AuthzCheck asks whether a subject (a user id, or a role) can do an action on an object
+type AuthzCheck struct {
+	Subject string `json:"subject" binding:"required"`
+	Object  string `json:"object" binding:"required"`
+	Action  string `json:"action" binding:"required"`
+}
+
+// AuthzChecks are the checks of a batch
+type AuthzChecks struct {
+	Checks []AuthzCheck `json:"checks" binding:"required,min=1,max=100,dive"`
+}
+
+// AuthzDecision is the decision of a check, and why
+type AuthzDecision struct {
+	Subject string `json:"subject"`
+	Object  string `json:"object"`
+	Action  string `json:"action"`
+	Allowed bool   `json:"allowed"`
+	// Policy is the policy rule (subject, object, action) allowing the request, empty if denied
+	Policy []string `json:"policy"`
+	// RolePath leads from the subject to the subject of Policy through the role rules, like [uid, admin]
+	RolePath []string `json:"role_path"`
+	// Roles are every role of the subject, direct or inherited
+	Roles []string `json:"roles"`
+	// ObjectPolicies are the policy rules of the object and action, whoever their subject: those a denied subject lacks
+	ObjectPolicies [][]string `json:"object_policies"`
+}
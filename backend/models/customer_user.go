@@ -0,0 +1,15 @@
+package models
+
+// CustomerUser is a user associated with a customer, along with their access to the customer's data
+type CustomerUser struct {
+	Id                   string `json:"id" db:"id"`
+	Email                string `json:"email" db:"email"`
+	HasPerformanceAccess bool   `json:"has_performance_access"`
+	HasFinancialAccess   bool   `json:"has_financial_access"`
+}
+
+// Objects of the data of a customer, a user can be given access to
+const (
+	AccessFinance     = "finance"
+	AccessPerformance = "performance"
+)
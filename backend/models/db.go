@@ -1,35 +0,0 @@
-package models
-
-import (
-	"fmt"
-	"log"
-	"os"
-	"time"
-
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
-)
-
-//DBConnection -> return db instance
-func DBConnection() (*gorm.DB, error) {
-	USER := os.Getenv("DBUSER")
-	PASS := os.Getenv("DBPASS")
-	HOST := os.Getenv("DBHOST")
-	PORT := os.Getenv("DBPORT")
-	DBNAME := os.Getenv("DBNAME")
-
-	newLogger := logger.New(
-		log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
-		logger.Config{
-			SlowThreshold: time.Second, // Slow SQL threshold
-			LogLevel:      logger.Info, // Log level
-			Colorful:      true,        // Disable color
-		},
-	)
-
-	url := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", USER, PASS, HOST, PORT, DBNAME)
-
-	return gorm.Open(mysql.Open(url), &gorm.Config{Logger: newLogger})
-
-}
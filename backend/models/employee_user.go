@@ -0,0 +1,7 @@
+package models
+
+// EmployeeUser is a user associated with an employee
+type EmployeeUser struct {
+	Id    string `json:"id" db:"id"`
+	Email string `json:"email" db:"email"`
+}
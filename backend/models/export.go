@@ -0,0 +1,57 @@
+package models
+
+// Exports, by the name of their route (and of their file)
+const (
+	ExportCustomers   = "customers"
+	ExportEmployees   = "employees"
+	ExportPermissions = "permissions"
+)
+
+// Formats of the exports
+const (
+	ExportJSON = "json"
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+)
+
+// ExportOptions : the format of an export, JSON by default
+type ExportOptions struct {
+	Format string `json:"format" form:"format" binding:"omitempty,oneof=json csv xlsx"`
+}
+
+// CustomerExport is a customer with its users, and their access to its data
+type CustomerExport struct {
+	Id       int            `json:"id"`
+	FullName string         `json:"full_name"`
+	Users    []CustomerUser `json:"users"`
+}
+
+// EmployeeExport is an employee with its users, and their roles
+type EmployeeExport struct {
+	Id       int                 `json:"id"`
+	FullName string              `json:"full_name"`
+	Users    []EmployeeUserRoles `json:"users"`
+}
+
+// EmployeeUserRoles is a user of an employee, with its roles
+type EmployeeUserRoles struct {
+	Id    string   `json:"id"`
+	Email string   `json:"email"`
+	Roles []string `json:"roles"`
+}
+
+// PermissionMatrix is every permission, with the roles having it (directly or through the roles they inherit)
+type PermissionMatrix struct {
+	Roles       []Role             `json:"roles"`
+	Permissions []PermissionGrants `json:"permissions"`
+}
+
+// PermissionGrants is a permission and the roles having it.
+// Policies of roles missing from "permissions" are listed too, without category
+type PermissionGrants struct {
+	Category    string   `json:"category"`
+	Resource    string   `json:"resource"`
+	Action      string   `json:"action"`
+	Description string   `json:"description"`
+	Roles       []string `json:"roles"`
+}
@@ -1,5 +1,63 @@
 package models
 
+// Default and maximum number of rows of a page
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
 type Filter struct {
 	Keyword string `json:"keyword" form:"keyword"`
+	// Expression filters the list by its fields, like "role=admin and (email_domain=example.com or has_employee=false)"
+	Expression string `json:"filter" form:"filter"`
+	// Page starts from 1. PageSize defaults to DefaultPageSize
+	Page     int `json:"page" form:"page" binding:"omitempty,gte=1"`
+	PageSize int `json:"page_size" form:"page_size" binding:"omitempty,gte=1,lte=500"`
+	// Sort is a comma separated list of columns, descending when prefixed by "-" (e.g. "-full_name,id")
+	Sort string `json:"sort" form:"sort"`
+}
+
+// OutboxFilter is the filter of the outbox operations, optionally of one status
+type OutboxFilter struct {
+	Filter
+	Status string `json:"status" form:"status" binding:"omitempty,oneof=pending processing done failed"`
+}
+
+// Limit is the number of rows of the page
+func (f Filter) Limit() int {
+	if f.PageSize <= 0 {
+		return DefaultPageSize
+	}
+	if f.PageSize > MaxPageSize {
+		return MaxPageSize
+	}
+	return f.PageSize
+}
+
+// Offset is the number of rows before the page
+func (f Filter) Offset() int {
+	if f.Page <= 1 {
+		return 0
+	}
+	return (f.Page - 1) * f.Limit()
+}
+
+// Page is the envelope of a list: one page of items, and the total number of items matching the filter
+type Page[T any] struct {
+	Items    []T   `json:"items"`
+	Total    int64 `json:"total"`
+	Page     int   `json:"page"`
+	PageSize int   `json:"page_size"`
+}
+
+// NewPage returns the page of filter holding items
+func NewPage[T any](items []T, total int64, filter Filter) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	page := filter.Page
+	if page < 1 {
+		page = 1
+	}
+	return Page[T]{Items: items, Total: total, Page: page, PageSize: filter.Limit()}
 }
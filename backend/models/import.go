@@ -0,0 +1,65 @@
+package models
+
+import "mime/multipart"
+
+// Sheets of an import, and the columns of their header (the optional ones between brackets):
+//
+//	customers:      id, full_name
+//	employees:      full_name, [email]
+//	customer_users: customer_id, email, [finance], [performance]
+const (
+	ImportCustomers     = "customers"
+	ImportEmployees     = "employees"
+	ImportCustomerUsers = "customer_users"
+)
+
+// Actions of the rows of an import
+const (
+	ImportCreate    = "create"
+	ImportUpdate    = "update"
+	ImportUnchanged = "unchanged"
+	ImportInvalid   = "invalid"
+)
+
+// ImportOptions : a dry run only validates the rows and reports what would change.
+// With CreateUsers, the emails without a user are added from firebase, or created in firebase (through the outbox)
+type ImportOptions struct {
+	DryRun      bool `json:"dry_run" form:"dry_run"`
+	CreateUsers bool `json:"create_users" form:"create_users"`
+}
+
+// ImportFiles are the CSV or XLSX files of an import: a workbook with a sheet of each name, and/or a file of each sheet
+// (an XLSX file of a sheet is read from its first sheet)
+type ImportFiles struct {
+	Workbook      *multipart.FileHeader `form:"workbook"`
+	Customers     *multipart.FileHeader `form:"customers"`
+	Employees     *multipart.FileHeader `form:"employees"`
+	CustomerUsers *multipart.FileHeader `form:"customer_users"`
+}
+
+// ImportReport is what an import changed, or would change (dry run), row by row
+type ImportReport struct {
+	DryRun  bool          `json:"dry_run"`
+	Applied bool          `json:"applied"`
+	Summary ImportSummary `json:"summary"`
+	Rows    []ImportRow   `json:"rows"`
+}
+
+// ImportSummary counts the rows of each action, and the users added
+type ImportSummary struct {
+	Created      int `json:"created"`
+	Updated      int `json:"updated"`
+	Unchanged    int `json:"unchanged"`
+	Invalid      int `json:"invalid"`
+	UsersCreated int `json:"users_created"`
+}
+
+// ImportRow is the action of a row of a sheet: what it changes, or why it is invalid
+type ImportRow struct {
+	Sheet string `json:"sheet"`
+	// Line of the row in its file, from 1 (the header)
+	Line    int      `json:"line"`
+	Action  string   `json:"action"`
+	Changes []string `json:"changes,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Firebase operations that can be queued in the outbox
+const (
+	OutboxCreateUser   = "create"
+	OutboxDeleteUser   = "delete"
+	OutboxDisableUser  = "disable"
+	OutboxRevokeTokens = "revoke_tokens"
+)
+
+// Status of an outbox operation
+const (
+	OutboxPending    = "pending"
+	OutboxProcessing = "processing"
+	OutboxDone       = "done"
+	// OutboxFailed operations have used all their attempts and wait for an admin to retry them
+	OutboxFailed = "failed"
+)
+
+// OutboxOperation is a Firebase operation saved in the same transaction as the database changes it belongs to,
+// and executed afterwards by the outbox worker (with retries), so the two systems cannot drift apart
+type OutboxOperation struct {
+	Id        uint   `json:"id" gorm:"primaryKey"`
+	Operation string `json:"operation" gorm:"size:32"`
+	Uid       string `json:"uid" gorm:"size:128;index"`
+	Email     string `json:"email,omitempty"`
+	// Bcrypt hash of the password of a user to create (never the plain password)
+	PasswordHash string `json:"-"`
+	// Only one operation with the same key is ever queued
+	IdempotencyKey string    `json:"idempotency_key" gorm:"size:191;uniqueIndex"`
+	Status         string    `json:"status" gorm:"size:16;index"`
+	Attempts       int       `json:"attempts"`
+	LastError      string    `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time `json:"next_attempt_at" gorm:"index"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+//TableName Change table's name manually, instead of letting gorm do it automatically
+func (OutboxOperation) TableName() string {
+	return "firebase_outbox"
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Operations of a policy change
+const (
+	PolicyAdd            = "add"
+	PolicyRemove         = "remove"
+	PolicyRemoveFiltered = "remove_filtered"
+	// PolicyReload asks every instance to reload the whole policy (e.g. after SavePolicy)
+	PolicyReload = "reload"
+)
+
+// PolicyChange is a casbin rule change, logged with the policy revision it produced,
+// so that every running instance can apply it to its own enforcer
+type PolicyChange struct {
+	Revision int64 `json:"revision" gorm:"primaryKey;autoIncrement:false"`
+	// Instance that made the change (it is already applied there)
+	Instance   string `json:"instance"`
+	Operation  string `json:"operation"`
+	Sec        string `json:"sec"`
+	Ptype      string `json:"ptype"`
+	FieldIndex int    `json:"field_index"`
+	// JSON encoded rules ([][]string). For PolicyRemoveFiltered, the field values are the only rule
+	Rules     string    `json:"rules"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+//TableName Change table's name manually, instead of letting gorm do it automatically
+func (PolicyChange) TableName() string {
+	return "casbin_policy_changes"
+}
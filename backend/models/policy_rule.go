@@ -0,0 +1,69 @@
+package models
+
+import "mime/multipart"
+
+// Formats of the policy files
+const (
+	PolicyCSV  = "csv"
+	PolicyJSON = "json"
+	PolicyYAML = "yaml"
+)
+
+// PolicyRule is a casbin rule, like the line "p, admin, rbac::data, write" of a policy CSV file
+type PolicyRule struct {
+	Ptype string   `json:"ptype"`
+	Rule  []string `json:"rule"`
+}
+
+// PolicyRules are the rules of a policy JSON or YAML file, by type:
+//
+//	p:
+//	  - [admin, rbac::data, write]
+//	g:
+//	  - [<uid>, admin]
+type PolicyRules map[string][][]string
+
+// PolicyFilter selects the rules of a subject (the user or role of their first value),
+// and/or the policy rules of the resources starting with a prefix (excluding the role rules). Empty selects everything
+type PolicyFilter struct {
+	Subject        string `json:"subject" form:"subject"`
+	ResourcePrefix string `json:"resource_prefix" form:"resource_prefix"`
+}
+
+// PolicyExportOptions : the rules exported, and their format (JSON by default)
+type PolicyExportOptions struct {
+	Subject        string `json:"subject" form:"subject"`
+	ResourcePrefix string `json:"resource_prefix" form:"resource_prefix"`
+	Format         string `json:"format" form:"format" binding:"omitempty,oneof=csv json yaml"`
+}
+
+// PolicyImportOptions : the rules replaced by those of the file, and whether to only compute the diff
+type PolicyImportOptions struct {
+	Subject        string `json:"subject" form:"subject"`
+	ResourcePrefix string `json:"resource_prefix" form:"resource_prefix"`
+	DryRun         bool   `json:"dry_run" form:"dry_run"`
+}
+
+// PolicyFile is the file of a policy import, its format given by its extension (.csv, .json, .yaml or .yml)
+type PolicyFile struct {
+	File *multipart.FileHeader `form:"file" binding:"required"`
+}
+
+// PolicyDiff is what a policy import changed, or would change (dry run)
+type PolicyDiff struct {
+	DryRun    bool         `json:"dry_run"`
+	Applied   bool         `json:"applied"`
+	Added     []PolicyRule `json:"added"`
+	Removed   []PolicyRule `json:"removed"`
+	Unchanged int          `json:"unchanged"`
+}
+
+// Filter returns the filter of the rules exported
+func (o PolicyExportOptions) Filter() PolicyFilter {
+	return PolicyFilter{Subject: o.Subject, ResourcePrefix: o.ResourcePrefix}
+}
+
+// Filter returns the filter of the rules replaced
+func (o PolicyImportOptions) Filter() PolicyFilter {
+	return PolicyFilter{Subject: o.Subject, ResourcePrefix: o.ResourcePrefix}
+}
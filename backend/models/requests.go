@@ -0,0 +1,146 @@
+package models
+
+// Request bodies of the API. Their `binding` tags are checked by gin (see the validation package),
+// and they are documented in the OpenAPI document of the routes.
+
+type UpdateRoleRequest struct {
+	UserId  string `json:"id" binding:"required,notblank"`
+	NewRole string `json:"role" binding:"required"`
+}
+
+type AddRoleRequest struct {
+	Role        string `json:"role" binding:"required,notblank,max=191"`
+	Description string `json:"description"`
+}
+
+type DeleteRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type AddPermissionRequest struct {
+	NewRole      string `json:"newRole" binding:"required"`
+	NewData      string `json:"newData" binding:"required,resource"`
+	NewPrivilege string `json:"newPrivilege" binding:"required,oneof=read write"`
+}
+
+type DeletePermissionRequest struct {
+	Role      string `json:"role" binding:"required"`
+	Data      string `json:"data" binding:"required,resource"`
+	Privilege string `json:"privilege" binding:"required,oneof=read write"`
+}
+
+// AddCustomerRequest : customer's name must not be empty and customer's id (its podio id) must be a positive integer
+type AddCustomerRequest struct {
+	Id       int    `json:"id" binding:"required,gt=0"`
+	FullName string `json:"full_name" binding:"required,notblank"`
+}
+
+type UpdateCustomerRequest struct {
+	Id       int    `json:"id" binding:"required"`
+	FullName string `json:"full_name" binding:"required,notblank"`
+}
+
+// AddEmployeeRequest : employee's name must not be empty (its id is given by the database)
+type AddEmployeeRequest struct {
+	FullName string `json:"full_name" binding:"required,notblank"`
+}
+
+type UpdateEmployeeRequest struct {
+	Id       int    `json:"id" binding:"required,gt=0"`
+	FullName string `json:"full_name" binding:"required,notblank"`
+}
+
+type AddEmployeeUserRequest struct {
+	UserEmail  string `json:"user_email" binding:"required,email"`
+	EmployeeId int    `json:"employee_id" binding:"required,gt=0"`
+}
+
+type DeleteEmployeeUserRequest struct {
+	UserId     string `json:"user_id" binding:"required"`
+	EmployeeId int    `json:"employee_id" binding:"required,gt=0"`
+}
+
+type AddCustomerUserRequest struct {
+	CustomerId int    `json:"id" binding:"required"`
+	UserEmail  string `json:"email" binding:"required,email"`
+}
+
+type DeleteCustomerUserRequest struct {
+	UserId     string `json:"user_id" binding:"required"`
+	CustomerId int    `json:"customer_id" binding:"required"`
+}
+
+// ToggleCustomerUserAccessRequest gives (or takes) the access of a user to the finance or performance data of a customer
+type ToggleCustomerUserAccessRequest struct {
+	CustomerId   int    `json:"customer_id" binding:"required"`
+	UserId       string `json:"user_id" binding:"required"`
+	AccessObject string `json:"access_object" binding:"required,oneof=finance performance"`
+	HasAccess    bool   `json:"has_access"`
+}
+
+type AddFirebaseUserRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6,max=72"`
+}
+
+// Requests of /api/v2. Its routes identify the resources by their path, so the bodies only hold the fields to set
+// (the bodies of v1 are reused where they already do)
+
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// RenameRequest renames an employee or a customer
+type RenameRequest struct {
+	FullName string `json:"full_name" binding:"required,notblank"`
+}
+
+// AssociateUserRequest assigns the user of an email to an employee, or associates it with a customer
+type AssociateUserRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// Path parameters of /api/v2
+
+type RolePath struct {
+	Role string `uri:"role" binding:"required"`
+}
+
+type RolePermissionPath struct {
+	Role     string `uri:"role" binding:"required"`
+	Resource string `uri:"resource" binding:"required,resource"`
+	Action   string `uri:"action" binding:"required,oneof=read write"`
+}
+
+type UserPath struct {
+	UserId string `uri:"uid" binding:"required"`
+}
+
+type EmployeePath struct {
+	Id int `uri:"id" binding:"required,gt=0"`
+}
+
+type EmployeeUserPath struct {
+	Id     int    `uri:"id" binding:"required,gt=0"`
+	UserId string `uri:"uid" binding:"required"`
+}
+
+type CustomerPath struct {
+	Id int `uri:"id" binding:"required"`
+}
+
+type CustomerUserPath struct {
+	Id     int    `uri:"id" binding:"required"`
+	UserId string `uri:"uid" binding:"required"`
+}
+
+// CustomerAccessPath is the access of a user to the finance or performance data (object) of a customer
+type CustomerAccessPath struct {
+	Id     int    `uri:"id" binding:"required"`
+	UserId string `uri:"uid" binding:"required"`
+	Object string `uri:"object" binding:"required,oneof=finance performance"`
+}
+
+type OutboxOperationPath struct {
+	Id uint `uri:"id" binding:"required,gt=0"`
+}
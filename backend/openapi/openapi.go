@@ -0,0 +1,294 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operation documents a route: its parameters, body and response are Go values (usually zero values of structs)
+// turned into schemas by reflection, with the json, form and uri tags and the validation rules of the binding tags.
+type Operation struct {
+	Summary     string
+	Description string
+	Tag         string
+	// Public operations need no bearer token
+	Public bool
+	// Permission is the casbin object and action required, like "rbac::data read"
+	Permission string
+	// Path is a struct of the path parameters (uri tags). They are strings if nil
+	Path interface{}
+	// Query is a struct of the query parameters (form tags)
+	Query interface{}
+	// Body is the JSON request body
+	Body interface{}
+	// Form is a struct of the fields of a multipart/form-data request body (form tags), *multipart.FileHeader being a file
+	Form interface{}
+	// Response is the JSON response body, nil if the response has no content (null)
+	Response interface{}
+	// ContentType of the response, application/json if empty
+	ContentType string
+	// Status of the response, 200 if zero. A 204 response has no content
+	Status int
+	// Descriptions of the path and query parameters, by name
+	Descriptions map[string]string
+}
+
+// Spec is the table of the documented routes of the API, rendered as an OpenAPI 3 document
+type Spec struct {
+	title       string
+	version     string
+	description string
+	operations  map[string]Operation
+
+	once     sync.Once
+	document map[string]interface{}
+}
+
+func New(title string, version string, description string) *Spec {
+	return &Spec{title: title, version: version, description: description, operations: map[string]Operation{}}
+}
+
+// Add documents the route of method and path (in gin's syntax, like "/api/customers/:id")
+func (s *Spec) Add(method string, path string, operation Operation) {
+	s.operations[method+" "+path] = operation
+}
+
+// Documented reports whether the route of method and path is documented
+func (s *Spec) Documented(method string, path string) bool {
+	_, ok := s.operations[method+" "+path]
+	return ok
+}
+
+// Routes returns the documented routes, as "METHOD path"
+func (s *Spec) Routes() []string {
+	routes := make([]string, 0, len(s.operations))
+	for route := range s.operations {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// Handler serves the document as JSON
+func (s *Spec) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.Document())
+	}
+}
+
+// Document builds the OpenAPI document (once)
+func (s *Spec) Document() map[string]interface{} {
+	s.once.Do(func() {
+		s.document = s.build()
+	})
+	return s.document
+}
+
+func (s *Spec) build() map[string]interface{} {
+	generator := &schemas{components: map[string]*Schema{}}
+	paths := map[string]map[string]interface{}{}
+
+	keys := make([]string, 0, len(s.operations))
+	for key := range s.operations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		method, path, _ := strings.Cut(key, " ")
+		openAPIPath, pathParameters := convertPath(path)
+		if paths[openAPIPath] == nil {
+			paths[openAPIPath] = map[string]interface{}{}
+		}
+		paths[openAPIPath][strings.ToLower(method)] = s.operation(generator, method, path, pathParameters, s.operations[key])
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]string{"title": s.title, "version": s.version, "description": s.description},
+		"paths":   paths,
+		"security": []map[string][]string{
+			{"bearerAuth": {}},
+		},
+		"components": map[string]interface{}{
+			"schemas": generator.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]string{"type": "http", "scheme": "bearer", "bearerFormat": "Firebase ID token"},
+			},
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "Error, rendered as the error envelope of the API",
+					"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": errorSchema}},
+				},
+			},
+		},
+	}
+}
+
+// errorSchema is the error envelope rendered by apierror.Render
+var errorSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"code":       {Type: "string", Description: "Stable error code, like validation_failed or role_in_use"},
+		"message":    {Type: "string", Description: "User-facing message"},
+		"type":       {Type: "string", Enum: []string{"warning", "error"}, Description: "warning for the mistakes the user can fix"},
+		"details":    {Description: "Details of the error, like the errors of each field of an invalid request"},
+		"request_id": {Type: "string"},
+	},
+	Required: []string{"code", "message", "type"},
+}
+
+func (s *Spec) operation(generator *schemas, method string, path string, pathParameters []string, op Operation) map[string]interface{} {
+	description := op.Description
+	if op.Permission != "" {
+		description = strings.TrimSpace(description + "\n\nRequires the permission: " + op.Permission + ".")
+	}
+
+	operation := map[string]interface{}{
+		"operationId": operationID(method, path),
+		"summary":     op.Summary,
+	}
+	if description != "" {
+		operation["description"] = description
+	}
+	if op.Tag != "" {
+		operation["tags"] = []string{op.Tag}
+	}
+	if op.Public {
+		operation["security"] = []interface{}{}
+	}
+
+	var parameters []map[string]interface{}
+	pathTypes := map[string]*Schema{}
+	if op.Path != nil {
+		for _, field := range fields(reflect.TypeOf(op.Path), "uri") {
+			pathTypes[field.name] = generator.schema(field.typ)
+			applyRules(pathTypes[field.name], field.rules)
+		}
+	}
+	for _, name := range pathParameters {
+		schema := pathTypes[name]
+		if schema == nil {
+			schema = &Schema{Type: "string"}
+		}
+		parameters = append(parameters, parameter(name, "path", true, schema, op.Descriptions[name]))
+	}
+	if op.Query != nil {
+		for _, field := range fields(reflect.TypeOf(op.Query), "form") {
+			schema := generator.schema(field.typ)
+			applyRules(schema, field.rules)
+			parameters = append(parameters, parameter(field.name, "query", field.required, schema, op.Descriptions[field.name]))
+		}
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if op.Body != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": generator.of(op.Body)}},
+		}
+	}
+
+	if op.Form != nil {
+		form := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		for _, field := range fields(reflect.TypeOf(op.Form), "form") {
+			form.Properties[field.name] = generator.schema(field.typ)
+		}
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"multipart/form-data": map[string]interface{}{"schema": form}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := map[string]interface{}{"description": http.StatusText(status)}
+	if status != http.StatusNoContent {
+		contentType := op.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		responseSchema := &Schema{Nullable: true, Description: "null"}
+		if op.Response != nil {
+			responseSchema = generator.of(op.Response)
+		} else if contentType != "application/json" {
+			responseSchema = &Schema{Type: "string"}
+		}
+		response["content"] = map[string]interface{}{contentType: map[string]interface{}{"schema": responseSchema}}
+	}
+	operation["responses"] = map[string]interface{}{
+		strconv.Itoa(status): response,
+		"default":            map[string]string{"$ref": "#/components/responses/Error"},
+	}
+	return operation
+}
+
+func parameter(name string, in string, required bool, schema *Schema, description string) map[string]interface{} {
+	parameter := map[string]interface{}{"name": name, "in": in, "required": required, "schema": schema}
+	if description != "" {
+		parameter["description"] = description
+	}
+	return parameter
+}
+
+// convertPath converts a gin path ("/api/customers/:id") to an OpenAPI path ("/api/customers/{id}"),
+// and returns the names of its parameters
+func convertPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var parameters []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			parameters = append(parameters, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), parameters
+}
+
+// operationID is a unique name of the operation, like "deleteApiCustomersId"
+func operationID(method string, path string) string {
+	id := strings.ToLower(method)
+	for _, word := range strings.FieldsFunc(path, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		id += strings.ToUpper(word[:1]) + word[1:]
+	}
+	return id
+}
+
+// UI serves the interactive documentation of the document at specURL
+func UI(title string, specURL string) gin.HandlerFunc {
+	page := fmt.Sprintf(uiTemplate, title, specURL)
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	}
+}
+
+// Swagger UI from its CDN, at an exact version so new releases do not change the page.
+// The bearer token (a Firebase ID token) is given with the Authorize button, and forgotten when the page is closed
+const uiTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8"/>
+    <title>%s</title>
+    <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui.css" crossorigin="anonymous"/>
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui-bundle.js" crossorigin="anonymous"></script>
+<script>
+    window.ui = SwaggerUIBundle({url: %q, dom_id: '#swagger-ui'});
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,207 @@
+package openapi
+
+import (
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI schema object (only the keywords generated from Go types)
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Descriptions of the custom validation rules (see the validation package), added to the fields using them
+var ruleDescriptions = map[string]string{
+	"notblank": "Must not be blank.",
+	"resource": `A resource name, like "portal::data::1996::finance".`,
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// fileType is the type of the uploaded files of a form
+var fileType = reflect.TypeOf(&multipart.FileHeader{})
+
+// schemas generates the schemas of Go types, the named structs being registered as components
+type schemas struct {
+	components map[string]*Schema
+}
+
+// of returns the schema of the type of value: a reference for named structs
+func (s *schemas) of(value interface{}) *Schema {
+	return s.schema(reflect.TypeOf(value))
+}
+
+func (s *schemas) schema(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	if t == fileType {
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := s.schema(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		copied := *schema
+		copied.Nullable = true
+		return &copied
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := schemaName(t)
+		if _, ok := s.components[name]; !ok {
+			// Registered before its fields, so recursive types end
+			s.components[name] = &Schema{}
+			*s.components[name] = *s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		// interfaces: any value
+		return &Schema{}
+	}
+}
+
+// object is the schema of a struct: its json fields, with the rules of their binding tags
+func (s *schemas) object(t reflect.Type) *Schema {
+	object := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, field := range fields(t, "json") {
+		schema := s.schema(field.typ)
+		if schema.Ref == "" {
+			applyRules(schema, field.rules)
+		}
+		object.Properties[field.name] = schema
+		if field.required {
+			object.Required = append(object.Required, field.name)
+		}
+	}
+	return object
+}
+
+// field is an exported field of a struct, named by its tag
+type field struct {
+	name     string
+	typ      reflect.Type
+	rules    []string
+	required bool
+}
+
+// fields returns the fields of t named by tag (json, form or uri), with the fields of the embedded structs
+func fields(t reflect.Type, tag string) []field {
+	var result []field
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+		name := strings.SplitN(structField.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			continue
+		}
+		if structField.Anonymous && name == "" && structField.Type.Kind() == reflect.Struct {
+			result = append(result, fields(structField.Type, tag)...)
+			continue
+		}
+		if name == "" {
+			if tag != "json" {
+				continue
+			}
+			name = structField.Name
+		}
+
+		var rules []string
+		if binding := structField.Tag.Get("binding"); binding != "" {
+			rules = strings.Split(binding, ",")
+		}
+		required := false
+		for _, rule := range rules {
+			required = required || rule == "required"
+		}
+		result = append(result, field{name: name, typ: structField.Type, rules: rules, required: required})
+	}
+	return result
+}
+
+// applyRules documents the validation rules of a field in its schema
+func applyRules(schema *Schema, rules []string) {
+	var descriptions []string
+	for _, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		number, numberErr := strconv.ParseFloat(param, 64)
+		switch {
+		case name == "email":
+			schema.Format = "email"
+		case name == "oneof":
+			schema.Enum = strings.Fields(param)
+		case (name == "gt" || name == "gte") && numberErr == nil:
+			schema.Minimum = &number
+			schema.ExclusiveMinimum = name == "gt"
+		case name == "lte" && numberErr == nil:
+			schema.Maximum = &number
+		case (name == "min" || name == "max") && numberErr == nil:
+			length := int(number)
+			if schema.Type == "string" && name == "min" {
+				schema.MinLength = &length
+			} else if schema.Type == "string" {
+				schema.MaxLength = &length
+			} else if name == "min" {
+				schema.Minimum = &number
+			} else {
+				schema.Maximum = &number
+			}
+		case ruleDescriptions[name] != "":
+			descriptions = append(descriptions, ruleDescriptions[name])
+		}
+	}
+	if len(descriptions) > 0 {
+		schema.Description = strings.Join(descriptions, " ")
+	}
+}
+
+// schemaName is the name of the component of a named struct, e.g. "Customer", or "CustomerPage" for models.Page[models.Customer]
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	base, argument, generic := strings.Cut(name, "[")
+	if !generic {
+		return name
+	}
+	argument = strings.TrimSuffix(argument, "]")
+	if i := strings.LastIndex(argument, "."); i >= 0 {
+		argument = argument[i+1:]
+	}
+	return argument + base
+}
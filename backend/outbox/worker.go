@@ -0,0 +1,229 @@
+package outbox
+
+import (
+	"backend/logging"
+	"backend/metrics"
+	"backend/models"
+	"backend/store"
+	"backend/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"firebase.google.com/go/auth"
+	"firebase.google.com/go/auth/hash"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Retry policy of failed operations: the delay doubles on every attempt, up to maxBackoff
+const (
+	baseBackoff = 5 * time.Second
+	maxBackoff  = time.Hour
+	maxAttempts = 10
+)
+
+const (
+	// pollInterval is how often the worker looks for due operations when it is not woken up
+	pollInterval = 10 * time.Second
+	// batchSize is the max number of operations processed in one pass
+	batchSize = 50
+	// processingTimeout is how long an operation may stay in processing before it is considered abandoned
+	processingTimeout = 5 * time.Minute
+	// operationTimeout bounds the execution of one operation
+	operationTimeout = time.Minute
+)
+
+// Worker executes the Firebase operations queued in the outbox, retrying failed ones with exponential backoff.
+// Every operation is idempotent, so running it twice (e.g. after a crash) is harmless,
+// and several instances can run a worker on the same database, since operations are claimed before execution.
+// The operations of a user are executed in the order they were queued, a failed one holding back the next ones until it is retried.
+type Worker struct {
+	outbox       store.OutboxStore
+	firebaseAuth *auth.Client
+	wake         chan struct{}
+}
+
+func New(outbox store.OutboxStore, firebaseAuth *auth.Client) *Worker {
+	return &Worker{outbox: outbox, firebaseAuth: firebaseAuth, wake: make(chan struct{}, 1)}
+}
+
+// Notify wakes the worker up, to process newly queued operations without waiting for the next poll
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes due operations until ctx is cancelled.
+// The operation in progress when ctx is cancelled is completed (and recorded) before Run returns.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.processDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+func (w *Worker) processDue(ctx context.Context) {
+	// Take back operations abandoned by a crashed (or restarted) instance
+	if err := w.outbox.Release(ctx, processingTimeout); err != nil {
+		slog.ErrorContext(ctx, "failed to release abandoned outbox operations", slog.Any("error", err))
+	}
+
+	ops, err := w.outbox.Due(ctx, time.Now(), batchSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list due outbox operations", slog.Any("error", err))
+		return
+	}
+
+	for _, op := range ops {
+		if ctx.Err() != nil {
+			return
+		}
+
+		claimed, err := w.outbox.Claim(ctx, op.Id)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to claim outbox operation", slog.Any("outbox_id", op.Id), slog.Any("error", err))
+			continue
+		}
+		// Another instance got it first
+		if !claimed {
+			continue
+		}
+
+		// Not cancelled with ctx, so that a claimed operation is always executed and recorded
+		opCtx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+		w.process(opCtx, op)
+		cancel()
+	}
+}
+
+func (w *Worker) process(ctx context.Context, op models.OutboxOperation) {
+	ctx = logging.With(ctx, slog.Any("outbox_id", op.Id), slog.String("operation", op.Operation), slog.String("uid", op.Uid))
+	ctx, span := tracing.Start(ctx, "outbox."+op.Operation, attribute.Int64("outbox.id", int64(op.Id)), attribute.Int("outbox.attempt", op.Attempts+1))
+	defer span.End()
+
+	err := w.execute(ctx, op)
+	tracing.Fail(span, err)
+	if err == nil {
+		if err = w.outbox.Complete(ctx, op.Id); err != nil {
+			slog.ErrorContext(ctx, "failed to complete outbox operation", slog.Any("error", err))
+		}
+		return
+	}
+
+	attempts := op.Attempts + 1
+	giveUp := attempts >= maxAttempts
+	slog.WarnContext(ctx, "outbox operation failed", slog.Int("attempt", attempts), slog.Bool("give_up", giveUp), slog.Any("error", err))
+
+	if err = w.outbox.Fail(ctx, op.Id, attempts, err.Error(), time.Now().Add(backoff(attempts)), giveUp); err != nil {
+		slog.ErrorContext(ctx, "failed to record outbox operation failure", slog.Any("error", err))
+		return
+	}
+
+	// A user that cannot be deleted must at least not be able to sign in anymore
+	if giveUp && op.Operation == models.OutboxDeleteUser {
+		for _, operation := range []string{models.OutboxDisableUser, models.OutboxRevokeTokens} {
+			if err = w.outbox.Enqueue(ctx, &models.OutboxOperation{Operation: operation, Uid: op.Uid}); err != nil {
+				slog.ErrorContext(ctx, "failed to enqueue outbox operation", slog.String("fallback", operation), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// execute runs op against Firebase. Each operation succeeds if Firebase is already in the wanted state
+func (w *Worker) execute(ctx context.Context, op models.OutboxOperation) error {
+	switch op.Operation {
+	case models.OutboxCreateUser:
+		return w.createUser(ctx, op)
+	case models.OutboxDeleteUser:
+		err := metrics.Firebase(ctx, "delete_user", func() error {
+			return w.firebaseAuth.DeleteUser(ctx, op.Uid)
+		}, auth.IsUserNotFound)
+		// Already deleted
+		if auth.IsUserNotFound(err) {
+			return nil
+		}
+		return err
+	case models.OutboxDisableUser:
+		err := metrics.Firebase(ctx, "update_user", func() error {
+			_, err := w.firebaseAuth.UpdateUser(ctx, op.Uid, (&auth.UserToUpdate{}).Disabled(true))
+			return err
+		}, auth.IsUserNotFound)
+		if auth.IsUserNotFound(err) {
+			return nil
+		}
+		return err
+	case models.OutboxRevokeTokens:
+		err := metrics.Firebase(ctx, "revoke_refresh_tokens", func() error {
+			return w.firebaseAuth.RevokeRefreshTokens(ctx, op.Uid)
+		}, auth.IsUserNotFound)
+		if auth.IsUserNotFound(err) {
+			return nil
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown outbox operation %q", op.Operation)
+	}
+}
+
+// createUser imports the user with the uid and bcrypt password hash (if any) saved in the database,
+// so that retrying never creates a second account
+func (w *Worker) createUser(ctx context.Context, op models.OutboxOperation) error {
+	// Already created
+	err := metrics.Firebase(ctx, "get_user", func() error {
+		_, err := w.firebaseAuth.GetUser(ctx, op.Uid)
+		return err
+	}, auth.IsUserNotFound)
+	if err == nil {
+		return nil
+	}
+	if !auth.IsUserNotFound(err) {
+		return err
+	}
+
+	user := (&auth.UserToImport{}).
+		UID(op.Uid).
+		Email(op.Email)
+	// Users created by an import have no password: they set it with the "forgot password" link
+	var options []auth.UserImportOption
+	if op.PasswordHash != "" {
+		user.PasswordHash([]byte(op.PasswordHash))
+		options = append(options, auth.WithHash(hash.Bcrypt{}))
+	}
+	var result *auth.UserImportResult
+	err = metrics.Firebase(ctx, "import_users", func() error {
+		result, err = w.firebaseAuth.ImportUsers(ctx, []*auth.UserToImport{user}, options...)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if result.FailureCount > 0 {
+		return errors.New(result.Errors[0].Reason)
+	}
+	return nil
+}
+
+// backoff returns the delay before the next attempt of an operation that failed attempts times
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
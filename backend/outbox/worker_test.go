@@ -0,0 +1,187 @@
+package outbox
+
+import (
+	"backend/models"
+	"backend/store"
+	"backend/store/storetest"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	firebase "firebase.google.com/go"
+	"google.golang.org/api/option"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{6, 160 * time.Second},
+		{10, 2560 * time.Second},
+		{11, time.Hour},
+		{50, time.Hour},
+	}
+	for _, test := range tests {
+		if got := backoff(test.attempts); got != test.want {
+			t.Errorf("backoff(%d) = %s, want %s", test.attempts, got, test.want)
+		}
+	}
+}
+
+// firebaseStub answers the requests of the Firebase client with status, and records their paths.
+// Failures are answered with 400, which the client does not retry by itself
+type firebaseStub struct {
+	status int
+
+	mu    sync.Mutex
+	paths []string
+}
+
+func (f *firebaseStub) RoundTrip(request *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	f.paths = append(f.paths, request.URL.Path)
+	f.mu.Unlock()
+	return &http.Response{
+		StatusCode: f.status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{}`)),
+		Request:    request,
+	}, nil
+}
+
+// newTestWorker returns a worker on a throwaway database, whose Firebase requests are answered with status
+func newTestWorker(t *testing.T, status int) (*store.Store, *Worker, *firebaseStub) {
+	t.Helper()
+	st := storetest.Open(t)
+
+	stub := &firebaseStub{status: status}
+	app, err := firebase.NewApp(context.Background(), &firebase.Config{ProjectID: "test"},
+		option.WithHTTPClient(&http.Client{Transport: stub}))
+	if err != nil {
+		t.Fatalf("failed to create the firebase app: %v", err)
+	}
+	client, err := app.Auth(context.Background())
+	if err != nil {
+		t.Fatalf("failed to create the firebase client: %v", err)
+	}
+	return st, New(st.Outbox, client), stub
+}
+
+func queued(t *testing.T, st *store.Store) map[string]models.OutboxOperation {
+	t.Helper()
+	ops, _, err := st.Outbox.List(context.Background(), "", models.Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	byOperation := make(map[string]models.OutboxOperation)
+	for _, op := range ops {
+		byOperation[op.Operation] = op
+	}
+	return byOperation
+}
+
+func TestWorkerRetriesWithBackoff(t *testing.T) {
+	st, worker, _ := newTestWorker(t, http.StatusBadRequest)
+	ctx := context.Background()
+	if err := st.Outbox.Enqueue(ctx, &models.OutboxOperation{Operation: models.OutboxDeleteUser, Uid: "alice"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		before := time.Now()
+		worker.processDue(ctx)
+
+		op := queued(t, st)[models.OutboxDeleteUser]
+		if op.Status != models.OutboxPending || op.Attempts != attempt || op.LastError == "" {
+			t.Fatalf("after attempt %d: operation is %s after %d attempts (%q), want pending with an error", attempt, op.Status, op.Attempts, op.LastError)
+		}
+		if earliest := before.Add(backoff(attempt)); op.NextAttemptAt.Before(earliest) || op.NextAttemptAt.After(time.Now().Add(backoff(attempt))) {
+			t.Errorf("after attempt %d: next attempt at %s, want %s after the attempt", attempt, op.NextAttemptAt, backoff(attempt))
+		}
+
+		// Not due before its next attempt
+		worker.processDue(ctx)
+		if op = queued(t, st)[models.OutboxDeleteUser]; op.Attempts != attempt {
+			t.Fatalf("operation attempted again before its backoff")
+		}
+		if err := st.DB().Model(&models.OutboxOperation{}).Where("id = ?", op.Id).
+			UpdateColumn("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+			t.Fatalf("failed to make the operation due: %v", err)
+		}
+	}
+}
+
+// A user that cannot be deleted is disabled, and its sessions revoked
+func TestWorkerGivesUpDeletion(t *testing.T) {
+	st, worker, stub := newTestWorker(t, http.StatusBadRequest)
+	ctx := context.Background()
+
+	if err := st.Outbox.Enqueue(ctx, &models.OutboxOperation{Operation: models.OutboxDeleteUser, Uid: "alice"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	op := queued(t, st)[models.OutboxDeleteUser]
+	op.Attempts = maxAttempts - 1
+	worker.process(ctx, op)
+
+	op = queued(t, st)[models.OutboxDeleteUser]
+	if op.Status != models.OutboxFailed || op.Attempts != maxAttempts {
+		t.Errorf("deletion is %s after %d attempts, want failed after %d", op.Status, op.Attempts, maxAttempts)
+	}
+	for _, fallback := range []string{models.OutboxDisableUser, models.OutboxRevokeTokens} {
+		queuedFallback, ok := queued(t, st)[fallback]
+		if !ok {
+			t.Errorf("%s not queued after giving up the deletion", fallback)
+			continue
+		}
+		if queuedFallback.Status != models.OutboxPending {
+			t.Errorf("%s is %s, want pending", fallback, queuedFallback.Status)
+		}
+	}
+	if len(stub.paths) != 1 {
+		t.Errorf("firebase requests = %q, want the deletion only", stub.paths)
+	}
+}
+
+// Other operations are marked as failed, without fallback
+func TestWorkerGivesUpOtherOperations(t *testing.T) {
+	st, worker, _ := newTestWorker(t, http.StatusBadRequest)
+	ctx := context.Background()
+	if err := st.Outbox.Enqueue(ctx, &models.OutboxOperation{Operation: models.OutboxDisableUser, Uid: "alice"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	op := queued(t, st)[models.OutboxDisableUser]
+	op.Attempts = maxAttempts - 1
+	worker.process(ctx, op)
+
+	ops := queued(t, st)
+	if len(ops) != 1 || ops[models.OutboxDisableUser].Status != models.OutboxFailed {
+		t.Errorf("queued operations = %v, want the disabling only, failed", ops)
+	}
+}
+
+func TestWorkerCompletes(t *testing.T) {
+	st, worker, stub := newTestWorker(t, http.StatusOK)
+	ctx := context.Background()
+	for _, uid := range []string{"alice", "bob"} {
+		if err := st.Outbox.Enqueue(ctx, &models.OutboxOperation{Operation: models.OutboxRevokeTokens, Uid: uid}); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	worker.processDue(ctx)
+	done, _, err := st.Outbox.List(ctx, models.OutboxDone, models.Filter{})
+	if err != nil || len(done) != 2 {
+		t.Errorf("done operations = %v, %v, want 2", done, err)
+	}
+	if len(stub.paths) != 2 {
+		t.Errorf("firebase requests = %q, want 2", stub.paths)
+	}
+}
@@ -0,0 +1,187 @@
+// Package policyfile reads and writes the files of the policy imports and exports:
+// CSV like the policy files of casbin ("p, admin, rbac::data, write"), JSON and YAML (models.PolicyRules)
+package policyfile
+
+import (
+	"backend/models"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxFileSize is the limit of the files read
+const MaxFileSize = 10 << 20
+
+// FormatOf returns the format of a file from the extension of its name
+func FormatOf(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return models.PolicyCSV, nil
+	case ".json":
+		return models.PolicyJSON, nil
+	case ".yaml", ".yml":
+		return models.PolicyYAML, nil
+	}
+	return "", fmt.Errorf("unsupported file %q: expected a .csv, .json or .yaml file", filename)
+}
+
+// ContentType returns the media type of the files of format
+func ContentType(format string) string {
+	switch format {
+	case models.PolicyCSV:
+		return "text/csv; charset=utf-8"
+	case models.PolicyYAML:
+		return "application/yaml; charset=utf-8"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// Read reads the rules of a file of format. The values are trimmed, but not checked against the model
+func Read(r io.Reader, format string) ([]models.PolicyRule, error) {
+	content, err := io.ReadAll(io.LimitReader(r, MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > MaxFileSize {
+		return nil, fmt.Errorf("the file is larger than %d MB", MaxFileSize>>20)
+	}
+
+	var rules models.PolicyRules
+	switch format {
+	case models.PolicyCSV:
+		return readCSV(content)
+	case models.PolicyJSON:
+		if err = json.Unmarshal(content, &rules); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	case models.PolicyYAML:
+		if err = yaml.Unmarshal(content, &rules); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+
+	var result []models.PolicyRule
+	for _, ptype := range ptypes(rules) {
+		for _, rule := range rules[ptype] {
+			for i := range rule {
+				rule[i] = strings.TrimSpace(rule[i])
+			}
+			result = append(result, models.PolicyRule{Ptype: ptype, Rule: rule})
+		}
+	}
+	return result, nil
+}
+
+// readCSV reads the lines of a casbin policy file, skipping the empty ones and the comments (#)
+func readCSV(content []byte) ([]models.PolicyRule, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var rules []models.PolicyRule
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rules, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		if len(record) == 1 && record[0] == "" {
+			continue
+		}
+		rules = append(rules, models.PolicyRule{Ptype: record[0], Rule: record[1:]})
+	}
+}
+
+// Write writes rules in format, grouped by type in JSON and YAML
+func Write(w io.Writer, format string, rules []models.PolicyRule) error {
+	switch format {
+	case models.PolicyCSV:
+		return writeCSV(w, rules)
+	case models.PolicyJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(group(rules))
+	case models.PolicyYAML:
+		return writeYAML(w, group(rules))
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+// writeCSV writes a line of each rule, its values separated by ", " like casbin does
+func writeCSV(w io.Writer, rules []models.PolicyRule) error {
+	for _, rule := range rules {
+		values := append([]string{rule.Ptype}, rule.Rule...)
+		for i, value := range values {
+			if strings.ContainsAny(value, ",\"\n") {
+				values[i] = `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+			}
+		}
+		if _, err := io.WriteString(w, strings.Join(values, ", ")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeYAML writes each rule on one line ("- [admin, rbac::data, write]"), quoting the values only when needed
+func writeYAML(w io.Writer, rules models.PolicyRules) error {
+	document := &yaml.Node{Kind: yaml.MappingNode}
+	for _, ptype := range ptypes(rules) {
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, rule := range rules[ptype] {
+			values := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for _, value := range rule {
+				values.Content = append(values.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+			}
+			list.Content = append(list.Content, values)
+		}
+		document.Content = append(document.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: ptype}, list)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// group returns rules by type, in their order
+func group(rules []models.PolicyRule) models.PolicyRules {
+	grouped := models.PolicyRules{}
+	for _, rule := range rules {
+		grouped[rule.Ptype] = append(grouped[rule.Ptype], rule.Rule)
+	}
+	return grouped
+}
+
+// ptypes returns the types of rules, the policy types (p, p2...) before the role types (g, g2...)
+func ptypes(rules models.PolicyRules) []string {
+	var types []string
+	for ptype := range rules {
+		types = append(types, ptype)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if isPolicy, other := strings.HasPrefix(types[i], "p"), strings.HasPrefix(types[j], "p"); isPolicy != other {
+			return isPolicy
+		}
+		return types[i] < types[j]
+	})
+	return types
+}
@@ -0,0 +1,320 @@
+package routes
+
+import (
+	"backend/authz"
+	"backend/handlers"
+	"backend/models"
+	"backend/openapi"
+	"backend/store"
+	"net/http"
+)
+
+// apiDocs documents every route of registerRoutes, served at /api/openapi.json.
+// A route missing here fails TestRoutesAreDocumented.
+var apiDocs = newAPIDocs()
+
+// Path parameters of the routes with an id
+type customerPath struct {
+	Id int `uri:"id"`
+}
+
+type employeePath struct {
+	Id int `uri:"id"`
+}
+
+type userPath struct {
+	Id string `uri:"id"`
+}
+
+type outboxOperationPath struct {
+	Id uint `uri:"id"`
+}
+
+// Descriptions of the parameters of the lists
+func listDescriptions(filters store.FilterFields, sortable string) map[string]string {
+	return map[string]string{
+		"keyword":   "Rows containing the keyword in any column",
+		"filter":    `Field conditions ("=" or "!="), combined with "and", "or" and parentheses, like role=admin and (email_domain=example.com or has_employee=false). Fields: ` + filters.Describe(),
+		"page":      "Page number, from 1",
+		"page_size": "Rows of a page (50 by default)",
+		"sort":      `Comma separated columns, descending when prefixed by "-". Columns: ` + sortable,
+	}
+}
+
+func newAPIDocs() *openapi.Spec {
+	docs := openapi.New("RBAC API", "1.0.0",
+		"Users, roles, permissions, employees and customers of the RBAC backend. "+
+			"Every /api route except this documentation needs a Firebase ID token as bearer token, "+
+			"and most need a permission (checked by casbin).")
+
+	// Health, metrics and documentation
+	docs.Add(http.MethodGet, "/healthz", openapi.Operation{Tag: "health", Public: true,
+		Summary: "Liveness probe", Response: map[string]string{}})
+	docs.Add(http.MethodGet, "/readyz", openapi.Operation{Tag: "health", Public: true,
+		Summary: "Readiness probe", Description: "503 if the database, the policy or firebase is down.", Response: handlers.Readiness{}})
+	docs.Add(http.MethodGet, "/metrics", openapi.Operation{Tag: "health", Public: true,
+		Summary: "Prometheus metrics", ContentType: "text/plain"})
+	docs.Add(http.MethodGet, "/api/openapi.json", openapi.Operation{Tag: "docs", Public: true,
+		Summary: "This OpenAPI document", Response: map[string]interface{}{}})
+	docs.Add(http.MethodGet, "/api/docs", openapi.Operation{Tag: "docs", Public: true,
+		Summary: "Interactive documentation", ContentType: "text/html"})
+
+	// Users
+	docs.Add(http.MethodGet, "/api/users/unassigned", openapi.Operation{Tag: "users", Permission: "rbac::data read",
+		Summary: "Emails of the users assigned to neither an employee nor a customer", Response: []string{}})
+	docs.Add(http.MethodGet, "/api/users/emails", openapi.Operation{Tag: "users", Permission: "rbac::data read",
+		Summary: "Emails of the users that can be associated with a customer", Response: []string{}})
+	docs.Add(http.MethodGet, "/api/users/", openapi.Operation{Tag: "users", Permission: "rbac::data read",
+		Summary: "Page of the users assigned to an employee, with their role",
+		Query:   models.Filter{}, Response: models.Page[models.FrontendUser]{},
+		Descriptions: listDescriptions(store.UserFilters, "id, full_name, email, role")})
+	docs.Add(http.MethodGet, "/api/users/sync", openapi.Operation{Tag: "users",
+		Summary: "Copy the users of firebase to the database"})
+
+	// Roles
+	docs.Add(http.MethodPut, "/api/roles/", openapi.Operation{Tag: "roles", Permission: "rbac::data write",
+		Summary: "Set the role of a user", Body: models.UpdateRoleRequest{}})
+	docs.Add(http.MethodGet, "/api/roles/", openapi.Operation{Tag: "roles", Permission: "rbac::data read",
+		Summary: "All roles", Response: []models.Role{}})
+	docs.Add(http.MethodPost, "/api/roles/", openapi.Operation{Tag: "roles", Permission: "rbac::data write",
+		Summary: "Add a role, or update its description", Body: models.AddRoleRequest{}})
+	docs.Add(http.MethodDelete, "/api/roles/", openapi.Operation{Tag: "roles", Permission: "rbac::data write",
+		Summary: "Delete a role", Description: "409 role_in_use if users still have the role.", Body: models.DeleteRoleRequest{}})
+
+	// Permissions
+	docs.Add(http.MethodGet, "/api/permissions/", openapi.Operation{Tag: "permissions", Permission: "rbac::data read",
+		Summary: "Every permission by category, and whether the role has it", Query: models.Role{}, Response: models.FrontendPolicy{}})
+	docs.Add(http.MethodPost, "/api/permissions/", openapi.Operation{Tag: "permissions", Permission: "rbac::data write",
+		Summary: "Give a permission to a role", Body: models.AddPermissionRequest{}})
+	docs.Add(http.MethodDelete, "/api/permissions/", openapi.Operation{Tag: "permissions", Permission: "rbac::data write",
+		Summary: "Take a permission from a role", Body: models.DeletePermissionRequest{}})
+
+	// Casbin
+	docs.Add(http.MethodPost, "/api/casbin/permissions", openapi.Operation{Tag: "casbin",
+		Summary:  "Permissions of the current user, including those of their roles",
+		Response: [][]string{}, Description: "Each permission is [subject, object, action]."})
+	docs.Add(http.MethodGet, "/api/casbin/cache", openapi.Operation{Tag: "casbin", Permission: "rbac::data read",
+		Summary: "Counters of the authorization decision cache", Response: authz.Stats{}})
+	docs.Add(http.MethodPost, "/api/v2/authz/check", openapi.Operation{Tag: "v2 authz", Permission: "rbac::data read",
+		Summary: "Whether a subject can do an action on an object, and why",
+		Description: "Decided by the enforcer like the API does (without the decision cache). When allowed, policy is the rule allowing it " +
+			"and role_path the roles leading from the subject to its subject. roles are every role of the subject, " +
+			"and object_policies the rules of the object and action, whoever their subject.",
+		Body: models.AuthzCheck{}, Response: models.AuthzDecision{}})
+	docs.Add(http.MethodPost, "/api/v2/authz/check/batch", openapi.Operation{Tag: "v2 authz", Permission: "rbac::data read",
+		Summary:     "Decide up to 100 checks, like /api/v2/authz/check",
+		Description: "The decisions are in the order of the checks.",
+		Body:        models.AuthzChecks{}, Response: []models.AuthzDecision{}})
+
+	// Employees
+	docs.Add(http.MethodGet, "/api/employees/", openapi.Operation{Tag: "employees", Permission: "rbac::data read",
+		Summary: "Page of employees", Query: models.Filter{}, Response: models.Page[models.Employee]{},
+		Descriptions: listDescriptions(store.EmployeeFilters, "id, full_name")})
+	docs.Add(http.MethodPost, "/api/employees/", openapi.Operation{Tag: "employees", Permission: "rbac::data write",
+		Summary: "Add an employee", Body: models.AddEmployeeRequest{}})
+	docs.Add(http.MethodDelete, "/api/employees/:id", openapi.Operation{Tag: "employees", Permission: "rbac::data write",
+		Summary: "Delete an employee", Description: "The users of the employee are deleted too, in firebase through the outbox.", Path: employeePath{}})
+	docs.Add(http.MethodPut, "/api/employees/", openapi.Operation{Tag: "employees", Permission: "rbac::data write",
+		Summary: "Rename an employee", Body: models.UpdateEmployeeRequest{}})
+	docs.Add(http.MethodPost, "/api/employees/associations/", openapi.Operation{Tag: "employees", Permission: "rbac::data write",
+		Summary: "Assign a user to an employee", Body: models.AddEmployeeUserRequest{}})
+	docs.Add(http.MethodDelete, "/api/employees/associations/", openapi.Operation{Tag: "employees", Permission: "rbac::data write",
+		Summary: "Delete the user of an employee", Body: models.DeleteEmployeeUserRequest{}})
+	docs.Add(http.MethodGet, "/api/employees/associations/:id", openapi.Operation{Tag: "employees", Permission: "rbac::data read",
+		Summary: "Users of an employee", Path: employeePath{}, Response: []models.EmployeeUser{}})
+
+	// Customers
+	docs.Add(http.MethodGet, "/api/customers/", openapi.Operation{Tag: "customers", Permission: "rbac::data read",
+		Summary: "Page of customers", Query: models.Filter{}, Response: models.Page[models.Customer]{},
+		Descriptions: listDescriptions(store.CustomerFilters, "id, full_name")})
+	docs.Add(http.MethodPost, "/api/customers/", openapi.Operation{Tag: "customers", Permission: "rbac::data write",
+		Summary: "Add a customer", Body: models.AddCustomerRequest{}})
+	docs.Add(http.MethodDelete, "/api/customers/:id", openapi.Operation{Tag: "customers", Permission: "rbac::data write",
+		Summary: "Delete a customer", Description: "Users associated only with this customer are deleted too, in firebase through the outbox.", Path: customerPath{}})
+	docs.Add(http.MethodPut, "/api/customers/", openapi.Operation{Tag: "customers", Permission: "rbac::data write",
+		Summary: "Rename a customer", Body: models.UpdateCustomerRequest{}})
+	docs.Add(http.MethodGet, "/api/customers/associations/:id", openapi.Operation{Tag: "customers", Permission: "rbac::data read",
+		Summary: "Users of a customer, with their access to its data", Path: customerPath{}, Response: []models.CustomerUser{}})
+	docs.Add(http.MethodPut, "/api/customers/associations/", openapi.Operation{Tag: "customers", Permission: "rbac::data read",
+		Summary: "Give or take the access of a user to the finance or performance data of a customer", Body: models.ToggleCustomerUserAccessRequest{}})
+	docs.Add(http.MethodPost, "/api/customers/associations/", openapi.Operation{Tag: "customers", Permission: "rbac::data write",
+		Summary: "Associate a user with a customer", Body: models.AddCustomerUserRequest{}})
+	docs.Add(http.MethodDelete, "/api/customers/associations/", openapi.Operation{Tag: "customers", Permission: "rbac::data write",
+		Summary: "Dissociate a user from a customer", Description: "The user is deleted if it has no other customer.", Body: models.DeleteCustomerUserRequest{}})
+
+	// Firebase users
+	docs.Add(http.MethodGet, "/api/firebase/", openapi.Operation{Tag: "firebase", Permission: "rbac::data read",
+		Summary: "Page of users", Query: models.Filter{}, Response: models.Page[models.User]{},
+		Descriptions: listDescriptions(store.UserFilters, "id, email")})
+	docs.Add(http.MethodPost, "/api/firebase/", openapi.Operation{Tag: "firebase", Permission: "rbac::data write",
+		Summary: "Add a user", Description: "The user is saved in the database, and created in firebase through the outbox.", Body: models.AddFirebaseUserRequest{}})
+	docs.Add(http.MethodDelete, "/api/firebase/:id", openapi.Operation{Tag: "firebase", Permission: "rbac::data write",
+		Summary: "Delete a user", Description: "The user is deleted from firebase through the outbox.", Path: userPath{}})
+
+	// Outbox
+	docs.Add(http.MethodGet, "/api/outbox/", openapi.Operation{Tag: "outbox", Permission: "rbac::data read",
+		Summary: "Page of the firebase operations of the outbox", Query: models.OutboxFilter{}, Response: models.Page[models.OutboxOperation]{},
+		Descriptions: listDescriptions(store.OutboxFilters, "id, status, operation, attempts, created_at (newest first by default)")})
+	docs.Add(http.MethodPost, "/api/outbox/:id/retry", openapi.Operation{Tag: "outbox", Permission: "rbac::data write",
+		Summary: "Queue a failed (or stuck) operation again", Path: outboxOperationPath{}})
+
+	addV2Docs(docs)
+	return docs
+}
+
+// addV2Docs documents the routes of registerV2Routes
+func addV2Docs(docs *openapi.Spec) {
+	// Users
+	docs.Add(http.MethodGet, "/api/v2/users", openapi.Operation{Tag: "v2 users", Permission: "rbac::data read",
+		Summary: "Page of users", Query: models.Filter{}, Response: models.Page[models.User]{},
+		Descriptions: listDescriptions(store.UserFilters, "id, email")})
+	docs.Add(http.MethodPost, "/api/v2/users", openapi.Operation{Tag: "v2 users", Permission: "rbac::data write",
+		Summary: "Add a user", Description: "The user is saved in the database, and created in firebase through the outbox.",
+		Body: models.AddFirebaseUserRequest{}, Response: models.User{}, Status: http.StatusAccepted})
+	docs.Add(http.MethodGet, "/api/v2/users/unassigned", openapi.Operation{Tag: "v2 users", Permission: "rbac::data read",
+		Summary: "Emails of the users assigned to neither an employee nor a customer", Response: []string{}})
+	docs.Add(http.MethodGet, "/api/v2/users/emails", openapi.Operation{Tag: "v2 users", Permission: "rbac::data read",
+		Summary: "Emails of the users that can be associated with a customer", Response: []string{}})
+	docs.Add(http.MethodPost, "/api/v2/users/sync", openapi.Operation{Tag: "v2 users", Permission: "rbac::data write",
+		Summary: "Copy the users of firebase to the database", Status: http.StatusNoContent})
+	docs.Add(http.MethodDelete, "/api/v2/users/:uid", openapi.Operation{Tag: "v2 users", Permission: "rbac::data write",
+		Summary: "Delete a user", Description: "The user is deleted from firebase through the outbox.",
+		Path: models.UserPath{}, Status: http.StatusNoContent})
+	docs.Add(http.MethodPut, "/api/v2/users/:uid/role", openapi.Operation{Tag: "v2 users", Permission: "rbac::data write",
+		Summary: "Set the role of a user", Path: models.UserPath{}, Body: models.AssignRoleRequest{}, Status: http.StatusNoContent})
+
+	// Roles and their permissions
+	docs.Add(http.MethodGet, "/api/v2/roles", openapi.Operation{Tag: "v2 roles", Permission: "rbac::data read",
+		Summary: "All roles", Response: []models.Role{}})
+	docs.Add(http.MethodPost, "/api/v2/roles", openapi.Operation{Tag: "v2 roles", Permission: "rbac::data write",
+		Summary: "Add a role, or update its description", Body: models.AddRoleRequest{}, Response: models.Role{}, Status: http.StatusCreated})
+	docs.Add(http.MethodGet, "/api/v2/roles/assignments", openapi.Operation{Tag: "v2 roles", Permission: "rbac::data read",
+		Summary: "Page of the users assigned to an employee, with their role",
+		Query:   models.Filter{}, Response: models.Page[models.FrontendUser]{},
+		Descriptions: listDescriptions(store.UserFilters, "id, full_name, email, role")})
+	docs.Add(http.MethodDelete, "/api/v2/roles/:role", openapi.Operation{Tag: "v2 roles", Permission: "rbac::data write",
+		Summary: "Delete a role", Description: "409 role_in_use if users still have the role.", Path: models.RolePath{}, Status: http.StatusNoContent})
+	docs.Add(http.MethodGet, "/api/v2/roles/:role/permissions", openapi.Operation{Tag: "v2 roles", Permission: "rbac::data read",
+		Summary: "Every permission by category, and whether the role has it", Path: models.RolePath{}, Response: models.FrontendPolicy{}})
+	docs.Add(http.MethodPut, "/api/v2/roles/:role/permissions/:resource/:action", openapi.Operation{Tag: "v2 roles", Permission: "rbac::data write",
+		Summary: "Give a permission to a role", Path: models.RolePermissionPath{}, Status: http.StatusNoContent})
+	docs.Add(http.MethodDelete, "/api/v2/roles/:role/permissions/:resource/:action", openapi.Operation{Tag: "v2 roles", Permission: "rbac::data write",
+		Summary: "Take a permission from a role", Path: models.RolePermissionPath{}, Status: http.StatusNoContent})
+
+	// Employees
+	docs.Add(http.MethodGet, "/api/v2/employees", openapi.Operation{Tag: "v2 employees", Permission: "rbac::data read",
+		Summary: "Page of employees", Query: models.Filter{}, Response: models.Page[models.Employee]{},
+		Descriptions: listDescriptions(store.EmployeeFilters, "id, full_name")})
+	docs.Add(http.MethodPost, "/api/v2/employees", openapi.Operation{Tag: "v2 employees", Permission: "rbac::data write",
+		Summary: "Add an employee", Body: models.AddEmployeeRequest{}, Response: models.Employee{}, Status: http.StatusCreated})
+	docs.Add(http.MethodPut, "/api/v2/employees/:id", openapi.Operation{Tag: "v2 employees", Permission: "rbac::data write",
+		Summary: "Rename an employee", Path: models.EmployeePath{}, Body: models.RenameRequest{}, Response: models.Employee{}})
+	docs.Add(http.MethodDelete, "/api/v2/employees/:id", openapi.Operation{Tag: "v2 employees", Permission: "rbac::data write",
+		Summary: "Delete an employee", Description: "The users of the employee are deleted too, in firebase through the outbox.",
+		Path: models.EmployeePath{}, Status: http.StatusNoContent})
+	docs.Add(http.MethodGet, "/api/v2/employees/:id/users", openapi.Operation{Tag: "v2 employees", Permission: "rbac::data read",
+		Summary: "Users of an employee", Path: models.EmployeePath{}, Response: []models.EmployeeUser{}})
+	docs.Add(http.MethodPost, "/api/v2/employees/:id/users", openapi.Operation{Tag: "v2 employees", Permission: "rbac::data write",
+		Summary: "Assign a user to an employee", Path: models.EmployeePath{}, Body: models.AssociateUserRequest{}, Status: http.StatusNoContent})
+	docs.Add(http.MethodDelete, "/api/v2/employees/:id/users/:uid", openapi.Operation{Tag: "v2 employees", Permission: "rbac::data write",
+		Summary: "Delete the user of an employee", Path: models.EmployeeUserPath{}, Status: http.StatusNoContent})
+
+	// Customers
+	docs.Add(http.MethodGet, "/api/v2/customers", openapi.Operation{Tag: "v2 customers", Permission: "rbac::data read",
+		Summary: "Page of customers", Query: models.Filter{}, Response: models.Page[models.Customer]{},
+		Descriptions: listDescriptions(store.CustomerFilters, "id, full_name")})
+	docs.Add(http.MethodPost, "/api/v2/customers", openapi.Operation{Tag: "v2 customers", Permission: "rbac::data write",
+		Summary: "Add a customer", Body: models.AddCustomerRequest{}, Response: models.Customer{}, Status: http.StatusCreated})
+	docs.Add(http.MethodPut, "/api/v2/customers/:id", openapi.Operation{Tag: "v2 customers", Permission: "rbac::data write",
+		Summary: "Rename a customer", Path: models.CustomerPath{}, Body: models.RenameRequest{}, Response: models.Customer{}})
+	docs.Add(http.MethodDelete, "/api/v2/customers/:id", openapi.Operation{Tag: "v2 customers", Permission: "rbac::data write",
+		Summary: "Delete a customer", Description: "Users associated only with this customer are deleted too, in firebase through the outbox.",
+		Path: models.CustomerPath{}, Status: http.StatusNoContent})
+	docs.Add(http.MethodGet, "/api/v2/customers/:id/users", openapi.Operation{Tag: "v2 customers", Permission: "rbac::data read",
+		Summary: "Users of a customer, with their access to its data", Path: models.CustomerPath{}, Response: []models.CustomerUser{}})
+	docs.Add(http.MethodPost, "/api/v2/customers/:id/users", openapi.Operation{Tag: "v2 customers", Permission: "rbac::data write",
+		Summary: "Associate a user with a customer", Path: models.CustomerPath{}, Body: models.AssociateUserRequest{}, Status: http.StatusNoContent})
+	docs.Add(http.MethodDelete, "/api/v2/customers/:id/users/:uid", openapi.Operation{Tag: "v2 customers", Permission: "rbac::data write",
+		Summary: "Dissociate a user from a customer", Description: "The user is deleted if it has no other customer.",
+		Path: models.CustomerUserPath{}, Status: http.StatusNoContent})
+	docs.Add(http.MethodPut, "/api/v2/customers/:id/users/:uid/access/:object", openapi.Operation{Tag: "v2 customers", Permission: "rbac::data write",
+		Summary: "Give a user access to the finance or performance data of a customer", Path: models.CustomerAccessPath{}, Status: http.StatusNoContent})
+	docs.Add(http.MethodDelete, "/api/v2/customers/:id/users/:uid/access/:object", openapi.Operation{Tag: "v2 customers", Permission: "rbac::data write",
+		Summary: "Take the access of a user to the finance or performance data of a customer", Path: models.CustomerAccessPath{}, Status: http.StatusNoContent})
+
+	// Outbox
+	docs.Add(http.MethodGet, "/api/v2/outbox", openapi.Operation{Tag: "v2 outbox", Permission: "rbac::data read",
+		Summary: "Page of the firebase operations of the outbox", Query: models.OutboxFilter{}, Response: models.Page[models.OutboxOperation]{},
+		Descriptions: listDescriptions(store.OutboxFilters, "id, status, operation, attempts, created_at (newest first by default)")})
+	docs.Add(http.MethodPost, "/api/v2/outbox/:id/retry", openapi.Operation{Tag: "v2 outbox", Permission: "rbac::data write",
+		Summary: "Queue a failed (or stuck) operation again", Path: models.OutboxOperationPath{}, Status: http.StatusNoContent})
+
+	// Import
+	docs.Add(http.MethodPost, "/api/v2/imports", openapi.Operation{Tag: "v2 imports", Permission: "rbac::data write",
+		Summary: "Import customers, employees and customer users from CSV or XLSX files",
+		Description: "A workbook has a sheet of each kind (customers, employees, customer_users), or each kind is a file. " +
+			"Their first row is the header: customers have the columns id and full_name; employees full_name and email (optional); " +
+			"customer_users customer_id, email, finance and performance (yes or no, unchanged if empty). " +
+			"Every row is validated first: if any is invalid, nothing is imported and the report is the details of a 422 import_invalid error. " +
+			"Otherwise all rows are imported together, unless dry_run.",
+		Query: models.ImportOptions{}, Form: models.ImportFiles{}, Response: models.ImportReport{},
+		Descriptions: map[string]string{
+			"dry_run":      "Only validate the rows, and report what would change",
+			"create_users": "Add the users of the emails missing from the database: from firebase, or created in firebase without password",
+		}})
+
+	// Exports
+	for _, export := range []struct {
+		name, summary, rows string
+		response            interface{}
+	}{
+		{models.ExportCustomers, "Export the customers, with their users and their access to finance and performance data",
+			"a row by customer and user", []models.CustomerExport{}},
+		{models.ExportEmployees, "Export the employees, with their users and their roles",
+			"a row by employee and user", []models.EmployeeExport{}},
+		{models.ExportPermissions, "Export the role-permission matrix",
+			"a row by permission and a column by role", models.PermissionMatrix{}},
+	} {
+		docs.Add(http.MethodGet, "/api/v2/exports/"+export.name, openapi.Operation{Tag: "v2 exports", Permission: "rbac::data read",
+			Summary: export.summary,
+			Description: "JSON by default. With format csv or xlsx, the response is the attachment of a file with " + export.rows + ", " +
+				"named after the export and the date (e.g. " + export.name + "-2024-01-31.csv).",
+			Query: models.ExportOptions{}, Response: export.response,
+			Descriptions: map[string]string{"format": "json, csv or xlsx"}})
+	}
+
+	// Policy
+	subject := "Only the rules of this user or role (their first value)"
+	resourcePrefix := "Only the policy rules of the resources starting with this prefix (no role rules)"
+	docs.Add(http.MethodGet, "/api/v2/policy", openapi.Operation{Tag: "v2 policy", Permission: "rbac::data read",
+		Summary: "Export the casbin rules",
+		Description: "JSON by default, the rules by type ({\"p\": [[\"admin\", \"rbac::data\", \"write\"]], \"g\": [[uid, \"admin\"]]}). " +
+			"With format csv or yaml, the response is the attachment of a file: csv has a line of each rule, like the policy files of casbin " +
+			"(p, admin, rbac::data, write); yaml has the rules by type like json.",
+		Query: models.PolicyExportOptions{}, Response: models.PolicyRules{},
+		Descriptions: map[string]string{"subject": subject, "resource_prefix": resourcePrefix, "format": "json, csv or yaml"}})
+	docs.Add(http.MethodPost, "/api/v2/policy/import", openapi.Operation{Tag: "v2 policy", Permission: "rbac::data write",
+		Summary: "Import casbin rules, replacing those of the policy (matching the filter)",
+		Description: "The file is a CSV, JSON or YAML file, as exported. The rules of the policy matching the filter are replaced by those of the file: " +
+			"the diff lists the rules added and removed. Every rule is checked against the model first: if any is invalid (or outside the filter), " +
+			"nothing is imported and the problems are the details of a 422 policy_invalid error. Otherwise all rules change together, unless dry_run.",
+		Query: models.PolicyImportOptions{}, Form: models.PolicyFile{}, Response: models.PolicyDiff{},
+		Descriptions: map[string]string{"subject": subject, "resource_prefix": resourcePrefix, "dry_run": "Only compute the diff"}})
+
+	// Authorization
+	docs.Add(http.MethodGet, "/api/v2/me/permissions", openapi.Operation{Tag: "v2 authz",
+		Summary:  "Permissions of the current user, including those of their roles",
+		Response: [][]string{}, Description: "Each permission is [subject, object, action]."})
+	docs.Add(http.MethodGet, "/api/v2/authz/cache", openapi.Operation{Tag: "v2 authz", Permission: "rbac::data read",
+		Summary: "Counters of the authorization decision cache", Response: authz.Stats{}})
+	docs.Add(http.MethodPost, "/api/v2/authz/check", openapi.Operation{Tag: "v2 authz", Permission: "rbac::data read",
+		Summary: "Whether a subject can do an action on an object, and why",
+		Description: "Decided by the enforcer like the API does (without the decision cache). When allowed, policy is the rule allowing it " +
+			"and role_path the roles leading from the subject to its subject. roles are every role of the subject, " +
+			"and object_policies the rules of the object and action, whoever their subject.",
+		Body: models.AuthzCheck{}, Response: models.AuthzDecision{}})
+	docs.Add(http.MethodPost, "/api/v2/authz/check/batch", openapi.Operation{Tag: "v2 authz", Permission: "rbac::data read",
+		Summary:     "Decide up to 100 checks, like /api/v2/authz/check",
+		Description: "The decisions are in the order of the checks.",
+		Body:        models.AuthzChecks{}, Response: []models.AuthzDecision{}})
+}
@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"backend/store"
+	"encoding/json"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The handlers are only built, never called: the dependencies can be empty
+func testRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	registerRoutes(router, dependencies{store: &store.Store{}})
+	return router
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	for _, route := range testRouter().Routes() {
+		if !apiDocs.Documented(route.Method, route.Path) {
+			t.Errorf("%s %s is not documented: add it to newAPIDocs (routes/docs.go)", route.Method, route.Path)
+		}
+	}
+}
+
+func TestDocumentedRoutesExist(t *testing.T) {
+	registered := map[string]bool{}
+	for _, route := range testRouter().Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for _, route := range apiDocs.Routes() {
+		if !registered[route] {
+			t.Errorf("%s is documented but not registered", route)
+		}
+	}
+}
+
+func TestDocumentIsValidJSON(t *testing.T) {
+	document, err := json.Marshal(apiDocs.Document())
+	if err != nil {
+		t.Fatalf("failed to marshal the OpenAPI document: %v", err)
+	}
+
+	var parsed struct {
+		OpenAPI string                            `json:"openapi"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}
+	if err = json.Unmarshal(document, &parsed); err != nil {
+		t.Fatalf("failed to parse the OpenAPI document: %v", err)
+	}
+	if parsed.OpenAPI == "" || len(parsed.Paths) == 0 {
+		t.Fatalf("the OpenAPI document has no version or no paths")
+	}
+}
@@ -1,24 +1,41 @@
 package routes
 
 import (
+	"backend/apierror"
+	"backend/authz"
 	"backend/config"
 	"backend/handlers"
+	v2 "backend/handlers/v2"
+	"backend/logging"
+	"backend/metrics"
 	"backend/middleware"
+	"backend/openapi"
+	"backend/outbox"
+	"backend/service"
+	"backend/store"
+	"backend/tracing"
+	"backend/validation"
+	"context"
+	"errors"
+	"firebase.google.com/go/auth"
 	"fmt"
 	"github.com/casbin/casbin/v2"
-	gormadapter "github.com/casbin/gorm-adapter/v3"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/contrib/static"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
-	"gorm.io/gorm"
-	"log"
+	"log/slog"
 	"net/http"
+	"path/filepath"
+	"sync"
 )
 
 //SetupRoutes : all the routes are defined here
-func SetupRoutes(db *gorm.DB) {
-	httpRouter := gin.Default()
+// The background workers are started with ctx, and registered in workers: they stop once ctx is cancelled.
+func SetupRoutes(ctx context.Context, cfg *config.Config, st *store.Store, workers *sync.WaitGroup) *gin.Engine {
+	// Requests are traced, and logged as JSON (with their request ID) like everything else.
+	// Errors (and panics) of the next handlers are rendered as the API's error envelope
+	httpRouter := gin.New()
+	httpRouter.Use(logging.Middleware, tracing.Middleware, metrics.HTTP, apierror.Render, apierror.Recovery)
 
 	//CORS
 	cors_conf := cors.DefaultConfig()
@@ -31,33 +48,118 @@ func SetupRoutes(db *gorm.DB) {
 	httpRouter.Use(cors.New(cors_conf))
 	httpRouter.MaxMultipartMemory = 1024 << 20
 
+	// Validate the request payloads against their `binding` tags, with the custom rules needing the database
+	if err := validation.Register(); err != nil {
+		panic(fmt.Sprintf("failed to register validation rules: %v", err))
+	}
+
+	// Export the statistics of the database connection pool
+	sqlDB, err := st.DB().DB()
+	if err != nil {
+		panic(fmt.Sprintf("failed to get database connection pool: %v", err))
+	}
+	metrics.RegisterDB(sqlDB)
+
 	//------
 	//Casbin
 	//------
-	// Initialize  casbin adapter
-	adapter, err := gormadapter.NewAdapterByDB(db)
+	// Initialize casbin enforcer, with its adapter on "casbin_rule"
+	enforcer, err := st.NewEnforcer(cfg.Casbin.ModelFile)
 	if err != nil {
-		panic(fmt.Sprintf("failed to initialize casbin adapter: %v", err))
+		panic(err.Error())
 	}
 
-	// Load models configuration file and policy store adapter
-	enforcer, err := casbin.NewSyncedEnforcer("config/rbac_model.conf", adapter)
+	metrics.RegisterEnforcer(enforcer)
+
+	// Cache the enforcer's decisions, invalidated on every policy change
+	decisions := authz.NewDecisionCache(enforcer, cfg.Casbin.DecisionCacheSize)
+	st.OnPolicyChange(decisions)
+
+	// Keep the enforcer in sync with the changes made by other instances
+	policyWatcher, err := st.NewPolicyWatcher(enforcer, cfg.Casbin.PolicySyncInterval)
 	if err != nil {
-		panic(fmt.Sprintf("failed to create casbin enforcer: %v", err))
+		panic(fmt.Sprintf("failed to create policy watcher: %v", err))
 	}
+	startWorker(workers, func() { policyWatcher.Run(ctx) })
 
 	//--------
 	//Firebase
 	//--------
 	// configure firebase
-	firebaseAuth := config.SetupFirebase()
+	firebaseAuth := config.SetupFirebase(cfg.Firebase)
+
+	// Start the worker executing the firebase operations queued in the outbox
+	// and wake it up every time new operations are committed
+	outboxWorker := outbox.New(st.Outbox, firebaseAuth)
+	st.OnOutboxEnqueued(outboxWorker.Notify)
+	startWorker(workers, func() { outboxWorker.Run(ctx) })
 
-	// set db & firebase auth to gin context with a middleware to all incoming request
+	// set firebase auth to gin context with a middleware to all incoming request
 	httpRouter.Use(func(c *gin.Context) {
-		c.Set("db", db)
 		c.Set("firebaseAuth", firebaseAuth)
 	})
 
+	registerRoutes(httpRouter, dependencies{
+		store:         st,
+		enforcer:      enforcer,
+		decisions:     decisions,
+		policyWatcher: policyWatcher,
+		firebaseAuth:  firebaseAuth,
+	})
+
+	// SERVE FRONTEND
+	if cfg.Env == "prod" {
+		slog.Info("production mode")
+		gin.SetMode(gin.ReleaseMode)
+
+		httpRouter.LoadHTMLGlob(filepath.Join(cfg.Server.FrontendDir, "index.html"))
+
+		httpRouter.NoRoute(func(c *gin.Context) {
+			httpRouter.Use(static.Serve("/", static.LocalFile(cfg.Server.FrontendDir, true)))
+			c.HTML(http.StatusOK, "index.html", nil)
+		})
+	}
+
+	return httpRouter
+}
+
+// dependencies are what the handlers of the routes are built with
+type dependencies struct {
+	store         *store.Store
+	enforcer      *casbin.SyncedEnforcer
+	decisions     *authz.DecisionCache
+	policyWatcher *store.PolicyWatcher
+	firebaseAuth  *auth.Client
+}
+
+// registerRoutes registers the routes of the API. Every route must be documented in apiDocs
+func registerRoutes(httpRouter *gin.Engine, deps dependencies) {
+	//------------
+	//HEALTH AND METRICS ROUTES
+	//------------
+	// Unauthenticated, for the load balancer, the service manager and prometheus
+	httpRouter.GET("/healthz", handlers.Healthz)
+	httpRouter.GET("/metrics", metrics.Handler())
+	httpRouter.GET("/readyz", handlers.Readyz(
+		handlers.HealthCheck{Name: "database", Check: deps.store.Ping},
+		handlers.HealthCheck{Name: "policy", Check: deps.policyWatcher.Check},
+		// Only the client is checked, without calling Firebase: most routes work without it,
+		// so an outage of Firebase must not take every instance out of rotation
+		handlers.HealthCheck{Name: "firebase", Check: func(ctx context.Context) error {
+			if deps.firebaseAuth == nil {
+				return errors.New("firebase credentials not initialised")
+			}
+			return nil
+		}},
+	))
+
+	// The documentation of the API, public like the health routes
+	httpRouter.GET("/api/openapi.json", apiDocs.Handler())
+	httpRouter.GET("/api/docs", openapi.UI("RBAC API", "/api/openapi.json"))
+
+	// The operations of the API, shared by v1 and v2
+	services := service.New(deps.store, deps.enforcer, deps.firebaseAuth)
+
 	apiRoutes := httpRouter.Group("/api", middleware.AuthMiddleware)
 
 	//------------
@@ -65,10 +167,10 @@ func SetupRoutes(db *gorm.DB) {
 	//------------
 	userProtectedRoutes := apiRoutes.Group("/users") //, middleware.AuthMiddleware
 	{
-		userProtectedRoutes.GET("/unassigned", middleware.Authorize("rbac::data", "read", enforcer), handlers.GetUnassignedUsers())
-		userProtectedRoutes.GET("/emails", middleware.Authorize("rbac::data", "read", enforcer), handlers.GetUsersEmails())
-		userProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", enforcer), handlers.GetAllUsers())
-		userProtectedRoutes.GET("/sync", handlers.SyncUsersWithFirebase())
+		userProtectedRoutes.GET("/unassigned", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.GetUnassignedUsers(services.Users))
+		userProtectedRoutes.GET("/emails", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.GetUsersEmails(services.Users))
+		userProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.GetAllUsers(services.Users))
+		userProtectedRoutes.GET("/sync", handlers.SyncUsersWithFirebase(services.Users))
 	}
 
 	//------------
@@ -76,10 +178,10 @@ func SetupRoutes(db *gorm.DB) {
 	//------------
 	roleProtectedRoutes := apiRoutes.Group("/roles")
 	{
-		roleProtectedRoutes.PUT("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.UpdateRole(enforcer))
-		roleProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", enforcer), handlers.GetAllRoles())
-		roleProtectedRoutes.POST("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.AddRole())
-		roleProtectedRoutes.DELETE("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.DeleteRole(enforcer))
+		roleProtectedRoutes.PUT("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.UpdateRole(services.Roles))
+		roleProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.GetAllRoles(services.Roles))
+		roleProtectedRoutes.POST("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.AddRole(services.Roles))
+		roleProtectedRoutes.DELETE("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.DeleteRole(services.Roles))
 	}
 
 	//------------
@@ -87,9 +189,9 @@ func SetupRoutes(db *gorm.DB) {
 	//------------
 	permissionProtectedRoutes := apiRoutes.Group("/permissions")
 	{
-		permissionProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", enforcer), handlers.GetPermissionsForRole(enforcer))
-		permissionProtectedRoutes.POST("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.AddPermission(enforcer))
-		permissionProtectedRoutes.DELETE("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.DeletePermission(enforcer))
+		permissionProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.GetPermissionsForRole(services.Permissions))
+		permissionProtectedRoutes.POST("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.AddPermission(services.Permissions))
+		permissionProtectedRoutes.DELETE("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.DeletePermission(services.Permissions))
 	}
 
 	//------------
@@ -97,7 +199,8 @@ func SetupRoutes(db *gorm.DB) {
 	//------------
 	casbinProtectedRoutes := apiRoutes.Group("/casbin")
 	{
-		casbinProtectedRoutes.POST("/permissions", handlers.GetFrontendPermission(enforcer))
+		casbinProtectedRoutes.POST("/permissions", handlers.GetFrontendPermission(deps.decisions))
+		casbinProtectedRoutes.GET("/cache", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.GetDecisionCacheStats(deps.decisions))
 	}
 
 	//------------
@@ -105,16 +208,16 @@ func SetupRoutes(db *gorm.DB) {
 	//------------
 	employeesProtectedRoutes := apiRoutes.Group("/employees")
 	{
-		employeesProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", enforcer), handlers.GetAllEmployees())
-		employeesProtectedRoutes.POST("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.AddEmployee())
-		employeesProtectedRoutes.DELETE("/:id", middleware.Authorize("rbac::data", "write", enforcer), handlers.DeleteEmployee(enforcer))
-		employeesProtectedRoutes.PUT("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.UpdateEmployee())
+		employeesProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.GetAllEmployees(services.Employees))
+		employeesProtectedRoutes.POST("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.AddEmployee(services.Employees))
+		employeesProtectedRoutes.DELETE("/:id", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.DeleteEmployee(services.Employees))
+		employeesProtectedRoutes.PUT("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.UpdateEmployee(services.Employees))
 
 		associations := employeesProtectedRoutes.Group("/associations")
 		{
-			associations.POST("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.AddAssociation())
-			associations.DELETE("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.DeleteAssociation())
-			associations.GET("/:id", middleware.Authorize("rbac::data", "read", enforcer), handlers.GetEmployeeUsers())
+			associations.POST("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.AddAssociation(services.Employees))
+			associations.DELETE("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.DeleteAssociation(services.Employees))
+			associations.GET("/:id", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.GetEmployeeUsers(services.Employees))
 		}
 
 	}
@@ -124,17 +227,17 @@ func SetupRoutes(db *gorm.DB) {
 	//------------
 	customersProtectedRoutes := apiRoutes.Group("/customers")
 	{
-		customersProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", enforcer), handlers.GetAllCustomers())
-		customersProtectedRoutes.POST("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.AddCustomer())
-		customersProtectedRoutes.DELETE("/:id", middleware.Authorize("rbac::data", "write", enforcer), handlers.DeleteCustomer(enforcer))
-		customersProtectedRoutes.PUT("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.UpdateCustomer())
+		customersProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.GetAllCustomers(services.Customers))
+		customersProtectedRoutes.POST("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.AddCustomer(services.Customers))
+		customersProtectedRoutes.DELETE("/:id", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.DeleteCustomer(services.Customers))
+		customersProtectedRoutes.PUT("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.UpdateCustomer(services.Customers))
 
 		associations := customersProtectedRoutes.Group("/associations")
 		{
-			associations.GET("/:id", middleware.Authorize("rbac::data", "read", enforcer), handlers.GetCustomerUsers(enforcer))
-			associations.PUT("/", middleware.Authorize("rbac::data", "read", enforcer), handlers.ToggleCustomerUserAccess(enforcer))
-			associations.POST("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.AddCustomerUserAssociation(enforcer))
-			associations.DELETE("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.DeleteCustomerUserAssociation(enforcer))
+			associations.GET("/:id", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.GetCustomerUsers(services.Customers))
+			associations.PUT("/", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.ToggleCustomerUserAccess(services.Customers))
+			associations.POST("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.AddCustomerUserAssociation(services.Customers))
+			associations.DELETE("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.DeleteCustomerUserAssociation(services.Customers))
 		}
 	}
 
@@ -143,31 +246,113 @@ func SetupRoutes(db *gorm.DB) {
 	//------------
 	firebaseProtectedRoutes := apiRoutes.Group("/firebase")
 	{
-		firebaseProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", enforcer), handlers.GetAllFirebaseUsers())
-		firebaseProtectedRoutes.POST("/", middleware.Authorize("rbac::data", "write", enforcer), handlers.AddFirebaseUser())
-		firebaseProtectedRoutes.DELETE("/:id", middleware.Authorize("rbac::data", "write", enforcer), handlers.DeleteFirebaseUser(enforcer))
+		firebaseProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.GetAllFirebaseUsers(services.Users))
+		firebaseProtectedRoutes.POST("/", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.AddFirebaseUser(services.Users))
+		firebaseProtectedRoutes.DELETE("/:id", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.DeleteFirebaseUser(services.Users))
 	}
 
-	// SERVE FRONTEND
-	if config.ENV("APP_ENV") == "prod" {
-		fmt.Println("Production mode")
-		log.Println("Production mode")
-		gin.SetMode(gin.ReleaseMode)
+	//------------
+	//OUTBOX ROUTES
+	//------------
+	outboxProtectedRoutes := apiRoutes.Group("/outbox")
+	{
+		outboxProtectedRoutes.GET("/", middleware.Authorize("rbac::data", "read", deps.decisions), handlers.GetOutboxOperations(services.Outbox))
+		outboxProtectedRoutes.POST("/:id/retry", middleware.Authorize("rbac::data", "write", deps.decisions), handlers.RetryOutboxOperation(services.Outbox))
+	}
+
+	registerV2Routes(apiRoutes.Group("/v2"), services, deps)
+}
 
-		httpRouter.LoadHTMLGlob("../frontend/build/index.html")
+// registerV2Routes registers the routes of /api/v2, side by side with v1:
+// resources are identified by their path, and no DELETE has a body
+func registerV2Routes(v2Routes *gin.RouterGroup, services *service.Service, deps dependencies) {
+	read := middleware.Authorize("rbac::data", "read", deps.decisions)
+	write := middleware.Authorize("rbac::data", "write", deps.decisions)
 
-		httpRouter.NoRoute(func(c *gin.Context) {
-			httpRouter.Use(static.Serve("/", static.LocalFile("../frontend/build", true)))
-			c.HTML(http.StatusOK, "index.html", nil)
-		})
+	users := v2Routes.Group("/users")
+	{
+		users.GET("", read, v2.ListUsers(services.Users))
+		users.POST("", write, v2.CreateUser(services.Users))
+		users.GET("/unassigned", read, v2.ListUnassignedEmails(services.Users))
+		users.GET("/emails", read, v2.ListCustomerEmails(services.Users))
+		users.POST("/sync", write, v2.SyncUsers(services.Users))
+		users.DELETE("/:uid", write, v2.DeleteUser(services.Users))
+		users.PUT("/:uid/role", write, v2.AssignRole(services.Roles))
 	}
 
-	var envs map[string]string
-	envs, err = godotenv.Read("./info/.env")
-	if err != nil {
-		log.Fatal("Error loading .env file")
+	roles := v2Routes.Group("/roles")
+	{
+		roles.GET("", read, v2.ListRoles(services.Roles))
+		roles.POST("", write, v2.CreateRole(services.Roles))
+		roles.GET("/assignments", read, v2.ListRoleAssignments(services.Users))
+		roles.DELETE("/:role", write, v2.DeleteRole(services.Roles))
+		roles.GET("/:role/permissions", read, v2.GetRolePermissions(services.Permissions))
+		roles.PUT("/:role/permissions/:resource/:action", write, v2.AddRolePermission(services.Permissions))
+		roles.DELETE("/:role/permissions/:resource/:action", write, v2.DeleteRolePermission(services.Permissions))
+	}
+
+	employees := v2Routes.Group("/employees")
+	{
+		employees.GET("", read, v2.ListEmployees(services.Employees))
+		employees.POST("", write, v2.CreateEmployee(services.Employees))
+		employees.PUT("/:id", write, v2.RenameEmployee(services.Employees))
+		employees.DELETE("/:id", write, v2.DeleteEmployee(services.Employees))
+		employees.GET("/:id/users", read, v2.ListEmployeeUsers(services.Employees))
+		employees.POST("/:id/users", write, v2.AddEmployeeUser(services.Employees))
+		employees.DELETE("/:id/users/:uid", write, v2.DeleteEmployeeUser(services.Employees))
+	}
+
+	customers := v2Routes.Group("/customers")
+	{
+		customers.GET("", read, v2.ListCustomers(services.Customers))
+		customers.POST("", write, v2.CreateCustomer(services.Customers))
+		customers.PUT("/:id", write, v2.RenameCustomer(services.Customers))
+		customers.DELETE("/:id", write, v2.DeleteCustomer(services.Customers))
+		customers.GET("/:id/users", read, v2.ListCustomerUsers(services.Customers))
+		customers.POST("/:id/users", write, v2.AddCustomerUser(services.Customers))
+		customers.DELETE("/:id/users/:uid", write, v2.DeleteCustomerUser(services.Customers))
+		customers.PUT("/:id/users/:uid/access/:object", write, v2.SetCustomerUserAccess(services.Customers, true))
+		customers.DELETE("/:id/users/:uid/access/:object", write, v2.SetCustomerUserAccess(services.Customers, false))
+	}
+
+	outbox := v2Routes.Group("/outbox")
+	{
+		outbox.GET("", read, v2.ListOutboxOperations(services.Outbox))
+		outbox.POST("/:id/retry", write, v2.RetryOutboxOperation(services.Outbox))
 	}
-	port := envs["APIPORT"]
-	httpRouter.Run(":" + port)
 
+	// Bulk import of customers, employees and customer users from CSV or XLSX files
+	v2Routes.POST("/imports", write, v2.Import(services.Imports))
+
+	// Exports as JSON, CSV or XLSX, for account managers and auditors
+	exports := v2Routes.Group("/exports")
+	{
+		exports.GET("/customers", read, v2.Export(services.Exports, "customers"))
+		exports.GET("/employees", read, v2.Export(services.Exports, "employees"))
+		exports.GET("/permissions", read, v2.Export(services.Exports, "permissions"))
+	}
+
+	// The casbin rules, to move them between environments
+	policy := v2Routes.Group("/policy")
+	{
+		policy.GET("", read, v2.ExportPolicy(services.Policies))
+		policy.POST("/import", write, v2.ImportPolicy(services.Policies))
+	}
+
+	// The permissions of the current user, and the counters of the decision cache
+	v2Routes.GET("/me/permissions", handlers.GetFrontendPermission(deps.decisions))
+	v2Routes.GET("/authz/cache", read, handlers.GetDecisionCacheStats(deps.decisions))
+
+	// Why a subject can (or cannot) do an action on an object
+	v2Routes.POST("/authz/check", read, v2.CheckAuthorization(services.Authorization))
+	v2Routes.POST("/authz/check/batch", read, v2.CheckAuthorizations(services.Authorization))
+}
+
+// startWorker runs worker in the background, registered in workers
+func startWorker(workers *sync.WaitGroup, worker func()) {
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		worker()
+	}()
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"backend/config"
+	"backend/database/migrate"
+	"backend/routes"
+	"backend/store"
+	"backend/tracing"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// serve runs the server until SIGINT or SIGTERM, then shuts down in order:
+// in-flight requests are drained, then the background workers are stopped.
+// The caller closes the database pool afterwards.
+func serve(cfg *config.Config, st *store.Store) error {
+	// Refuse to serve with an outdated schema
+	if err := migrate.Check(st.DB()); err != nil {
+		return err
+	}
+
+	// Spans are flushed once everything else has stopped
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Options{
+		ServiceName: cfg.Tracing.ServiceName,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	router := routes.SetupRoutes(workersCtx, cfg, st, &workers)
+
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Server.Port),
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("listening", slog.String("addr", server.Addr))
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serverErr:
+		// The server could not start (e.g. port already in use)
+	case <-signals.Done():
+		slog.Info("shutting down: draining in-flight requests")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		err = server.Shutdown(shutdownCtx)
+		cancel()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	slog.Info("shutting down: stopping background workers")
+	stopWorkers()
+	workers.Wait()
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	if flushErr := shutdownTracing(flushCtx); flushErr != nil {
+		slog.Error("failed to flush traces", slog.Any("error", flushErr))
+	}
+	cancel()
+	slog.Info("shutdown complete")
+	return err
+}
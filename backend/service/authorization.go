@@ -0,0 +1,88 @@
+package service
+
+import (
+	"backend/models"
+	"github.com/casbin/casbin/v2"
+)
+
+// Authorization explains the decisions of the enforcer, for support and security
+type Authorization struct {
+	enforcer *casbin.SyncedEnforcer
+}
+
+// Check decides whether check.Subject can do check.Action on check.Object like the API does (without the decision cache),
+// with the policy rule allowing it and the roles leading to it
+func (s *Authorization) Check(check models.AuthzCheck) (models.AuthzDecision, error) {
+	var decision = models.AuthzDecision{Subject: check.Subject, Object: check.Object, Action: check.Action,
+		Policy: []string{}, RolePath: []string{}}
+
+	allowed, policy, err := s.enforcer.EnforceEx(check.Subject, check.Object, check.Action)
+	if err != nil {
+		return decision, err
+	}
+	decision.Allowed = allowed
+	if allowed && len(policy) > 0 {
+		decision.Policy = policy
+		if decision.RolePath, err = s.rolePath(check.Subject, policy[0]); err != nil {
+			return decision, err
+		}
+	}
+
+	if decision.Roles, err = s.enforcer.GetImplicitRolesForUser(check.Subject); err != nil {
+		return decision, err
+	}
+	if decision.Roles == nil {
+		decision.Roles = []string{}
+	}
+	if decision.ObjectPolicies, err = s.enforcer.GetFilteredPolicy(1, check.Object, check.Action); err != nil {
+		return decision, err
+	}
+	if decision.ObjectPolicies == nil {
+		decision.ObjectPolicies = [][]string{}
+	}
+	return decision, nil
+}
+
+// CheckAll decides each check, in order
+func (s *Authorization) CheckAll(checks []models.AuthzCheck) ([]models.AuthzDecision, error) {
+	decisions := make([]models.AuthzDecision, 0, len(checks))
+	for _, check := range checks {
+		decision, err := s.Check(check)
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions, nil
+}
+
+// rolePath returns the shortest path from subject to role through the role rules, starting with subject
+// (only subject if they are the same)
+func (s *Authorization) rolePath(subject string, role string) ([]string, error) {
+	previous := map[string]string{subject: ""}
+	queue := []string{subject}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == role {
+			var path []string
+			for name := role; name != ""; name = previous[name] {
+				path = append([]string{name}, path...)
+			}
+			return path, nil
+		}
+
+		roles, err := s.enforcer.GetRolesForUser(current)
+		if err != nil {
+			return nil, err
+		}
+		for _, next := range roles {
+			if _, seen := previous[next]; !seen {
+				previous[next] = current
+				queue = append(queue, next)
+			}
+		}
+	}
+	// The policy matched without a role rule leading to it
+	return []string{subject}, nil
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/store"
+	"context"
+	"fmt"
+	"github.com/casbin/casbin/v2"
+)
+
+type Customers struct {
+	store    *store.Store
+	enforcer *casbin.SyncedEnforcer
+}
+
+// List returns a page of customers, and the number of customers matching filter
+func (s *Customers) List(ctx context.Context, filter models.Filter) ([]models.Customer, int64, error) {
+	return s.store.Customers.List(ctx, filter)
+}
+
+// Create adds a customer, with the id given (its podio id)
+func (s *Customers) Create(ctx context.Context, customer *models.Customer) error {
+	return s.store.Customers.Create(ctx, customer)
+}
+
+// Rename updates the name of a customer
+func (s *Customers) Rename(ctx context.Context, customer *models.Customer) error {
+	return s.store.Customers.Rename(ctx, customer)
+}
+
+// Delete deletes a customer with the permissions of its users to its data.
+// Users associated only with this customer are deleted too, and their deletion from firebase is queued in the outbox
+func (s *Customers) Delete(ctx context.Context, id int) error {
+	if err := mustExistCustomer(ctx, s.store.Customers, id); err != nil {
+		return err
+	}
+	var customer = models.Customer{Id: id}
+
+	// All database and casbin changes are committed together (or not at all)
+	// Firebase users are queued for deletion in the outbox, in the same transaction
+	return s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		// Fetch all users associated with this customer podio id in "users" table
+		customerUsers, err := uow.Customers.Users(ctx, &customer)
+		if err != nil {
+			return err
+		}
+
+		// First check if user associates with multiple customers
+		// If true, do NOT delete him
+		// If false, delete him completely
+
+		// Delete firebase users that are only associated with this customer
+		// Keep those users that have one association, to delete them after
+		var usersToDelete []models.User
+		for _, user := range customerUsers {
+			if uow.Users.CountCustomers(ctx, &user) == 1 {
+				usersToDelete = append(usersToDelete, user)
+			}
+		}
+
+		// Clear all customer's associations from "customer_user" table (deletes all associations with customer_id = this customer's id)
+		err = uow.Customers.ClearUsers(ctx, &customer)
+		if err != nil {
+			return err
+		}
+
+		//
+		// Delete permissions from "casbin_rule" table in DB, using casbin
+		// For example, for customer 1996, delete "portal::data::1996::finance" and "portal::data::1996::performance" permissions, if exist
+		//
+		for _, user := range customerUsers {
+			// Remove financial policy for user, if exists
+			err = uow.Policies.RemovePolicy(user.Id, customerData(customer.Id, models.AccessFinance), "read")
+			if err != nil {
+				return err
+			}
+			// Remove performance policy for user, if exists
+			err = uow.Policies.RemovePolicy(user.Id, customerData(customer.Id, models.AccessPerformance), "read")
+			if err != nil {
+				return err
+			}
+		}
+
+		// Delete customer from "customers" table
+		err = uow.Customers.Delete(ctx, &customer)
+		if err != nil {
+			return err
+		}
+
+		//
+		// If there are users that need to be completely deleted
+		//
+		if usersToDelete != nil {
+			// Delete those users from "users" table
+			err = uow.Users.Delete(ctx, usersToDelete...)
+			if err != nil {
+				return err
+			}
+
+			// Foreach user of deleted customer
+			for _, userToDelete := range usersToDelete {
+				// Delete user from "casbin_rule"
+				err = uow.Policies.DeleteUser(userToDelete.Id)
+				if err != nil {
+					return err
+				}
+
+				// Delete user from firebase, through the outbox
+				err = deleteFirebaseUser(ctx, uow, userToDelete.Id)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Users returns the users of a customer, with their access to its data
+func (s *Customers) Users(ctx context.Context, id int) ([]models.CustomerUser, error) {
+	if err := mustExistCustomer(ctx, s.store.Customers, id); err != nil {
+		return nil, err
+	}
+	users, err := s.store.Customers.AssociatedUsers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, user := range users {
+		permissions, err := s.enforcer.GetImplicitPermissionsForUser(user.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, permission := range permissions {
+			if permission[1] == customerData(id, models.AccessPerformance) {
+				users[i].HasPerformanceAccess = true
+			}
+			if permission[1] == customerData(id, models.AccessFinance) {
+				users[i].HasFinancialAccess = true
+			}
+		}
+	}
+
+	return users, nil
+}
+
+// AddUser associates the user of email with a customer, and registers it as a customer.
+// It fails with user_not_found if there is no user with email, and with association_exists if they are already associated
+func (s *Customers) AddUser(ctx context.Context, id int, email string) error {
+	if err := mustExistCustomer(ctx, s.store.Customers, id); err != nil {
+		return err
+	}
+	var customer = models.Customer{Id: id}
+
+	//Get user associated with passed user's email
+	user, err := findByEmail(ctx, s.store.Users, email)
+	if err != nil {
+		return err
+	}
+
+	// Check if customerId - userId association already exists in customer_user table
+	// If true return message, if false add new association to customer_user
+	exists, err := s.store.Customers.HasUser(ctx, customer.Id, user.Id)
+	if err != nil {
+		return err
+	}
+	// Association already exists
+	if exists {
+		return apierror.Conflict(apierror.CodeAssociationExists, "This association already exists!\nPlease select another email.").
+			WithDetails(map[string]interface{}{"customer_id": customer.Id, "user_id": user.Id})
+	}
+	return s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		// Association does not exist, so add new association to customer_user table in database
+		err := uow.Customers.AddUser(ctx, &customer, &user)
+		if err != nil {
+			return err
+		}
+
+		// Register user as a customer (nothing happens if the user already has the permission)
+		return uow.Policies.AddPolicy(user.Id, "portal::data::customer", "read")
+	})
+}
+
+// RemoveUser dissociates a user from a customer, taking its access to the customer's data.
+// A user left without customers is deleted, and its deletion from firebase is queued in the outbox
+func (s *Customers) RemoveUser(ctx context.Context, id int, uid string) error {
+	if err := mustExistCustomer(ctx, s.store.Customers, id); err != nil {
+		return err
+	}
+	// Make sure user exists in database
+	err := mustExist(ctx, s.store.Users, uid, "Please select an existing user to delete.")
+	if err != nil {
+		return err
+	}
+
+	// Initialize customer with given id
+	var customer = models.Customer{Id: id}
+	// Initialize user with given id
+	var user = models.User{Id: uid}
+
+	// All database and casbin changes are committed together (or not at all)
+	// Firebase user is queued for deletion in the outbox, in the same transaction
+	return s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		// First check if user associates with multiple customers
+		// If true, do NOT delete him
+		// If false, delete him completely
+		associatedCustomers, err := uow.Users.Customers(ctx, user.Id)
+		if err != nil {
+			return err
+		}
+
+		//Delete user association with this customer from "customer_user" table in DB
+		err = uow.Customers.RemoveUser(ctx, &customer, &user)
+		if err != nil {
+			return err
+		}
+
+		//
+		// Delete user's permissions, specifically for this customer
+		//
+		// Remove financial policy from user, if exists
+		err = uow.Policies.RemovePolicy(user.Id, customerData(customer.Id, models.AccessFinance), "read")
+		if err != nil {
+			return err
+		}
+		// Remove performance policy from user, if exists
+		err = uow.Policies.RemovePolicy(user.Id, customerData(customer.Id, models.AccessPerformance), "read")
+		if err != nil {
+			return err
+		}
+
+		// User is associated only with this customer
+		// So delete user completely
+		if !(len(associatedCustomers) > 1) {
+			// User is not associated with any customer anymore
+			// Also remove general financial and performance access from user
+			for _, permission := range []string{"portal::data::customer", "portal::data::customer::finance", "portal::data::customer::performance"} {
+				err = uow.Policies.RemovePolicy(user.Id, permission, "read")
+				if err != nil {
+					return err
+				}
+			}
+
+			// Delete user from "users" table
+			err = uow.Users.Delete(ctx, user)
+			if err != nil {
+				return err
+			}
+
+			// Delete user from firebase, through the outbox
+			return deleteFirebaseUser(ctx, uow, user.Id)
+		}
+
+		// User is also associated with other customers
+		var hasFinancialAccessToAnotherCustomer = false
+		var hasPerformanceAccessToAnotherCustomer = false
+		for _, associatedCustomer := range associatedCustomers {
+			// Ignore current customer
+			if associatedCustomer.Id != customer.Id {
+				hasFinancialAccess, err := uow.Policies.HasPolicy(user.Id, customerData(associatedCustomer.Id, models.AccessFinance), "read")
+				if err != nil {
+					return err
+				}
+				hasPerformanceAccess, err := uow.Policies.HasPolicy(user.Id, customerData(associatedCustomer.Id, models.AccessPerformance), "read")
+				if err != nil {
+					return err
+				}
+				hasFinancialAccessToAnotherCustomer = hasFinancialAccessToAnotherCustomer || hasFinancialAccess
+				hasPerformanceAccessToAnotherCustomer = hasPerformanceAccessToAnotherCustomer || hasPerformanceAccess
+			}
+		}
+
+		if !hasFinancialAccessToAnotherCustomer {
+			err = uow.Policies.RemovePolicy(user.Id, "portal::data::customer::finance", "read")
+			if err != nil {
+				return err
+			}
+		}
+		if !hasPerformanceAccessToAnotherCustomer {
+			err = uow.Policies.RemovePolicy(user.Id, "portal::data::customer::performance", "read")
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetAccess gives (or takes) the access of a user to the finance or performance data (object) of a customer.
+// It fails with customer_not_found or user_not_found if the customer or the user does not exist
+func (s *Customers) SetAccess(ctx context.Context, id int, uid string, object string, hasAccess bool) error {
+	if err := mustExistCustomer(ctx, s.store.Customers, id); err != nil {
+		return err
+	}
+	// Make sure user exists in database
+	err := mustExist(ctx, s.store.Users, uid, "Please save an email before choosing permission!")
+	if err != nil {
+		return err
+	}
+
+	// Permissions for specific customer, and for general financial or performance access
+	return s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		for _, permissionName := range []string{customerData(id, object), fmt.Sprintf("portal::data::customer::%s", object)} {
+			var err error
+			if hasAccess {
+				// Add permission, if not exists
+				err = uow.Policies.AddPolicy(uid, permissionName, "read")
+			} else {
+				// Remove permission, if exists
+				err = uow.Policies.RemovePolicy(uid, permissionName, "read")
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// mustExistCustomer fails with customer_not_found if the customer with id does not exist
+func mustExistCustomer(ctx context.Context, customers store.CustomerStore, id int) error {
+	exists, err := customers.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apierror.NotFound(apierror.CodeCustomerNotFound, fmt.Sprintf("There is no customer %d.", id))
+	}
+	return nil
+}
+
+// customerData is the resource of the finance or performance data of a customer, like "portal::data::1996::finance"
+func customerData(id int, object string) string {
+	return fmt.Sprintf("portal::data::%d::%s", id, object)
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"backend/models"
+	"backend/store"
+	"context"
+	"github.com/casbin/casbin/v2"
+)
+
+type Employees struct {
+	store    *store.Store
+	enforcer *casbin.SyncedEnforcer
+}
+
+// List returns a page of employees, and the number of employees matching filter
+func (s *Employees) List(ctx context.Context, filter models.Filter) ([]models.Employee, int64, error) {
+	return s.store.Employees.List(ctx, filter)
+}
+
+// Create adds an employee, its id being given by the database
+func (s *Employees) Create(ctx context.Context, employee *models.Employee) error {
+	return s.store.Employees.Create(ctx, employee)
+}
+
+// Rename updates the name of an employee
+func (s *Employees) Rename(ctx context.Context, employee *models.Employee) error {
+	return s.store.Employees.Rename(ctx, employee)
+}
+
+// Delete deletes an employee with its users, whose deletion from firebase is queued in the outbox
+func (s *Employees) Delete(ctx context.Context, id int) error {
+	var employee = models.Employee{Id: id}
+
+	// All database and casbin changes are committed together (or not at all)
+	// Firebase users are queued for deletion in the outbox, in the same transaction
+	return s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		// Fetch all users associated with this employee id in "users" table
+		employeeUsers, err := uow.Employees.Users(ctx, &employee)
+		if err != nil {
+			return err
+		}
+
+		// If employee was associated with a user
+		if len(employeeUsers) > 0 {
+			// Clear employee's associations from "users" table (sets employee_id as null)
+			err = uow.Employees.ClearUsers(ctx, &employee)
+			if err != nil {
+				return err
+			}
+			// Delete users from "users" table
+			err = uow.Users.Delete(ctx, employeeUsers...)
+			if err != nil {
+				return err
+			}
+
+			//
+			// Delete user's (or users') permissions from "casbin_rule" table in DB, using casbin
+			// Also delete user's (or users') from firebase
+			//
+			for _, user := range employeeUsers {
+				// Delete user from "casbin_rule"
+				err = uow.Policies.DeleteUser(user.Id)
+				if err != nil {
+					return err
+				}
+
+				// Delete user from firebase, through the outbox
+				err = deleteFirebaseUser(ctx, uow, user.Id)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Delete employee with id = employeeId, from "employees" table
+		return uow.Employees.Delete(ctx, &employee)
+	})
+}
+
+// Users returns the users of an employee
+func (s *Employees) Users(ctx context.Context, id int) ([]models.EmployeeUser, error) {
+	return s.store.Employees.AssociatedUsers(ctx, id)
+}
+
+// AddUser assigns the user of email to an employee. It fails with user_not_found if there is no user with email
+func (s *Employees) AddUser(ctx context.Context, id int, email string) error {
+	//Get user associated with passed user's email
+	user, err := findByEmail(ctx, s.store.Users, email)
+	if err != nil {
+		return err
+	}
+
+	// Add association to users table in database
+	return s.store.Employees.AddUser(ctx, &models.Employee{Id: id}, &user)
+}
+
+// RemoveUser deletes the user of an employee, with its permissions, and queues its deletion from firebase in the outbox
+func (s *Employees) RemoveUser(ctx context.Context, id int, uid string) error {
+	// Make sure user exists in database
+	err := mustExist(ctx, s.store.Users, uid, "Please select an existing user to delete.")
+	if err != nil {
+		return err
+	}
+
+	// Initialize employee with given id
+	var employee = models.Employee{Id: id}
+	// Initialize user with given id
+	var user = models.User{Id: uid}
+
+	return s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		// Remove association from users table in database
+		err := uow.Employees.RemoveUser(ctx, &employee, &user)
+		if err != nil {
+			return err
+		}
+
+		// Delete user from "users" table
+		err = uow.Users.Delete(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		// Remove user's role and permissions from "casbin_rule"
+		err = uow.Policies.DeleteUser(user.Id)
+		if err != nil {
+			return err
+		}
+
+		// Delete user from firebase, through the outbox
+		return deleteFirebaseUser(ctx, uow, user.Id)
+	})
+}
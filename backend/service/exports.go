@@ -0,0 +1,218 @@
+package service
+
+import (
+	"backend/models"
+	"backend/store"
+	"context"
+	"fmt"
+	"github.com/casbin/casbin/v2"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Exports struct {
+	store    *store.Store
+	enforcer *casbin.SyncedEnforcer
+}
+
+// Export is the data of an export, rendered as JSON (Data) or as a sheet (Rows, the header first)
+type Export struct {
+	Data interface{}
+	Rows [][]string
+}
+
+// Export returns the export name (models.ExportCustomers, models.ExportEmployees or models.ExportPermissions)
+func (s *Exports) Export(ctx context.Context, name string) (Export, error) {
+	switch name {
+	case models.ExportCustomers:
+		customers, err := s.Customers(ctx)
+		return Export{Data: customers, Rows: customerRows(customers)}, err
+	case models.ExportEmployees:
+		employees, err := s.Employees(ctx)
+		return Export{Data: employees, Rows: employeeRows(employees)}, err
+	case models.ExportPermissions:
+		matrix, err := s.Permissions(ctx)
+		return Export{Data: matrix, Rows: permissionRows(matrix)}, err
+	}
+	return Export{}, fmt.Errorf("unknown export %q", name)
+}
+
+// Customers returns every customer with its users, and their access to its data (as Customers.Users does for one)
+func (s *Exports) Customers(ctx context.Context) ([]models.CustomerExport, error) {
+	customers, err := s.store.Customers.ListWithUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Objects each user has access to (users may be associated with several customers)
+	objects := map[string]map[string]bool{}
+	exports := make([]models.CustomerExport, 0, len(customers))
+	for _, customer := range customers {
+		export := models.CustomerExport{Id: customer.Id, FullName: customer.FullName, Users: []models.CustomerUser{}}
+		for _, user := range customer.Users {
+			if objects[user.Id] == nil {
+				permissions, err := s.enforcer.GetImplicitPermissionsForUser(user.Id)
+				if err != nil {
+					return nil, err
+				}
+				objects[user.Id] = map[string]bool{}
+				for _, permission := range permissions {
+					objects[user.Id][permission[1]] = true
+				}
+			}
+
+			export.Users = append(export.Users, models.CustomerUser{
+				Id:                   user.Id,
+				Email:                user.Email,
+				HasPerformanceAccess: objects[user.Id][customerData(customer.Id, models.AccessPerformance)],
+				HasFinancialAccess:   objects[user.Id][customerData(customer.Id, models.AccessFinance)],
+			})
+		}
+		exports = append(exports, export)
+	}
+	return exports, nil
+}
+
+// Employees returns every employee with its users, and their roles
+func (s *Exports) Employees(ctx context.Context) ([]models.EmployeeExport, error) {
+	employees, err := s.store.Employees.ListWithUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	exports := make([]models.EmployeeExport, 0, len(employees))
+	for _, employee := range employees {
+		export := models.EmployeeExport{Id: employee.Id, FullName: employee.FullName, Users: []models.EmployeeUserRoles{}}
+		for _, user := range employee.Users {
+			roles, err := s.enforcer.GetRolesForUser(user.Id)
+			if err != nil {
+				return nil, err
+			}
+			if roles == nil {
+				roles = []string{}
+			}
+			export.Users = append(export.Users, models.EmployeeUserRoles{Id: user.Id, Email: user.Email, Roles: roles})
+		}
+		exports = append(exports, export)
+	}
+	return exports, nil
+}
+
+// Permissions returns every permission (by category) with the roles having it
+func (s *Exports) Permissions(ctx context.Context) (models.PermissionMatrix, error) {
+	var matrix = models.PermissionMatrix{Permissions: []models.PermissionGrants{}}
+
+	roles, err := s.store.Roles.List(ctx)
+	if err != nil {
+		return matrix, err
+	}
+	matrix.Roles = roles
+
+	permissions, err := s.store.Permissions.List(ctx)
+	if err != nil {
+		return matrix, err
+	}
+	// Index of each permission in the matrix, by resource and action
+	index := map[[2]string]int{}
+	for _, permission := range permissions {
+		index[[2]string{permission.Resource, permission.Action}] = len(matrix.Permissions)
+		matrix.Permissions = append(matrix.Permissions, models.PermissionGrants{
+			Category:    permission.Category,
+			Resource:    permission.Resource,
+			Action:      permission.Action,
+			Description: permission.Description,
+			Roles:       []string{},
+		})
+	}
+
+	// The policies missing from "permissions" come last, by resource and action
+	known := len(matrix.Permissions)
+	for _, role := range roles {
+		policies, err := s.enforcer.GetImplicitPermissionsForUser(role.Role)
+		if err != nil {
+			return matrix, err
+		}
+		for _, policy := range policies {
+			key := [2]string{policy[1], policy[2]}
+			i, ok := index[key]
+			if !ok {
+				i = len(matrix.Permissions)
+				index[key] = i
+				matrix.Permissions = append(matrix.Permissions, models.PermissionGrants{Resource: policy[1], Action: policy[2], Roles: []string{}})
+			}
+			if !contains(matrix.Permissions[i].Roles, role.Role) {
+				matrix.Permissions[i].Roles = append(matrix.Permissions[i].Roles, role.Role)
+			}
+		}
+	}
+	missing := matrix.Permissions[known:]
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Resource < missing[j].Resource || missing[i].Resource == missing[j].Resource && missing[i].Action < missing[j].Action
+	})
+	return matrix, nil
+}
+
+// customerRows : one row by customer and user (customers without users have a row without user)
+func customerRows(customers []models.CustomerExport) [][]string {
+	rows := [][]string{{"customer_id", "customer_name", "user_id", "email", "finance", "performance"}}
+	for _, customer := range customers {
+		id := strconv.Itoa(customer.Id)
+		if len(customer.Users) == 0 {
+			rows = append(rows, []string{id, customer.FullName, "", "", "", ""})
+		}
+		for _, user := range customer.Users {
+			rows = append(rows, []string{id, customer.FullName, user.Id, user.Email, yesNo(user.HasFinancialAccess), yesNo(user.HasPerformanceAccess)})
+		}
+	}
+	return rows
+}
+
+// employeeRows : one row by employee and user (employees without users have a row without user)
+func employeeRows(employees []models.EmployeeExport) [][]string {
+	rows := [][]string{{"employee_id", "employee_name", "user_id", "email", "roles"}}
+	for _, employee := range employees {
+		id := strconv.Itoa(employee.Id)
+		if len(employee.Users) == 0 {
+			rows = append(rows, []string{id, employee.FullName, "", "", ""})
+		}
+		for _, user := range employee.Users {
+			rows = append(rows, []string{id, employee.FullName, user.Id, user.Email, strings.Join(user.Roles, ", ")})
+		}
+	}
+	return rows
+}
+
+// permissionRows : one row by permission, with a column by role
+func permissionRows(matrix models.PermissionMatrix) [][]string {
+	header := []string{"category", "resource", "action", "description"}
+	for _, role := range matrix.Roles {
+		header = append(header, role.Role)
+	}
+
+	rows := [][]string{header}
+	for _, permission := range matrix.Permissions {
+		row := []string{permission.Category, permission.Resource, permission.Action, permission.Description}
+		for _, role := range matrix.Roles {
+			row = append(row, yesNo(contains(permission.Roles, role.Role)))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,601 @@
+package service
+
+import (
+	"backend/apierror"
+	"backend/metrics"
+	"backend/models"
+	"backend/spreadsheet"
+	"backend/store"
+	"backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"firebase.google.com/go/auth"
+	"github.com/casbin/casbin/v2"
+	"github.com/go-playground/validator/v10"
+)
+
+type Imports struct {
+	store        *store.Store
+	enforcer     *casbin.SyncedEnforcer
+	firebaseAuth *auth.Client
+}
+
+// Columns of the header of each sheet, the required ones first
+var importColumns = map[string]struct{ required, optional []string }{
+	models.ImportCustomers:     {required: []string{"id", "full_name"}},
+	models.ImportEmployees:     {required: []string{"full_name"}, optional: []string{"email"}},
+	models.ImportCustomerUsers: {required: []string{"customer_id", "email"}, optional: []string{models.AccessFinance, models.AccessPerformance}},
+}
+
+// The sheets are imported in this order, so the later ones can refer to the customers and users of the earlier ones
+var importOrder = []string{models.ImportCustomers, models.ImportEmployees, models.ImportCustomerUsers}
+
+// customerDataPattern matches the resources of the data of a customer, like "portal::data::1996::finance"
+var customerDataPattern = regexp.MustCompile(`^portal::data::\d+::(finance|performance)$`)
+
+var validate = validator.New()
+
+// Import adds (or updates) the customers, employees and customer users of the sheets, named after their content
+// (models.ImportCustomers, models.ImportEmployees and models.ImportCustomerUsers).
+// Every row is validated first: if any row is invalid, nothing changes and the report is the details of an
+// import_invalid error. Otherwise all changes are committed together, unless options.DryRun
+func (s *Imports) Import(ctx context.Context, sheets []spreadsheet.Sheet, options models.ImportOptions) (models.ImportReport, error) {
+	report := models.ImportReport{DryRun: options.DryRun, Rows: []models.ImportRow{}}
+
+	byName := map[string]spreadsheet.Sheet{}
+	for _, sheet := range sheets {
+		if _, ok := importColumns[sheet.Name]; !ok {
+			return report, apierror.BadRequest(fmt.Sprintf("Unknown sheet %q: expected %s.", sheet.Name, strings.Join(importOrder, ", ")))
+		}
+		if _, ok := byName[sheet.Name]; ok {
+			return report, apierror.BadRequest(fmt.Sprintf("The sheet %q is given twice.", sheet.Name))
+		}
+		byName[sheet.Name] = sheet
+	}
+	if len(byName) == 0 {
+		return report, apierror.BadRequest("Nothing to import: give a sheet of customers, employees or customer users.")
+	}
+
+	plan := &importPlan{Imports: s, options: options, customers: map[int]bool{}, users: map[string]*importUser{}, access: map[string]*userAccess{}}
+	for _, name := range importOrder {
+		if sheet, ok := byName[name]; ok {
+			if err := plan.sheet(ctx, sheet); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := plan.finishAccess(); err != nil {
+		return report, err
+	}
+
+	report.Rows = plan.rows
+	for _, row := range plan.rows {
+		switch row.Action {
+		case models.ImportCreate:
+			report.Summary.Created++
+		case models.ImportUpdate:
+			report.Summary.Updated++
+		case models.ImportUnchanged:
+			report.Summary.Unchanged++
+		case models.ImportInvalid:
+			report.Summary.Invalid++
+		}
+	}
+	for _, user := range plan.users {
+		if user.create {
+			report.Summary.UsersCreated++
+		}
+	}
+
+	if report.Summary.Invalid > 0 {
+		return report, apierror.New(http.StatusUnprocessableEntity, apierror.CodeImportInvalid,
+			fmt.Sprintf("%d rows are invalid, nothing was imported. Please correct them and try again.", report.Summary.Invalid)).
+			WithDetails(report)
+	}
+	if options.DryRun {
+		return report, nil
+	}
+
+	err := s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		for _, step := range plan.steps {
+			if err := step(ctx, uow); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	report.Applied = err == nil
+	return report, err
+}
+
+// importPlan validates the rows of an import against the database, and collects the steps applying them
+type importPlan struct {
+	*Imports
+	options models.ImportOptions
+
+	rows  []models.ImportRow
+	steps []func(ctx context.Context, uow *store.UnitOfWork) error
+
+	// customers are the ids of the customers of the import, and of those found in the database
+	customers map[int]bool
+	// users by email, and the access of the users by uid
+	users  map[string]*importUser
+	access map[string]*userAccess
+}
+
+// importUser is a user of the import, existing or added by it
+type importUser struct {
+	user models.User
+	// added to the database, and created in firebase (through the outbox) unless it is already there
+	added  bool
+	create bool
+	// employeeOf is the employee the import assigns the user to
+	employeeOf string
+	// reported once the addition of the user is in the changes of a row
+	reported bool
+}
+
+// userAccess is the access of a user to the data of the customers, changed by the import
+type userAccess struct {
+	uid string
+	// associated with a customer by the import
+	associated bool
+	// data is the access to the data of each customer changed by the import, like "portal::data::1996::finance": true
+	data map[string]bool
+}
+
+// sheet plans the rows of sheet (its header being the first row)
+func (p *importPlan) sheet(ctx context.Context, sheet spreadsheet.Sheet) error {
+	if len(sheet.Rows) == 0 {
+		p.invalid(sheet.Name, 1, "The sheet is empty: its first row must be the header.")
+		return nil
+	}
+	header, problems := parseHeader(sheet.Name, sheet.Rows[0].Cells)
+	if len(problems) > 0 {
+		p.invalid(sheet.Name, sheet.Rows[0].Line, problems...)
+		return nil
+	}
+
+	// Line of the first row of each key, to report duplicates
+	seen := map[string]int{}
+	for _, row := range sheet.Rows[1:] {
+		cell := func(column string) string {
+			if i, ok := header[column]; ok && i < len(row.Cells) {
+				return row.Cells[i]
+			}
+			return ""
+		}
+
+		var result models.ImportRow
+		var key string
+		var err error
+		switch sheet.Name {
+		case models.ImportCustomers:
+			key, result, err = p.customer(ctx, cell)
+		case models.ImportEmployees:
+			key, result, err = p.employee(ctx, cell)
+		case models.ImportCustomerUsers:
+			key, result, err = p.customerUser(ctx, cell)
+		}
+		if err != nil {
+			return err
+		}
+
+		if first, ok := seen[key]; ok && key != "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("Already imported by line %d.", first))
+		} else if key != "" {
+			seen[key] = row.Line
+		}
+		result.Sheet, result.Line = sheet.Name, row.Line
+		if len(result.Errors) > 0 {
+			result.Action = models.ImportInvalid
+			result.Changes = nil
+		}
+		p.rows = append(p.rows, result)
+	}
+	return nil
+}
+
+// parseHeader returns the index of each column of a header, or what is wrong with it
+func parseHeader(sheet string, cells []string) (map[string]int, []string) {
+	known := map[string]bool{}
+	for _, column := range importColumns[sheet].required {
+		known[column] = true
+	}
+	for _, column := range importColumns[sheet].optional {
+		known[column] = false
+	}
+
+	header := map[string]int{}
+	var problems []string
+	for i, cell := range cells {
+		column := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(cell), " ", "_"))
+		if _, ok := known[column]; !ok {
+			problems = append(problems, fmt.Sprintf("Unknown column %q.", cell))
+			continue
+		}
+		header[column] = i
+	}
+	for _, column := range importColumns[sheet].required {
+		if _, ok := header[column]; !ok {
+			problems = append(problems, fmt.Sprintf("Missing column %q.", column))
+		}
+	}
+	return header, problems
+}
+
+func (p *importPlan) invalid(sheet string, line int, problems ...string) {
+	p.rows = append(p.rows, models.ImportRow{Sheet: sheet, Line: line, Action: models.ImportInvalid, Errors: problems})
+}
+
+func (p *importPlan) step(step func(ctx context.Context, uow *store.UnitOfWork) error) {
+	p.steps = append(p.steps, step)
+}
+
+// customer plans a row of customers: the customer is created, or renamed
+func (p *importPlan) customer(ctx context.Context, cell func(string) string) (string, models.ImportRow, error) {
+	var result models.ImportRow
+	id, idErr := positiveInt(cell("id"))
+	if idErr != "" {
+		result.Errors = append(result.Errors, "id "+idErr)
+	}
+	fullName := cell("full_name")
+	if fullName == "" {
+		result.Errors = append(result.Errors, "full_name is required.")
+	}
+	if len(result.Errors) > 0 {
+		return "", result, nil
+	}
+	p.customers[id] = true
+
+	existing, err := p.store.Customers.Find(ctx, id)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		result.Action = models.ImportCreate
+		result.Changes = []string{fmt.Sprintf("create customer %d %q", id, fullName)}
+		p.step(func(ctx context.Context, uow *store.UnitOfWork) error {
+			return uow.Customers.Create(ctx, &models.Customer{Id: id, FullName: fullName})
+		})
+	case err != nil:
+		return "", result, err
+	case existing.FullName == fullName:
+		result.Action = models.ImportUnchanged
+	default:
+		result.Action = models.ImportUpdate
+		result.Changes = []string{fmt.Sprintf("rename customer %d from %q to %q", id, existing.FullName, fullName)}
+		p.step(func(ctx context.Context, uow *store.UnitOfWork) error {
+			return uow.Customers.Rename(ctx, &models.Customer{Id: id, FullName: fullName})
+		})
+	}
+	return strconv.Itoa(id), result, nil
+}
+
+// employee plans a row of employees: the employee (found by name) is created, and assigned the user of email
+func (p *importPlan) employee(ctx context.Context, cell func(string) string) (string, models.ImportRow, error) {
+	var result models.ImportRow
+	fullName := cell("full_name")
+	if fullName == "" {
+		result.Errors = append(result.Errors, "full_name is required.")
+		return "", result, nil
+	}
+
+	employees, err := p.store.Employees.FindByName(ctx, fullName)
+	if err != nil {
+		return "", result, err
+	}
+	if len(employees) > 1 {
+		result.Errors = append(result.Errors, fmt.Sprintf("%d employees are named %q: rename them first.", len(employees), fullName))
+		return fullName, result, nil
+	}
+
+	var user *importUser
+	if email := cell("email"); email != "" {
+		var problem string
+		if user, problem, err = p.user(ctx, email); err != nil {
+			return "", result, err
+		}
+		if problem == "" && user.employeeOf != "" {
+			problem = fmt.Sprintf("%s is already assigned to %q by this import.", user.user.Email, user.employeeOf)
+		}
+		if problem != "" {
+			result.Errors = append(result.Errors, problem)
+			return fullName, result, nil
+		}
+		user.employeeOf = fullName
+		result.Changes = append(result.Changes, user.changes()...)
+	}
+
+	var employee = &models.Employee{FullName: fullName}
+	if len(employees) == 0 {
+		result.Action = models.ImportCreate
+		result.Changes = append([]string{fmt.Sprintf("create employee %q", fullName)}, result.Changes...)
+		p.step(func(ctx context.Context, uow *store.UnitOfWork) error {
+			return uow.Employees.Create(ctx, employee)
+		})
+	} else {
+		employee = &employees[0]
+		result.Action = models.ImportUnchanged
+	}
+
+	if user != nil && (user.user.EmployeeID == nil || *user.user.EmployeeID != employee.Id) {
+		if result.Action == models.ImportUnchanged {
+			result.Action = models.ImportUpdate
+		}
+		result.Changes = append(result.Changes, fmt.Sprintf("assign %s to employee %q", user.user.Email, fullName))
+		p.step(func(ctx context.Context, uow *store.UnitOfWork) error {
+			// The id of a new employee is known once it is created
+			return uow.Employees.AddUser(ctx, employee, &models.User{Id: user.user.Id})
+		})
+	}
+	return fullName, result, nil
+}
+
+// customerUser plans a row of customer users: the user of email is associated with the customer,
+// and given (or taken) access to its finance and performance data
+func (p *importPlan) customerUser(ctx context.Context, cell func(string) string) (string, models.ImportRow, error) {
+	var result models.ImportRow
+	customerId, idErr := positiveInt(cell("customer_id"))
+	if idErr != "" {
+		result.Errors = append(result.Errors, "customer_id "+idErr)
+	} else if !p.customers[customerId] {
+		exists, err := p.store.Customers.Exists(ctx, customerId)
+		if err != nil {
+			return "", result, err
+		}
+		if !exists {
+			result.Errors = append(result.Errors, fmt.Sprintf("There is no customer %d.", customerId))
+		}
+		p.customers[customerId] = exists
+	}
+
+	access := map[string]*bool{}
+	for _, object := range []string{models.AccessFinance, models.AccessPerformance} {
+		flag, err := parseFlag(cell(object))
+		if err != "" {
+			result.Errors = append(result.Errors, object+" "+err)
+		}
+		access[object] = flag
+	}
+
+	email := cell("email")
+	if email == "" {
+		result.Errors = append(result.Errors, "email is required.")
+		return "", result, nil
+	}
+	user, problem, err := p.user(ctx, email)
+	if err != nil {
+		return "", result, err
+	}
+	if problem != "" {
+		result.Errors = append(result.Errors, problem)
+	}
+	if len(result.Errors) > 0 {
+		return "", result, nil
+	}
+	uid := user.user.Id
+	key := fmt.Sprintf("%d %s", customerId, user.user.Email)
+
+	associated := false
+	if !user.added {
+		if associated, err = p.store.Customers.HasUser(ctx, customerId, uid); err != nil {
+			return "", result, err
+		}
+	}
+
+	state := p.access[uid]
+	if state == nil {
+		state = &userAccess{uid: uid, data: map[string]bool{}}
+		p.access[uid] = state
+	}
+
+	result.Action = models.ImportUnchanged
+	if !associated {
+		result.Action = models.ImportCreate
+		result.Changes = append(user.changes(), fmt.Sprintf("associate %s with customer %d", user.user.Email, customerId))
+		state.associated = true
+		p.step(func(ctx context.Context, uow *store.UnitOfWork) error {
+			return uow.Customers.AddUser(ctx, &models.Customer{Id: customerId}, &models.User{Id: uid})
+		})
+	}
+
+	for _, object := range []string{models.AccessFinance, models.AccessPerformance} {
+		if access[object] == nil {
+			continue
+		}
+		data := customerData(customerId, object)
+		current := false
+		if !user.added {
+			if current, err = p.enforcer.HasPolicy(uid, data, "read"); err != nil {
+				return "", result, err
+			}
+		}
+		if *access[object] == current {
+			continue
+		}
+
+		state.data[data] = *access[object]
+		if result.Action == models.ImportUnchanged {
+			result.Action = models.ImportUpdate
+		}
+		if *access[object] {
+			result.Changes = append(result.Changes, fmt.Sprintf("grant %s access of %s to customer %d", object, user.user.Email, customerId))
+		} else {
+			result.Changes = append(result.Changes, fmt.Sprintf("revoke %s access of %s to customer %d", object, user.user.Email, customerId))
+		}
+	}
+	return key, result, nil
+}
+
+// finishAccess plans the policies of the users whose access changed: the access to the data of each customer,
+// and the general access to the finance and performance data, kept while the user has access to a customer's data
+func (p *importPlan) finishAccess() error {
+	for _, state := range p.access {
+		if !state.associated && len(state.data) == 0 {
+			continue
+		}
+		permissions, err := p.enforcer.GetPermissionsForUser(state.uid)
+		if err != nil {
+			return err
+		}
+
+		// Access to the data of each object after the import
+		remaining := map[string]bool{}
+		for _, permission := range permissions {
+			if len(permission) < 3 || permission[2] != "read" {
+				continue
+			}
+			if match := customerDataPattern.FindStringSubmatch(permission[1]); match != nil {
+				if hasAccess, changed := state.data[permission[1]]; !changed || hasAccess {
+					remaining[match[1]] = true
+				}
+			}
+		}
+
+		var add, remove [][]string
+		if state.associated {
+			add = append(add, []string{state.uid, "portal::data::customer", "read"})
+		}
+		changedObjects := map[string]bool{}
+		for data, hasAccess := range state.data {
+			object := customerDataPattern.FindStringSubmatch(data)[1]
+			changedObjects[object] = true
+			if hasAccess {
+				remaining[object] = true
+				add = append(add, []string{state.uid, data, "read"})
+			} else {
+				remove = append(remove, []string{state.uid, data, "read"})
+			}
+		}
+		for object := range changedObjects {
+			general := []string{state.uid, fmt.Sprintf("portal::data::customer::%s", object), "read"}
+			if remaining[object] {
+				add = append(add, general)
+			} else {
+				remove = append(remove, general)
+			}
+		}
+
+		p.step(func(ctx context.Context, uow *store.UnitOfWork) error {
+			for _, rule := range add {
+				if err := uow.Policies.AddPolicy(rule...); err != nil {
+					return err
+				}
+			}
+			for _, rule := range remove {
+				if err := uow.Policies.RemovePolicy(rule...); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// user returns the user of email, or what is wrong with it. With options.CreateUsers, a missing user is added
+// from firebase, or created (in firebase through the outbox); otherwise it is a problem
+func (p *importPlan) user(ctx context.Context, email string) (*importUser, string, error) {
+	// Users are keyed by their email in lower case, as they are kept in "users"
+	email = strings.ToLower(email)
+	if user, ok := p.users[email]; ok {
+		return user, "", nil
+	}
+	if validate.Var(email, "email") != nil {
+		return nil, fmt.Sprintf("%q is not a valid email.", email), nil
+	}
+
+	user, err := p.store.Users.FindByEmail(ctx, email)
+	if err == nil {
+		p.users[email] = &importUser{user: user}
+		return p.users[email], "", nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, "", err
+	}
+	if !p.options.CreateUsers {
+		return nil, fmt.Sprintf("There is no user with the email %s (import with create_users to create it).", email), nil
+	}
+	if p.firebaseAuth == nil {
+		return nil, "", firebaseUnavailable("Firebase is not configured", errors.New("firebase credentials not initialised"))
+	}
+
+	// A user of firebase missing from the database is only added to the database
+	var record *auth.UserRecord
+	err = metrics.Firebase(ctx, "get_user_by_email", func() (err error) {
+		record, err = p.firebaseAuth.GetUserByEmail(ctx, email)
+		return err
+	}, auth.IsUserNotFound)
+	imported := &importUser{added: true}
+	switch {
+	case err == nil:
+		imported.user = models.User{Id: record.UID, Email: email, CreationTimestamp: int(record.UserMetadata.CreationTimestamp), LastLoginTimestamp: int(record.UserMetadata.LastLogInTimestamp)}
+	case auth.IsUserNotFound(err):
+		uid, err := utils.NewUID()
+		if err != nil {
+			return nil, "", err
+		}
+		imported.user = models.User{Id: uid, Email: email}
+		imported.create = true
+	default:
+		return nil, "", firebaseUnavailable("Could not check the email in Firebase", err)
+	}
+
+	p.users[email] = imported
+	p.step(func(ctx context.Context, uow *store.UnitOfWork) error {
+		if err := uow.Users.Create(ctx, &models.User{Id: imported.user.Id, Email: email, CreationTimestamp: imported.user.CreationTimestamp, LastLoginTimestamp: imported.user.LastLoginTimestamp}); err != nil {
+			return err
+		}
+		if !imported.create {
+			return nil
+		}
+		// Without a password: the user sets it with the "forgot password" link
+		return uow.Outbox.Enqueue(ctx, &models.OutboxOperation{Operation: models.OutboxCreateUser, Uid: imported.user.Id, Email: email})
+	})
+	return imported, "", nil
+}
+
+// changes are the changes of the first row using the user, if the import adds it
+func (u *importUser) changes() []string {
+	if !u.added || u.reported {
+		return nil
+	}
+	u.reported = true
+	if u.create {
+		return []string{"create user " + u.user.Email}
+	}
+	return []string{"add user " + u.user.Email + " from firebase"}
+}
+
+// positiveInt parses a positive integer, or returns what is wrong with it
+func positiveInt(value string) (int, string) {
+	if value == "" {
+		return 0, "is required."
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return 0, fmt.Sprintf("must be a positive integer, not %q.", value)
+	}
+	return number, ""
+}
+
+// parseFlag parses an access flag: nil (unchanged) if empty, or yes/no (true/false, 1/0, x)
+func parseFlag(value string) (*bool, string) {
+	var flag bool
+	switch strings.ToLower(value) {
+	case "":
+		return nil, ""
+	case "yes", "y", "true", "1", "x":
+		flag = true
+	case "no", "n", "false", "0":
+		flag = false
+	default:
+		return nil, fmt.Sprintf("must be yes or no, not %q.", value)
+	}
+	return &flag, ""
+}
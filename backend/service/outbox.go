@@ -0,0 +1,31 @@
+package service
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/store"
+	"context"
+)
+
+type Outbox struct {
+	outbox store.OutboxStore
+}
+
+// List returns a page of the firebase operations of the outbox, of status if not empty
+func (s *Outbox) List(ctx context.Context, status string, filter models.Filter) ([]models.OutboxOperation, int64, error) {
+	return s.outbox.List(ctx, status, filter)
+}
+
+// Retry queues a failed (or stuck) operation again, with a fresh set of attempts.
+// It fails with outbox_operation_not_found if the operation does not exist, or is already done
+func (s *Outbox) Retry(ctx context.Context, id uint) error {
+	retried, err := s.outbox.Retry(ctx, id)
+	if err != nil {
+		return err
+	}
+	// Operation does not exist, or is already done
+	if !retried {
+		return apierror.NotFound(apierror.CodeOutboxOperationNotFound, "Operation not found or already done.")
+	}
+	return nil
+}
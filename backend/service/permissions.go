@@ -0,0 +1,75 @@
+package service
+
+import (
+	"backend/models"
+	"backend/store"
+	"context"
+	"github.com/casbin/casbin/v2"
+)
+
+type Permissions struct {
+	store    *store.Store
+	enforcer *casbin.SyncedEnforcer
+}
+
+// ForRole returns every permission by category, and whether role has it
+func (s *Permissions) ForRole(ctx context.Context, role string) (models.FrontendPolicy, error) {
+	if err := mustExistRole(ctx, s.store.Roles, role); err != nil {
+		return nil, err
+	}
+	permissionsForRole, err := s.enforcer.GetPermissionsForUser(role)
+	if err != nil {
+		return nil, err
+	}
+
+	// Select all permissions (order by category)
+	permissions, err := s.store.Permissions.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rolePermissionsObject models.FrontendPolicy
+	rolePermissionsObject = make(map[string][]models.PermissionInfo)
+	for _, globalPerm := range permissions {
+
+		var roleHasPermission bool
+		for _, rolePerm := range permissionsForRole {
+			if globalPerm.Action == rolePerm[2] && globalPerm.Resource == rolePerm[1] {
+				roleHasPermission = true
+				break
+			}
+		}
+
+		rolePermissionsObject[globalPerm.Category] = append(rolePermissionsObject[globalPerm.Category],
+			models.PermissionInfo{
+				PermissionId:          globalPerm.Id,
+				PermissionDescription: globalPerm.Description,
+				PermissionAction:      globalPerm.Action,
+				PermissionResource:    globalPerm.Resource,
+				HasPermission:         roleHasPermission,
+			},
+		)
+	}
+
+	return rolePermissionsObject, nil
+}
+
+// Add gives the permission of action on resource to role (nothing happens if role already has it)
+func (s *Permissions) Add(ctx context.Context, role string, resource string, action string) error {
+	if err := mustExistRole(ctx, s.store.Roles, role); err != nil {
+		return err
+	}
+	return s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		return uow.Policies.AddPolicy(role, resource, action)
+	})
+}
+
+// Remove takes the permission of action on resource from role (nothing happens if role does not have it)
+func (s *Permissions) Remove(ctx context.Context, role string, resource string, action string) error {
+	if err := mustExistRole(ctx, s.store.Roles, role); err != nil {
+		return err
+	}
+	return s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		return uow.Policies.RemovePolicy(role, resource, action)
+	})
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/store"
+	"context"
+	"fmt"
+	"github.com/casbin/casbin/v2"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Policies exports the casbin rules, and imports them from another environment
+type Policies struct {
+	store    *store.Store
+	enforcer *casbin.SyncedEnforcer
+}
+
+// Rules returns the rules matching filter: the policy rules first, then the role rules, by type
+func (s *Policies) Rules(filter models.PolicyFilter) ([]models.PolicyRule, error) {
+	return s.matchingRules(filter, func(sec string, ptype string) ([][]string, error) {
+		if sec == "g" {
+			return s.enforcer.GetNamedGroupingPolicy(ptype)
+		}
+		return s.enforcer.GetNamedPolicy(ptype)
+	})
+}
+
+// matchingRules returns the rules of policy matching filter, in the order of Rules
+func (s *Policies) matchingRules(filter models.PolicyFilter, policy func(sec string, ptype string) ([][]string, error)) ([]models.PolicyRule, error) {
+	var rules []models.PolicyRule
+	for _, sec := range []string{"p", "g"} {
+		var ptypes []string
+		for ptype := range s.enforcer.GetModel()[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+
+		for _, ptype := range ptypes {
+			values, err := policy(sec, ptype)
+			if err != nil {
+				return nil, err
+			}
+			for _, rule := range values {
+				if matchesPolicyFilter(filter, sec, rule) {
+					rules = append(rules, models.PolicyRule{Ptype: ptype, Rule: rule})
+				}
+			}
+		}
+	}
+	return rules, nil
+}
+
+// Import replaces the rules matching the filter of options with rules, and returns the diff.
+// Every rule is checked against the model first: if any is invalid, nothing changes and it fails with policy_invalid.
+// Otherwise the diff is computed from the rules in the database and applied in the same unit of work, unless options.DryRun
+func (s *Policies) Import(ctx context.Context, rules []models.PolicyRule, options models.PolicyImportOptions) (models.PolicyDiff, error) {
+	var diff = models.PolicyDiff{DryRun: options.DryRun, Added: []models.PolicyRule{}, Removed: []models.PolicyRule{}}
+	filter := options.Filter()
+
+	var problems []string
+	for _, rule := range rules {
+		if problem := s.checkRule(rule, filter); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", formatRule(rule), problem))
+		}
+	}
+	if len(problems) > 0 {
+		return diff, apierror.New(http.StatusUnprocessableEntity, apierror.CodePolicyInvalid,
+			fmt.Sprintf("%d rules are invalid, nothing was imported. Please correct them and try again.", len(problems))).
+			WithDetails(problems)
+	}
+	// An empty file would delete the whole policy, locking everyone out
+	if len(rules) == 0 && filter == (models.PolicyFilter{}) {
+		return diff, apierror.BadRequest("The policy file has no rules.")
+	}
+
+	// The diff is computed in the unit of work applying it, so it is exactly what changes
+	err := s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		current, err := s.matchingRules(filter, func(sec string, ptype string) ([][]string, error) {
+			return uow.Policies.NamedRules(ptype)
+		})
+		if err != nil {
+			return err
+		}
+		diff.Added, diff.Removed, diff.Unchanged = diffRules(current, rules)
+
+		if options.DryRun {
+			return nil
+		}
+		for _, rule := range diff.Removed {
+			if err := s.removeRule(uow.Policies, rule); err != nil {
+				return err
+			}
+		}
+		for _, rule := range diff.Added {
+			if err := s.addRule(uow.Policies, rule); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil || options.DryRun {
+		return diff, err
+	}
+	diff.Applied = true
+	return diff, nil
+}
+
+// checkRule returns what is wrong with rule, if anything
+func (s *Policies) checkRule(rule models.PolicyRule, filter models.PolicyFilter) string {
+	sec := s.sectionOf(rule.Ptype)
+	if sec == "" {
+		return fmt.Sprintf("unknown rule type %q.", rule.Ptype)
+	}
+	if tokens := s.enforcer.GetModel()[sec][rule.Ptype].Tokens; len(rule.Rule) != len(tokens) {
+		return fmt.Sprintf("rules of type %q have %d values, not %d.", rule.Ptype, len(tokens), len(rule.Rule))
+	}
+	for _, value := range rule.Rule {
+		if value == "" {
+			return "values must not be empty."
+		}
+	}
+	if !matchesPolicyFilter(filter, sec, rule.Rule) {
+		return "the rule is outside of the subject or resource prefix of the import."
+	}
+	return ""
+}
+
+// sectionOf returns the section of the model ("p" or "g") of the rules of type ptype, or "" if the model has no such type
+func (s *Policies) sectionOf(ptype string) string {
+	for _, sec := range []string{"p", "g"} {
+		if _, ok := s.enforcer.GetModel()[sec][ptype]; ok {
+			return sec
+		}
+	}
+	return ""
+}
+
+// matchesPolicyFilter reports whether the rule of section sec matches filter
+func matchesPolicyFilter(filter models.PolicyFilter, sec string, rule []string) bool {
+	if filter.Subject != "" && (len(rule) == 0 || rule[0] != filter.Subject) {
+		return false
+	}
+	if filter.ResourcePrefix != "" && (sec != "p" || len(rule) < 2 || !strings.HasPrefix(rule[1], filter.ResourcePrefix)) {
+		return false
+	}
+	return true
+}
+
+func (s *Policies) addRule(policies *store.PolicyTx, rule models.PolicyRule) error {
+	if s.sectionOf(rule.Ptype) == "g" {
+		return policies.AddNamedGroupingPolicy(rule.Ptype, rule.Rule...)
+	}
+	return policies.AddNamedPolicy(rule.Ptype, rule.Rule...)
+}
+
+func (s *Policies) removeRule(policies *store.PolicyTx, rule models.PolicyRule) error {
+	if s.sectionOf(rule.Ptype) == "g" {
+		return policies.RemoveNamedGroupingPolicy(rule.Ptype, rule.Rule...)
+	}
+	return policies.RemoveNamedPolicy(rule.Ptype, rule.Rule...)
+}
+
+// diffRules returns the rules to add to current and to remove from it, to get imported (duplicates being ignored),
+// and the number of imported rules already in current
+func diffRules(current []models.PolicyRule, imported []models.PolicyRule) (added []models.PolicyRule, removed []models.PolicyRule, unchanged int) {
+	added, removed = []models.PolicyRule{}, []models.PolicyRule{}
+	existing := map[string]bool{}
+	for _, rule := range current {
+		existing[store.PolicyKey(rule.Ptype, rule.Rule)] = true
+	}
+
+	kept := map[string]bool{}
+	for _, rule := range imported {
+		key := store.PolicyKey(rule.Ptype, rule.Rule)
+		if kept[key] {
+			continue
+		}
+		kept[key] = true
+		if existing[key] {
+			unchanged++
+		} else {
+			added = append(added, rule)
+		}
+	}
+	for _, rule := range current {
+		if !kept[store.PolicyKey(rule.Ptype, rule.Rule)] {
+			removed = append(removed, rule)
+		}
+	}
+	return added, removed, unchanged
+}
+
+// formatRule returns rule like a line of a casbin policy file
+func formatRule(rule models.PolicyRule) string {
+	return strings.Join(append([]string{rule.Ptype}, rule.Rule...), ", ")
+}
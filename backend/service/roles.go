@@ -0,0 +1,80 @@
+package service
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/store"
+	"context"
+	"fmt"
+	"github.com/casbin/casbin/v2"
+)
+
+type Roles struct {
+	store    *store.Store
+	enforcer *casbin.SyncedEnforcer
+}
+
+// List returns all roles
+func (s *Roles) List(ctx context.Context) ([]models.Role, error) {
+	return s.store.Roles.List(ctx)
+}
+
+// Save adds a role, or updates its description
+func (s *Roles) Save(ctx context.Context, role *models.Role) error {
+	return s.store.Roles.Save(ctx, role)
+}
+
+// Delete deletes a role and its permissions. It fails with role_not_found if there is no such role,
+// and with role_in_use if users still have it
+func (s *Roles) Delete(ctx context.Context, role string) error {
+	if err := mustExistRole(ctx, s.store.Roles, role); err != nil {
+		return err
+	}
+	//Get number of users with this role from "cabin_rule" table in DB
+	countUsersWithRole, err := s.store.Roles.CountAssignments(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	if countUsersWithRole != 0 {
+		return apierror.Conflict(apierror.CodeRoleInUse, "Please remove this role from all users before deleting it").
+			WithDetails(map[string]interface{}{"role": role, "users": countUsersWithRole})
+	}
+
+	// The role and its permissions are deleted together
+	return s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		//Delete from "role" table in DB
+		err := uow.Roles.Delete(ctx, &models.Role{Role: role})
+		if err != nil {
+			return err
+		}
+
+		//Delete from "casbin_rule" table in DB
+		return uow.Policies.RemoveFilteredPolicy(0, role)
+	})
+}
+
+// Assign sets the role of a user, replacing its previous role
+func (s *Roles) Assign(ctx context.Context, userId string, role string) error {
+	if err := mustExistRole(ctx, s.store.Roles, role); err != nil {
+		return err
+	}
+	return s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		if err := uow.Policies.RemoveFilteredGroupingPolicy(0, userId); err != nil {
+			return err
+		}
+		return uow.Policies.AddGroupingPolicy(userId, role)
+	})
+}
+
+// mustExistRole fails with role_not_found if role is not in "roles"
+func mustExistRole(ctx context.Context, roles store.RoleStore, role string) error {
+	exists, err := roles.Exists(ctx, role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apierror.NotFound(apierror.CodeRoleNotFound, fmt.Sprintf("There is no role %q.", role))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Package service holds the operations of the API, shared by its versions (/api and /api/v2).
+// Handlers only bind the request, call the service and render its result, or its error
+// (an *apierror.Error for the mistakes of the user, rendered by apierror.Render).
+package service
+
+import (
+	"backend/store"
+	"firebase.google.com/go/auth"
+	"github.com/casbin/casbin/v2"
+)
+
+// Service groups the services of each resource
+type Service struct {
+	Roles         *Roles
+	Permissions   *Permissions
+	Users         *Users
+	Employees     *Employees
+	Customers     *Customers
+	Outbox        *Outbox
+	Imports       *Imports
+	Exports       *Exports
+	Policies      *Policies
+	Authorization *Authorization
+}
+
+// New builds the services on top of the store, the enforcer and the firebase client.
+// firebaseAuth may be nil, if firebase is not configured: the operations needing it then fail.
+func New(st *store.Store, enforcer *casbin.SyncedEnforcer, firebaseAuth *auth.Client) *Service {
+	return &Service{
+		Roles:         &Roles{store: st, enforcer: enforcer},
+		Permissions:   &Permissions{store: st, enforcer: enforcer},
+		Users:         &Users{store: st, enforcer: enforcer, firebaseAuth: firebaseAuth},
+		Employees:     &Employees{store: st, enforcer: enforcer},
+		Customers:     &Customers{store: st, enforcer: enforcer},
+		Outbox:        &Outbox{outbox: st.Outbox},
+		Imports:       &Imports{store: st, enforcer: enforcer, firebaseAuth: firebaseAuth},
+		Exports:       &Exports{store: st, enforcer: enforcer},
+		Policies:      &Policies{store: st, enforcer: enforcer},
+		Authorization: &Authorization{enforcer: enforcer},
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"backend/apierror"
+	"backend/metrics"
+	"backend/models"
+	"backend/store"
+	"backend/utils"
+	"context"
+	"errors"
+	"firebase.google.com/go/auth"
+	"github.com/casbin/casbin/v2"
+	"google.golang.org/api/iterator"
+	"net/http"
+)
+
+type Users struct {
+	store        *store.Store
+	enforcer     *casbin.SyncedEnforcer
+	firebaseAuth *auth.Client
+}
+
+// List returns a page of users, and the number of users matching filter
+func (s *Users) List(ctx context.Context, filter models.Filter) ([]models.User, int64, error) {
+	return s.store.Users.List(ctx, filter)
+}
+
+// ListWithRoles returns a page of the users assigned to an employee, with their role
+func (s *Users) ListWithRoles(ctx context.Context, filter models.Filter) ([]models.FrontendUser, int64, error) {
+	return s.store.Users.ListWithRoles(ctx, filter)
+}
+
+// UnassignedEmails returns the emails of the users assigned to neither an employee nor a customer
+func (s *Users) UnassignedEmails(ctx context.Context) ([]string, error) {
+	return s.store.Users.UnassignedEmails(ctx)
+}
+
+// CustomerEmails returns the emails of the users that can be associated with a customer
+func (s *Users) CustomerEmails(ctx context.Context) ([]string, error) {
+	return s.store.Users.CustomerEmails(ctx)
+}
+
+// SyncWithFirebase copies the users of firebase to the database
+func (s *Users) SyncWithFirebase(ctx context.Context) error {
+	if s.firebaseAuth == nil {
+		return firebaseUnavailable("Firebase is not configured", errors.New("firebase credentials not initialised"))
+	}
+
+	// iterate firebase users
+	// Note, behind the scenes, the Users() iterator will retrieve 1000 users at a time through the API
+	iter := s.firebaseAuth.Users(ctx, "")
+	for {
+		var user *auth.ExportedUserRecord
+		err := metrics.Firebase(ctx, "list_users", func() (err error) {
+			user, err = iter.Next()
+			return err
+		}, func(err error) bool { return err == iterator.Done })
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return firebaseUnavailable("Failed to fetch users from Firebase", err)
+		}
+
+		// Update users
+		err = s.store.Users.Upsert(ctx, &models.User{Id: user.UID, Email: user.Email, CreationTimestamp: int(user.UserMetadata.CreationTimestamp), LastLoginTimestamp: int(user.UserMetadata.LastLogInTimestamp)})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Create saves a user in the database, and queues its creation in firebase in the outbox.
+// It fails with email_exists if the email is already used in firebase
+func (s *Users) Create(ctx context.Context, email string, password string) (models.User, error) {
+	if s.firebaseAuth == nil {
+		return models.User{}, firebaseUnavailable("Firebase is not configured", errors.New("firebase credentials not initialised"))
+	}
+
+	// If email already exists in firebase, return error
+	err := metrics.Firebase(ctx, "get_user_by_email", func() error {
+		_, err := s.firebaseAuth.GetUserByEmail(ctx, email)
+		return err
+	}, auth.IsUserNotFound)
+	if err == nil {
+		return models.User{}, apierror.Conflict(apierror.CodeEmailExists, "User already exists in Firebase! Please use another email.")
+	}
+	if !auth.IsUserNotFound(err) {
+		return models.User{}, firebaseUnavailable("Could not check the email in Firebase", err)
+	}
+
+	// The uid is generated here, so the user can be saved in the database before it is created in firebase
+	uid, err := utils.NewUID()
+	if err != nil {
+		return models.User{}, err
+	}
+	// Only the password's hash is kept, until the outbox worker creates the user
+	passwordHash := password
+	if err = utils.HashPassword(&passwordHash); err != nil {
+		return models.User{}, err
+	}
+
+	// Add user to users table in database, and queue its creation in firebase
+	var user = models.User{Id: uid, Email: email}
+	err = s.store.Atomic(ctx, nil, func(uow *store.UnitOfWork) error {
+		err := uow.Users.Create(ctx, &user)
+		if err != nil {
+			return err
+		}
+
+		return uow.Outbox.Enqueue(ctx, &models.OutboxOperation{
+			Operation:    models.OutboxCreateUser,
+			Uid:          uid,
+			Email:        email,
+			PasswordHash: passwordHash,
+		})
+	})
+	return user, err
+}
+
+// Delete deletes a user with its permissions and associations, and queues its deletion from firebase in the outbox
+func (s *Users) Delete(ctx context.Context, uid string) error {
+	var user = models.User{Id: uid}
+	return s.store.Atomic(ctx, s.enforcer, func(uow *store.UnitOfWork) error {
+		// Remove all user's permissions from casbin rule
+		err := uow.Policies.DeleteUser(user.Id)
+		if err != nil {
+			return err
+		}
+
+		//Delete all user's associations, if exists, from "customer_user" table in DB
+		err = uow.Users.ClearCustomers(ctx, &user)
+		if err != nil {
+			return err
+		}
+
+		// Delete user from "users" table
+		// In this way employee-user association is deleted too, if exists
+		err = uow.Users.Delete(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		// Delete user from firebase, through the outbox
+		return deleteFirebaseUser(ctx, uow, user.Id)
+	})
+}
+
+// findByEmail returns the user of email, failing with user_not_found if there is none
+func findByEmail(ctx context.Context, users store.UserStore, email string) (models.User, error) {
+	user, err := users.FindByEmail(ctx, email)
+	if errors.Is(err, store.ErrNotFound) {
+		return user, apierror.NotFound(apierror.CodeUserNotFound, "There is no user with this email.")
+	}
+	return user, err
+}
+
+// mustExist fails with user_not_found and message if the user of uid does not exist
+func mustExist(ctx context.Context, users store.UserStore, uid string, message string) error {
+	exists, err := users.Exists(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apierror.NotFound(apierror.CodeUserNotFound, message)
+	}
+	return nil
+}
+
+// deleteFirebaseUser queues the deletion of a user from firebase in the outbox of uow.
+// If deletion keeps failing, the outbox worker disables the account and revokes its tokens instead
+func deleteFirebaseUser(ctx context.Context, uow *store.UnitOfWork, uid string) error {
+	return uow.Outbox.Enqueue(ctx, &models.OutboxOperation{Operation: models.OutboxDeleteUser, Uid: uid})
+}
+
+func firebaseUnavailable(message string, cause error) error {
+	return apierror.New(http.StatusBadGateway, apierror.CodeFirebaseUnavailable, message).Wrap(cause)
+}
@@ -0,0 +1,221 @@
+// Package spreadsheet reads the CSV and XLSX files of the bulk imports, and writes those of the exports
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Formats of the files
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+)
+
+// Limits of the files read, so a big (or zip bomb) upload cannot exhaust the memory
+const (
+	MaxFileSize = 10 << 20
+	MaxRows     = 10000
+)
+
+// Sheet is a sheet of a workbook, or a CSV file (without name), its header being the first row
+type Sheet struct {
+	Name string
+	Rows []Row
+}
+
+// Row is a row of a sheet, with its line number in the file (from 1)
+type Row struct {
+	Line  int
+	Cells []string
+}
+
+// FormatOf returns the format of a file from the extension of its name
+func FormatOf(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return CSV, nil
+	case ".xlsx":
+		return XLSX, nil
+	}
+	return "", fmt.Errorf("unsupported file %q: expected a .csv or .xlsx file", filename)
+}
+
+// Read reads the sheets of a file of format: one sheet for CSV, every sheet of an XLSX workbook.
+// Empty rows are skipped, and the cells are trimmed
+func Read(r io.Reader, format string) ([]Sheet, error) {
+	content, err := io.ReadAll(io.LimitReader(r, MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > MaxFileSize {
+		return nil, fmt.Errorf("the file is larger than %d MB", MaxFileSize>>20)
+	}
+
+	switch format {
+	case CSV:
+		rows, err := readCSV(content)
+		if err != nil {
+			return nil, err
+		}
+		return []Sheet{{Rows: rows}}, nil
+	case XLSX:
+		return readXLSX(content)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+func readCSV(content []byte) ([]Row, error) {
+	// Excel saves CSV files with a byte order mark
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	// Excel also uses ";" as separator in some locales
+	if firstLine, _, _ := bytes.Cut(content, []byte("\n")); bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if rows, err = appendRow(rows, line, record); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readXLSX(content []byte) ([]Sheet, error) {
+	workbook, err := excelize.OpenReader(bytes.NewReader(content), excelize.Options{UnzipSizeLimit: 10 * MaxFileSize})
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX: %w", err)
+	}
+	defer workbook.Close()
+
+	var sheets []Sheet
+	for _, name := range workbook.GetSheetList() {
+		records, err := workbook.GetRows(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX sheet %q: %w", name, err)
+		}
+		sheet := Sheet{Name: name}
+		for i, record := range records {
+			if sheet.Rows, err = appendRow(sheet.Rows, i+1, record); err != nil {
+				return nil, fmt.Errorf("sheet %q: %w", name, err)
+			}
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets, nil
+}
+
+// appendRow appends the trimmed record of line to rows, unless all its cells are empty
+func appendRow(rows []Row, line int, record []string) ([]Row, error) {
+	empty := true
+	for i := range record {
+		record[i] = strings.TrimSpace(record[i])
+		empty = empty && record[i] == ""
+	}
+	if empty {
+		return rows, nil
+	}
+	if len(rows) > MaxRows {
+		return nil, fmt.Errorf("more than %d rows", MaxRows)
+	}
+	return append(rows, Row{Line: line, Cells: record}), nil
+}
+
+// ReadSheet reads the file filename as the sheet name: a CSV file, or the first sheet of an XLSX workbook
+func ReadSheet(r io.Reader, filename string, name string) (Sheet, error) {
+	format, err := FormatOf(filename)
+	if err != nil {
+		return Sheet{}, err
+	}
+	sheets, err := Read(r, format)
+	if err != nil {
+		return Sheet{}, fmt.Errorf("%s: %w", filename, err)
+	}
+	if len(sheets) == 0 {
+		return Sheet{Name: name}, nil
+	}
+	return Sheet{Name: name, Rows: sheets[0].Rows}, nil
+}
+
+// ReadWorkbook reads every sheet of the XLSX workbook filename
+func ReadWorkbook(r io.Reader, filename string) ([]Sheet, error) {
+	if format, err := FormatOf(filename); err != nil || format != XLSX {
+		return nil, fmt.Errorf("unsupported workbook %q: expected a .xlsx file", filename)
+	}
+	sheets, err := Read(r, XLSX)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return sheets, nil
+}
+
+// ContentType returns the media type of the files of format
+func ContentType(format string) string {
+	if format == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Write writes rows, the header first, in format: a CSV file, or an XLSX workbook with the single sheet name
+func Write(w io.Writer, format string, name string, rows [][]string) error {
+	switch format {
+	case CSV:
+		// Without the byte order mark, Excel does not read the file as UTF-8
+		if _, err := io.WriteString(w, "\xef\xbb\xbf"); err != nil {
+			return err
+		}
+		return csv.NewWriter(w).WriteAll(rows)
+	case XLSX:
+		return writeXLSX(w, name, rows)
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+func writeXLSX(w io.Writer, name string, rows [][]string) error {
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+	if err := workbook.SetSheetName(workbook.GetSheetName(0), name); err != nil {
+		return err
+	}
+
+	sheet, err := workbook.NewStreamWriter(name)
+	if err != nil {
+		return err
+	}
+	for i, row := range rows {
+		cells := make([]interface{}, len(row))
+		for j := range row {
+			cells[j] = row[j]
+		}
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		if err = sheet.SetRow(cell, cells); err != nil {
+			return err
+		}
+	}
+	if err = sheet.Flush(); err != nil {
+		return err
+	}
+	return workbook.Write(w)
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"backend/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// CustomerStore gives access to "customers" and their associations in "customer_user"
+type CustomerStore interface {
+	// List returns the page of customers whose id or name matches the keyword of filter, and their total
+	List(ctx context.Context, filter models.Filter) ([]models.Customer, int64, error)
+	// Exists reports whether the customer with id is in "customers"
+	Exists(ctx context.Context, id int) (bool, error)
+	// Find returns the customer with id, or ErrNotFound
+	Find(ctx context.Context, id int) (models.Customer, error)
+	Create(ctx context.Context, customer *models.Customer) error
+	// Rename sets the name of the customer with the id of customer, or fails with ErrNotFound if there is none
+	Rename(ctx context.Context, customer *models.Customer) error
+	Delete(ctx context.Context, customer *models.Customer) error
+
+	// ListWithUsers returns all customers by id, each with its users by email
+	ListWithUsers(ctx context.Context) ([]models.Customer, error)
+
+	// Users returns all users associated with customer
+	Users(ctx context.Context, customer *models.Customer) ([]models.User, error)
+	// AssociatedUsers returns id and email of all users associated with customer id
+	AssociatedUsers(ctx context.Context, customerId int) ([]models.CustomerUser, error)
+	// HasUser reports whether the customer - user association exists
+	HasUser(ctx context.Context, customerId int, userId string) (bool, error)
+	AddUser(ctx context.Context, customer *models.Customer, user *models.User) error
+	RemoveUser(ctx context.Context, customer *models.Customer, user *models.User) error
+	// ClearUsers deletes all associations with customer from "customer_user"
+	ClearUsers(ctx context.Context, customer *models.Customer) error
+}
+
+type customerStore struct {
+	db *gorm.DB
+}
+
+var customerSortColumns = sortColumns{
+	columns: map[string]string{"id": "id", "full_name": "full_name"},
+	key:     "id",
+}
+
+// CustomerFilters are the fields of the filter of the customers list
+var CustomerFilters = FilterFields{
+	{Name: "id", Type: FilterInt, Description: "id (podio id) of the customer", condition: equals("customers.id")},
+	{Name: "full_name", Type: FilterString, Description: "exact name of the customer", condition: equals("customers.full_name")},
+	{Name: "user_id", Type: FilterString, Description: "customers associated with this user", condition: func(value interface{}) (string, []interface{}) {
+		return "customers.id IN (SELECT customer_id FROM customer_user WHERE user_id = ?)", []interface{}{value}
+	}},
+	{Name: "has_users", Type: FilterBool, Description: "customers associated with at least one user", condition: func(value interface{}) (string, []interface{}) {
+		exists := "EXISTS (SELECT 1 FROM customer_user WHERE customer_user.customer_id = customers.id)"
+		if value.(bool) {
+			return exists, nil
+		}
+		return "NOT " + exists, nil
+	}},
+}
+
+func (s *customerStore) List(ctx context.Context, filter models.Filter) ([]models.Customer, int64, error) {
+	var customers []models.Customer
+	query := s.db.WithContext(ctx).Model(&models.Customer{}).
+		Select("id", "full_name").
+		Scopes(containsKeyword(filter.Keyword, "id", "full_name"))
+	total, err := findPage(query, filter, customerSortColumns, CustomerFilters, &customers)
+	return customers, total, err
+}
+
+func (s *customerStore) Exists(ctx context.Context, id int) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Customer{}).Where(&models.Customer{Id: id}).Count(&count).Error
+	return count > 0, err
+}
+
+func (s *customerStore) Find(ctx context.Context, id int) (models.Customer, error) {
+	var customer models.Customer
+	err := s.db.WithContext(ctx).Where(&models.Customer{Id: id}).First(&customer).Error
+	return customer, err
+}
+
+func (s *customerStore) Create(ctx context.Context, customer *models.Customer) error {
+	return s.db.WithContext(ctx).Create(customer).Error
+}
+
+func (s *customerStore) Rename(ctx context.Context, customer *models.Customer) error {
+	return rename(s.db.WithContext(ctx), &models.Customer{}, customer.Id, customer.FullName)
+}
+
+func (s *customerStore) Delete(ctx context.Context, customer *models.Customer) error {
+	return s.db.WithContext(ctx).Delete(customer).Error
+}
+
+func (s *customerStore) ListWithUsers(ctx context.Context) ([]models.Customer, error) {
+	var customers []models.Customer
+	err := s.db.WithContext(ctx).Order("id").
+		Preload("Users", func(db *gorm.DB) *gorm.DB { return db.Order("email") }).
+		Find(&customers).Error
+	return customers, err
+}
+
+func (s *customerStore) Users(ctx context.Context, customer *models.Customer) ([]models.User, error) {
+	var users []models.User
+	err := s.db.WithContext(ctx).Model(customer).Association("Users").Find(&users)
+	return users, err
+}
+
+func (s *customerStore) AssociatedUsers(ctx context.Context, customerId int) ([]models.CustomerUser, error) {
+	var users []models.CustomerUser
+	err := s.db.WithContext(ctx).
+		Table("users").
+		Joins("JOIN customer_user ON customer_user.user_id = users.id").
+		Joins("JOIN customers ON customers.id = customer_user.customer_id").
+		Where("customers.id = ?", customerId).
+		Select("users.id, users.email, FALSE AS has_performance_access, FALSE AS has_financial_access").
+		Scan(&users).Error
+	return users, err
+}
+
+func (s *customerStore) HasUser(ctx context.Context, customerId int, userId string) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Table("customer_user").Where("customer_id = ? AND user_id = ?", customerId, userId).Count(&count).Error
+	return count > 0, err
+}
+
+func (s *customerStore) AddUser(ctx context.Context, customer *models.Customer, user *models.User) error {
+	return s.db.WithContext(ctx).Model(customer).Association("Users").Append(user)
+}
+
+func (s *customerStore) RemoveUser(ctx context.Context, customer *models.Customer, user *models.User) error {
+	return s.db.WithContext(ctx).Model(user).Association("Customers").Delete(customer)
+}
+
+func (s *customerStore) ClearUsers(ctx context.Context, customer *models.Customer) error {
+	return s.db.WithContext(ctx).Model(customer).Association("Users").Clear()
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"backend/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// EmployeeStore gives access to "employees" and their users
+type EmployeeStore interface {
+	// List returns the page of employees whose id or name matches the keyword of filter, and their total
+	List(ctx context.Context, filter models.Filter) ([]models.Employee, int64, error)
+	// FindByName returns the employees named fullName
+	FindByName(ctx context.Context, fullName string) ([]models.Employee, error)
+	Create(ctx context.Context, employee *models.Employee) error
+	// Rename sets the name of the employee with the id of employee, or fails with ErrNotFound if there is none
+	Rename(ctx context.Context, employee *models.Employee) error
+	Delete(ctx context.Context, employee *models.Employee) error
+
+	// ListWithUsers returns all employees by id, each with its users by email
+	ListWithUsers(ctx context.Context) ([]models.Employee, error)
+
+	// Users returns all users associated with employee
+	Users(ctx context.Context, employee *models.Employee) ([]models.User, error)
+	// AssociatedUsers returns id and email of all users associated with employee id
+	AssociatedUsers(ctx context.Context, employeeId int) ([]models.EmployeeUser, error)
+	AddUser(ctx context.Context, employee *models.Employee, user *models.User) error
+	RemoveUser(ctx context.Context, employee *models.Employee, user *models.User) error
+	// ClearUsers sets employee_id to null for all users of employee
+	ClearUsers(ctx context.Context, employee *models.Employee) error
+}
+
+type employeeStore struct {
+	db *gorm.DB
+}
+
+var employeeSortColumns = sortColumns{
+	columns: map[string]string{"id": "id", "full_name": "full_name"},
+	key:     "id",
+}
+
+// EmployeeFilters are the fields of the filter of the employees list
+var EmployeeFilters = FilterFields{
+	{Name: "id", Type: FilterInt, Description: "id of the employee", condition: equals("employees.id")},
+	{Name: "full_name", Type: FilterString, Description: "exact name of the employee", condition: equals("employees.full_name")},
+	{Name: "has_users", Type: FilterBool, Description: "employees assigned at least one user", condition: func(value interface{}) (string, []interface{}) {
+		exists := "EXISTS (SELECT 1 FROM users WHERE users.employee_id = employees.id)"
+		if value.(bool) {
+			return exists, nil
+		}
+		return "NOT " + exists, nil
+	}},
+}
+
+func (s *employeeStore) List(ctx context.Context, filter models.Filter) ([]models.Employee, int64, error) {
+	var employees []models.Employee
+	query := s.db.WithContext(ctx).Model(&models.Employee{}).
+		Select("id", "full_name").
+		Scopes(containsKeyword(filter.Keyword, "id", "full_name"))
+	total, err := findPage(query, filter, employeeSortColumns, EmployeeFilters, &employees)
+	return employees, total, err
+}
+
+func (s *employeeStore) FindByName(ctx context.Context, fullName string) ([]models.Employee, error) {
+	var employees []models.Employee
+	err := s.db.WithContext(ctx).Where(&models.Employee{FullName: fullName}).Find(&employees).Error
+	return employees, err
+}
+
+func (s *employeeStore) Create(ctx context.Context, employee *models.Employee) error {
+	return s.db.WithContext(ctx).Create(employee).Error
+}
+
+func (s *employeeStore) Rename(ctx context.Context, employee *models.Employee) error {
+	return rename(s.db.WithContext(ctx), &models.Employee{}, employee.Id, employee.FullName)
+}
+
+func (s *employeeStore) Delete(ctx context.Context, employee *models.Employee) error {
+	return s.db.WithContext(ctx).Delete(employee).Error
+}
+
+func (s *employeeStore) ListWithUsers(ctx context.Context) ([]models.Employee, error) {
+	var employees []models.Employee
+	err := s.db.WithContext(ctx).Order("id").
+		Preload("Users", func(db *gorm.DB) *gorm.DB { return db.Order("email") }).
+		Find(&employees).Error
+	return employees, err
+}
+
+func (s *employeeStore) Users(ctx context.Context, employee *models.Employee) ([]models.User, error) {
+	var users []models.User
+	err := s.db.WithContext(ctx).Model(employee).Association("Users").Find(&users)
+	return users, err
+}
+
+func (s *employeeStore) AssociatedUsers(ctx context.Context, employeeId int) ([]models.EmployeeUser, error) {
+	var users []models.EmployeeUser
+	err := s.db.WithContext(ctx).Table("users").
+		Joins("JOIN employees ON users.employee_id = employees.id").
+		Select("users.id, users.email").
+		Where("employees.id = ?", employeeId).
+		Find(&users).Error
+	return users, err
+}
+
+func (s *employeeStore) AddUser(ctx context.Context, employee *models.Employee, user *models.User) error {
+	return s.db.WithContext(ctx).Model(employee).Association("Users").Append(user)
+}
+
+func (s *employeeStore) RemoveUser(ctx context.Context, employee *models.Employee, user *models.User) error {
+	return s.db.WithContext(ctx).Model(employee).Association("Users").Delete(user)
+}
+
+func (s *employeeStore) ClearUsers(ctx context.Context, employee *models.Employee) error {
+	return s.db.WithContext(ctx).Model(employee).Association("Users").Clear()
+}
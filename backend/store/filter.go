@@ -0,0 +1,367 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// The filter language of the list endpoints, given in the "filter" query parameter:
+//
+//	role=admin and (email_domain=example.com or has_employee=false)
+//
+// Each condition is a field of the list, "=" or "!=", and a value (in double quotes if it has spaces or parentheses).
+// Conditions are combined with "and" and "or" (and binds tighter) and grouped with parentheses.
+// Values are always passed to the database as parameters, never in the SQL.
+
+// Limits of a filter, so that a request cannot build an arbitrarily large query
+const (
+	maxFilterLength     = 1024
+	maxFilterConditions = 20
+)
+
+// Types of the values of the filter fields
+const (
+	FilterString = "string"
+	FilterInt    = "integer"
+	FilterBool   = "boolean"
+	// RFC 3339 (2023-01-31T12:00:00Z) or a date (2023-01-31)
+	FilterTime = "time"
+)
+
+// InvalidFilterError is returned when the filter of a list cannot be parsed, or uses a field the list does not have
+type InvalidFilterError struct {
+	Message string
+}
+
+func (e *InvalidFilterError) Error() string {
+	return "invalid filter: " + e.Message
+}
+
+// FilterField is a field of the filter language of a list
+type FilterField struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	// condition is the SQL condition matching the value (already parsed to Type), with "?" placeholders
+	condition func(value interface{}) (string, []interface{})
+}
+
+// FilterFields are the fields a list can be filtered by
+type FilterFields []FilterField
+
+func (fields FilterFields) lookup(name string) (FilterField, bool) {
+	for _, field := range fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return FilterField{}, false
+}
+
+func (fields FilterFields) names() []string {
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		names = append(names, field.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Describe is a one line documentation of the fields, e.g. for the endpoints' documentation
+func (fields FilterFields) Describe() string {
+	descriptions := make([]string, 0, len(fields))
+	for _, field := range fields {
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s): %s", field.Name, field.Type, field.Description))
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+// matchesFilter is a scope matching the rows of filter, parsed against fields. An empty filter matches every row
+func matchesFilter(filter string, fields FilterFields) (func(db *gorm.DB) *gorm.DB, error) {
+	if strings.TrimSpace(filter) == "" {
+		return func(db *gorm.DB) *gorm.DB { return db }, nil
+	}
+	if len(filter) > maxFilterLength {
+		return nil, &InvalidFilterError{Message: fmt.Sprintf("longer than %d characters", maxFilterLength)}
+	}
+
+	tokens, err := tokenizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	parser := filterParser{tokens: tokens, fields: fields}
+	sql, args, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !parser.done() {
+		return nil, &InvalidFilterError{Message: fmt.Sprintf("unexpected %q", parser.peek().text)}
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(sql, args...)
+	}, nil
+}
+
+type filterTokenKind int
+
+const (
+	tokenWord filterTokenKind = iota
+	tokenQuoted
+	tokenOpen
+	tokenClose
+	tokenEqual
+	tokenNotEqual
+	tokenEnd
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+}
+
+func tokenizeFilter(filter string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(filter)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenOpen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenClose, text: ")"})
+			i++
+		case r == '=':
+			tokens = append(tokens, filterToken{kind: tokenEqual, text: "="})
+			i++
+		case r == '!':
+			if i+1 >= len(runes) || runes[i+1] != '=' {
+				return nil, &InvalidFilterError{Message: `expected "!="`}
+			}
+			tokens = append(tokens, filterToken{kind: tokenNotEqual, text: "!="})
+			i += 2
+		case r == '"':
+			var value strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				value.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, &InvalidFilterError{Message: "unterminated quoted value"}
+			}
+			tokens = append(tokens, filterToken{kind: tokenQuoted, text: value.String()})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()=!"`, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokenWord, text: string(runes[start:i])})
+		}
+	}
+	return tokens, nil
+}
+
+// filterParser is a recursive descent parser of:
+//
+//	or        = and { "or" and }
+//	and       = operand { "and" operand }
+//	operand   = "(" or ")" | condition
+//	condition = field ( "=" | "!=" ) value
+type filterParser struct {
+	tokens     []filterToken
+	position   int
+	fields     FilterFields
+	conditions int
+}
+
+func (p *filterParser) peek() filterToken {
+	if p.position >= len(p.tokens) {
+		return filterToken{kind: tokenEnd, text: "end of filter"}
+	}
+	return p.tokens[p.position]
+}
+
+func (p *filterParser) next() filterToken {
+	token := p.peek()
+	if token.kind != tokenEnd {
+		p.position++
+	}
+	return token
+}
+
+func (p *filterParser) done() bool {
+	return p.peek().kind == tokenEnd
+}
+
+func (p *filterParser) keyword(word string) bool {
+	token := p.peek()
+	if token.kind == tokenWord && strings.EqualFold(token.text, word) {
+		p.position++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (string, []interface{}, error) {
+	return p.parseList("OR", p.parseAnd)
+}
+
+func (p *filterParser) parseAnd() (string, []interface{}, error) {
+	return p.parseList("AND", p.parseOperand)
+}
+
+// parseList parses operands separated by operator, and joins them
+func (p *filterParser) parseList(operator string, parseOperand func() (string, []interface{}, error)) (string, []interface{}, error) {
+	sql, args, err := parseOperand()
+	if err != nil {
+		return "", nil, err
+	}
+	parts := []string{sql}
+	for p.keyword(operator) {
+		sql, operandArgs, err := parseOperand()
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, sql)
+		args = append(args, operandArgs...)
+	}
+	if len(parts) == 1 {
+		return parts[0], args, nil
+	}
+	return "(" + strings.Join(parts, " "+operator+" ") + ")", args, nil
+}
+
+func (p *filterParser) parseOperand() (string, []interface{}, error) {
+	if p.peek().kind == tokenOpen {
+		p.next()
+		sql, args, err := p.parseOr()
+		if err != nil {
+			return "", nil, err
+		}
+		if token := p.next(); token.kind != tokenClose {
+			return "", nil, &InvalidFilterError{Message: fmt.Sprintf(`expected ")", got %q`, token.text)}
+		}
+		return sql, args, nil
+	}
+	return p.parseCondition()
+}
+
+func (p *filterParser) parseCondition() (string, []interface{}, error) {
+	name := p.next()
+	if name.kind != tokenWord {
+		return "", nil, &InvalidFilterError{Message: fmt.Sprintf("expected a field, got %q", name.text)}
+	}
+	field, ok := p.fields.lookup(name.text)
+	if !ok {
+		return "", nil, &InvalidFilterError{Message: fmt.Sprintf("unknown field %q, fields are %s", name.text, strings.Join(p.fields.names(), ", "))}
+	}
+
+	operator := p.next()
+	if operator.kind != tokenEqual && operator.kind != tokenNotEqual {
+		return "", nil, &InvalidFilterError{Message: fmt.Sprintf(`expected "=" or "!=" after %s, got %q`, field.Name, operator.text)}
+	}
+	raw := p.next()
+	if raw.kind != tokenWord && raw.kind != tokenQuoted {
+		return "", nil, &InvalidFilterError{Message: fmt.Sprintf("expected a value for %s, got %q", field.Name, raw.text)}
+	}
+	value, err := parseFilterValue(field, raw.text)
+	if err != nil {
+		return "", nil, err
+	}
+
+	p.conditions++
+	if p.conditions > maxFilterConditions {
+		return "", nil, &InvalidFilterError{Message: fmt.Sprintf("more than %d conditions", maxFilterConditions)}
+	}
+
+	sql, args := field.condition(value)
+	if operator.kind == tokenNotEqual {
+		sql = "NOT " + sql
+	}
+	return "(" + sql + ")", args, nil
+}
+
+func parseFilterValue(field FilterField, raw string) (interface{}, error) {
+	switch field.Type {
+	case FilterInt:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, &InvalidFilterError{Message: fmt.Sprintf("%s must be an integer, got %q", field.Name, raw)}
+		}
+		return value, nil
+	case FilterBool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, &InvalidFilterError{Message: fmt.Sprintf("%s must be true or false, got %q", field.Name, raw)}
+		}
+		return value, nil
+	case FilterTime:
+		if value, err := time.Parse(time.RFC3339, raw); err == nil {
+			return value, nil
+		}
+		value, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, &InvalidFilterError{Message: fmt.Sprintf("%s must be a date (2006-01-02) or a time (RFC 3339), got %q", field.Name, raw)}
+		}
+		return value, nil
+	default:
+		return raw, nil
+	}
+}
+
+// Conditions shared by the fields of several lists
+
+func equals(column string) func(value interface{}) (string, []interface{}) {
+	return func(value interface{}) (string, []interface{}) {
+		return column + " = ?", []interface{}{value}
+	}
+}
+
+func emailDomain(column string) func(value interface{}) (string, []interface{}) {
+	return func(value interface{}) (string, []interface{}) {
+		domain := strings.ToLower(strings.TrimPrefix(value.(string), "@"))
+		return "LOWER(" + column + ") LIKE ? ESCAPE '!'", []interface{}{"%@" + escapeLike(domain)}
+	}
+}
+
+func isNull(column string) func(value interface{}) (string, []interface{}) {
+	return func(value interface{}) (string, []interface{}) {
+		if value.(bool) {
+			return column + " IS NOT NULL", nil
+		}
+		return column + " IS NULL", nil
+	}
+}
+
+// compareTime compares a time column with operator ("<" or ">")
+func compareTime(column string, operator string) func(value interface{}) (string, []interface{}) {
+	return func(value interface{}) (string, []interface{}) {
+		return column + " " + operator + " ?", []interface{}{value.(time.Time)}
+	}
+}
+
+// compareMillis compares a column of milliseconds since the epoch (like firebase's timestamps) with operator
+func compareMillis(column string, operator string) func(value interface{}) (string, []interface{}) {
+	return func(value interface{}) (string, []interface{}) {
+		return column + " " + operator + " ?", []interface{}{value.(time.Time).UnixMilli()}
+	}
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, with "!": unlike the backslash,
+// it needs no escaping in the SQL of any of the supported databases
+func escapeLike(value string) string {
+	return strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`).Replace(value)
+}
@@ -0,0 +1,189 @@
+package store
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// whereOf returns the WHERE clause and the arguments of the query of the users matching filter
+func whereOf(t *testing.T, st *Store, filter string) (string, []interface{}, error) {
+	t.Helper()
+	scope, err := matchesFilter(filter, UserFilters)
+	if err != nil {
+		return "", nil, err
+	}
+	statement := st.DB().Session(&gorm.Session{DryRun: true}).Model(&models.User{}).Scopes(scope).Find(&[]models.User{}).Statement
+	sql := statement.SQL.String()
+	if i := strings.Index(sql, " WHERE "); i >= 0 {
+		return sql[i+len(" WHERE "):], statement.Vars, nil
+	}
+	return "", statement.Vars, nil
+}
+
+func TestMatchesFilter(t *testing.T) {
+	st := openTestStore(t)
+	role := "users.id IN (SELECT v0 FROM casbin_rule WHERE ptype = 'g' AND v1 = ?)"
+	domain := "LOWER(users.email) LIKE ? ESCAPE '!'"
+
+	tests := []struct {
+		name   string
+		filter string
+		where  string
+		args   []interface{}
+	}{
+		{"empty", "  ", "", nil},
+		{"equals", "id=abc", "(users.id = ?)", []interface{}{"abc"}},
+		{"not equals", "id != abc", "(NOT users.id = ?)", []interface{}{"abc"}},
+		{"keywords are case-insensitive", "id=a AND id=b Or id=c", "(((users.id = ?) AND (users.id = ?)) OR (users.id = ?))", []interface{}{"a", "b", "c"}},
+		{"and binds tighter than or", "id=a or id=b and id=c",
+			"((users.id = ?) OR ((users.id = ?) AND (users.id = ?)))", []interface{}{"a", "b", "c"}},
+		{"and after or", "id=a and id=b or id=c",
+			"(((users.id = ?) AND (users.id = ?)) OR (users.id = ?))", []interface{}{"a", "b", "c"}},
+		{"parentheses group or", "id=a and (id=b or id=c)",
+			"((users.id = ?) AND ((users.id = ?) OR (users.id = ?)))", []interface{}{"a", "b", "c"}},
+		{"nested parentheses", "((id=a))", "(users.id = ?)", []interface{}{"a"}},
+		{"quoted value", `id="a b (c)"`, "(users.id = ?)", []interface{}{"a b (c)"}},
+		{"escaped quote", `id="a\"b"`, "(users.id = ?)", []interface{}{`a"b`}},
+		{"emails are compared in lower case", "email=Foo@Example.com", "(users.email = ?)", []interface{}{"foo@example.com"}},
+		{"like wildcards are escaped", "email_domain=@ex_am%ple.com", "(" + domain + ")", []interface{}{"%@ex!_am!%ple.com"}},
+		{"integer", "employee_id=7", "(users.employee_id = ?)", []interface{}{7}},
+		{"boolean", "has_employee=false", "(users.employee_id IS NULL)", nil},
+		{"boolean not equals", "has_employee!=true", "(NOT users.employee_id IS NOT NULL)", nil},
+		{"date", "created_after=2023-01-31", "(users.creation_timestamp > ?)",
+			[]interface{}{time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC).UnixMilli()}},
+		{"time", "last_login_before=2023-01-31T12:00:00Z", "(users.last_login_timestamp < ?)",
+			[]interface{}{time.Date(2023, 1, 31, 12, 0, 0, 0, time.UTC).UnixMilli()}},
+		{"subquery", "role=admin", "(" + role + ")", []interface{}{"admin"}},
+		{"value looking like sql", `id="x' OR '1'='1"`, "(users.id = ?)", []interface{}{"x' OR '1'='1"}},
+		{"value looking like a statement", `role="admin'); DROP TABLE users; --"`, "(" + role + ")", []interface{}{"admin'); DROP TABLE users; --"}},
+		{"conditions limit", strings.TrimSuffix(strings.Repeat("id=a or ", maxFilterConditions), " or "),
+			"(" + strings.TrimSuffix(strings.Repeat("(users.id = ?) OR ", maxFilterConditions), " OR ") + ")",
+			func() []interface{} {
+				args := make([]interface{}, maxFilterConditions)
+				for i := range args {
+					args[i] = "a"
+				}
+				return args
+			}()},
+		{"deep nesting within the length limit", strings.Repeat("(", 100) + "id=a" + strings.Repeat(")", 100), "(users.id = ?)", []interface{}{"a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			where, args, err := whereOf(t, st, test.filter)
+			if err != nil {
+				t.Fatalf("matchesFilter(%q) failed: %v", test.filter, err)
+			}
+			if where != test.where {
+				t.Errorf("matchesFilter(%q) where = %q, want %q", test.filter, where, test.where)
+			}
+			if len(args) != 0 || len(test.args) != 0 {
+				if !reflect.DeepEqual(args, test.args) {
+					t.Errorf("matchesFilter(%q) args = %#v, want %#v", test.filter, args, test.args)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchesFilterInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		message string
+	}{
+		{"unknown field", "name=a", `unknown field "name"`},
+		{"field of another list", "full_name=a", `unknown field "full_name"`},
+		{"missing operator", "id a", `expected "=" or "!=" after id, got "a"`},
+		{"unsupported operator", "id < a", `expected "=" or "!=" after id, got "<"`},
+		{"lone bang", "id!a", `expected "!="`},
+		{"missing value", "id=", `expected a value for id, got "end of filter"`},
+		{"missing field", "=a", `expected a field, got "="`},
+		{"dangling and", "id=a and", `expected a field, got "end of filter"`},
+		{"missing operand", "id=a or or id=b", `unknown field "or"`},
+		{"unclosed parenthesis", "(id=a", `expected ")", got "end of filter"`},
+		{"extra parenthesis", "id=a)", `unexpected ")"`},
+		{"empty parentheses", "()", `expected a field, got ")"`},
+		{"juxtaposed conditions", "id=a id=b", `unexpected "id"`},
+		{"unterminated quote", `id="a`, "unterminated quoted value"},
+		{"invalid integer", "employee_id=seven", `employee_id must be an integer, got "seven"`},
+		{"invalid boolean", "has_employee=maybe", `has_employee must be true or false, got "maybe"`},
+		{"invalid time", "created_after=yesterday", `created_after must be a date`},
+		{"sql in an integer", "employee_id=1;DROP", `employee_id must be an integer, got "1;DROP"`},
+		{"too many conditions", strings.TrimSuffix(strings.Repeat("id=a or ", maxFilterConditions+1), " or "), "more than 20 conditions"},
+		{"too long", "id=" + strings.Repeat("a", maxFilterLength), "longer than 1024 characters"},
+		{"too deep", strings.Repeat("(", maxFilterLength) + "id=a" + strings.Repeat(")", maxFilterLength), "longer than 1024 characters"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := matchesFilter(test.filter, UserFilters)
+			var invalid *InvalidFilterError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("matchesFilter(%q) error = %v, want an InvalidFilterError", test.filter, err)
+			}
+			if !strings.Contains(invalid.Message, test.message) {
+				t.Errorf("matchesFilter(%q) error = %q, want it to contain %q", test.filter, invalid.Message, test.message)
+			}
+		})
+	}
+}
+
+// Values are passed as parameters: whatever they contain, they are only compared with the column
+func TestMatchesFilterRows(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	for _, user := range []models.User{
+		{Id: "a", Email: "a@example.com"},
+		{Id: "b", Email: "b@other.com"},
+		{Id: "x' OR '1'='1", Email: "quote@example.com"},
+	} {
+		user := user
+		if err := st.Users.Create(ctx, &user); err != nil {
+			t.Fatalf("failed to create user %s: %v", user.Id, err)
+		}
+	}
+
+	tests := []struct {
+		filter string
+		ids    []string
+	}{
+		{"", []string{"a", "b", "x' OR '1'='1"}},
+		{"email_domain=example.com", []string{"a", "x' OR '1'='1"}},
+		{"email_domain=example.com and id != a", []string{"x' OR '1'='1"}},
+		{"id=a or id=b and email_domain=example.com", []string{"a"}},
+		{"(id=a or id=b) and email_domain=example.com", []string{"a"}},
+		{"(id=a or id=b) and email_domain!=example.com", []string{"b"}},
+		{`id="x' OR '1'='1"`, []string{"x' OR '1'='1"}},
+		{`id="a' OR '1'='1"`, []string{}},
+		{`email="a@example.com' --"`, []string{}},
+		{"email_domain=%", []string{}},
+		{"email_domain=_xample.com", []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.filter, func(t *testing.T) {
+			scope, err := matchesFilter(test.filter, UserFilters)
+			if err != nil {
+				t.Fatalf("matchesFilter(%q) failed: %v", test.filter, err)
+			}
+			ids := []string{}
+			if err = st.DB().Model(&models.User{}).Scopes(scope).Order("id").Pluck("id", &ids).Error; err != nil {
+				t.Fatalf("query of %q failed: %v", test.filter, err)
+			}
+			if !reflect.DeepEqual(ids, test.ids) {
+				t.Errorf("users matching %q = %q, want %q", test.filter, ids, test.ids)
+			}
+		})
+	}
+
+	// The table survived the values above
+	var count int64
+	if err := st.DB().Model(&models.User{}).Count(&count).Error; err != nil || count != 3 {
+		t.Errorf("users count = %d (%v), want 3", count, err)
+	}
+}
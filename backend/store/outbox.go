@@ -0,0 +1,126 @@
+package store
+
+import (
+	"backend/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxStore gives access to the queue of Firebase operations in "firebase_outbox"
+type OutboxStore interface {
+	// Enqueue queues op as pending. An operation with the same idempotency key is never queued twice
+	// (the key defaults to "<operation>:<uid>")
+	Enqueue(ctx context.Context, op *models.OutboxOperation) error
+	// Due returns up to limit pending operations whose next attempt is due. The operations of a uid run in the order
+	// they were queued: one is not due while an older operation of the same uid is pending (e.g. waiting for a retry) or processing
+	Due(ctx context.Context, now time.Time, limit int) ([]models.OutboxOperation, error)
+	// Claim marks a pending operation as processing. It returns false if another worker claimed it first
+	Claim(ctx context.Context, id uint) (bool, error)
+	// Complete marks an operation as done
+	Complete(ctx context.Context, id uint) error
+	// Fail records a failed attempt. The operation is retried at nextAttemptAt, or marked as failed if giveUp is true
+	Fail(ctx context.Context, id uint, attempts int, lastError string, nextAttemptAt time.Time, giveUp bool) error
+	// List returns the page of operations with status (all operations if status is empty), newest first by default,
+	// and their total
+	List(ctx context.Context, status string, filter models.Filter) ([]models.OutboxOperation, int64, error)
+	// Retry queues a failed (or stuck) operation again, with a fresh set of attempts
+	Retry(ctx context.Context, id uint) (bool, error)
+	// Release puts operations left in processing (e.g. by a crashed instance) for longer than timeout back to pending
+	Release(ctx context.Context, timeout time.Duration) error
+}
+
+type outboxStore struct {
+	db *gorm.DB
+	// set when an operation is queued inside a unit of work, to wake up the worker after commit
+	enqueued bool
+}
+
+func (s *outboxStore) Enqueue(ctx context.Context, op *models.OutboxOperation) error {
+	op.Status = models.OutboxPending
+	if op.IdempotencyKey == "" {
+		op.IdempotencyKey = op.Operation + ":" + op.Uid
+	}
+	if op.NextAttemptAt.IsZero() {
+		op.NextAttemptAt = time.Now()
+	}
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(op).Error
+	if err == nil {
+		s.enqueued = true
+	}
+	return err
+}
+
+func (s *outboxStore) Due(ctx context.Context, now time.Time, limit int) ([]models.OutboxOperation, error) {
+	var ops []models.OutboxOperation
+	err := s.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, now).
+		Where("NOT EXISTS (SELECT 1 FROM firebase_outbox AS older WHERE older.uid = firebase_outbox.uid AND older.id < firebase_outbox.id AND older.status IN ?)",
+			[]string{models.OutboxPending, models.OutboxProcessing}).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&ops).Error
+	return ops, err
+}
+
+func (s *outboxStore) Claim(ctx context.Context, id uint) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.OutboxOperation{}).
+		Where("id = ? AND status = ?", id, models.OutboxPending).
+		Update("status", models.OutboxProcessing)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *outboxStore) Complete(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Model(&models.OutboxOperation{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"status": models.OutboxDone, "last_error": "", "password_hash": ""}).Error
+}
+
+func (s *outboxStore) Fail(ctx context.Context, id uint, attempts int, lastError string, nextAttemptAt time.Time, giveUp bool) error {
+	status := models.OutboxPending
+	if giveUp {
+		status = models.OutboxFailed
+	}
+	return s.db.WithContext(ctx).Model(&models.OutboxOperation{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "attempts": attempts, "last_error": lastError, "next_attempt_at": nextAttemptAt}).Error
+}
+
+// OutboxFilters are the fields of the filter of the outbox operations list
+var OutboxFilters = FilterFields{
+	{Name: "status", Type: FilterString, Description: "pending, processing, done or failed", condition: equals("status")},
+	{Name: "operation", Type: FilterString, Description: "create, delete, disable or revoke_tokens", condition: equals("operation")},
+	{Name: "created_after", Type: FilterTime, Description: "operations queued after this time", condition: compareTime("created_at", ">")},
+	{Name: "created_before", Type: FilterTime, Description: "operations queued before this time", condition: compareTime("created_at", "<")},
+}
+
+var outboxSortColumns = sortColumns{
+	columns:     map[string]string{"id": "id", "status": "status", "operation": "operation", "attempts": "attempts", "created_at": "created_at"},
+	key:         "id",
+	defaultSort: "-id",
+}
+
+func (s *outboxStore) List(ctx context.Context, status string, filter models.Filter) ([]models.OutboxOperation, int64, error) {
+	var ops []models.OutboxOperation
+	query := s.db.WithContext(ctx).Model(&models.OutboxOperation{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	total, err := findPage(query, filter, outboxSortColumns, OutboxFilters, &ops)
+	return ops, total, err
+}
+
+func (s *outboxStore) Retry(ctx context.Context, id uint) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.OutboxOperation{}).
+		Where("id = ? AND status <> ?", id, models.OutboxDone).
+		Updates(map[string]interface{}{"status": models.OutboxPending, "attempts": 0, "next_attempt_at": time.Now()})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *outboxStore) Release(ctx context.Context, timeout time.Duration) error {
+	return s.db.WithContext(ctx).Model(&models.OutboxOperation{}).
+		Where("status = ? AND updated_at < ?", models.OutboxProcessing, time.Now().Add(-timeout)).
+		Update("status", models.OutboxPending).Error
+}
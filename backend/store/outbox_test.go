@@ -0,0 +1,206 @@
+package store
+
+import (
+	"backend/models"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// enqueue queues the operations, and returns their ids
+func enqueue(t *testing.T, outbox OutboxStore, ops ...models.OutboxOperation) []uint {
+	t.Helper()
+	ids := make([]uint, 0, len(ops))
+	for _, op := range ops {
+		op := op
+		if err := outbox.Enqueue(context.Background(), &op); err != nil {
+			t.Fatalf("failed to enqueue %s %s: %v", op.Operation, op.Uid, err)
+		}
+		ids = append(ids, op.Id)
+	}
+	return ids
+}
+
+// checkDue checks the ids of the operations due at now
+func checkDue(t *testing.T, outbox OutboxStore, now time.Time, want ...uint) {
+	t.Helper()
+	due, err := outbox.Due(context.Background(), now, 50)
+	if err != nil {
+		t.Fatalf("Due failed: %v", err)
+	}
+	ids := []uint{}
+	for _, op := range due {
+		ids = append(ids, op.Id)
+	}
+	if want == nil {
+		want = []uint{}
+	}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("due operations = %v, want %v", ids, want)
+	}
+}
+
+func findOperation(t *testing.T, st *Store, id uint) models.OutboxOperation {
+	t.Helper()
+	var op models.OutboxOperation
+	if err := st.DB().First(&op, id).Error; err != nil {
+		t.Fatalf("failed to find operation %d: %v", id, err)
+	}
+	return op
+}
+
+func TestOutboxEnqueueIsIdempotent(t *testing.T) {
+	st := openTestStore(t)
+	enqueue(t, st.Outbox,
+		models.OutboxOperation{Operation: models.OutboxDeleteUser, Uid: "alice"},
+		models.OutboxOperation{Operation: models.OutboxDeleteUser, Uid: "alice"},
+		models.OutboxOperation{Operation: models.OutboxDisableUser, Uid: "alice"},
+	)
+
+	ops, total, err := st.Outbox.List(context.Background(), "", models.Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if total != 2 {
+		t.Fatalf("%d operations queued, want 2: %+v", total, ops)
+	}
+	for _, op := range ops {
+		if want := op.Operation + ":alice"; op.IdempotencyKey != want || op.Status != models.OutboxPending {
+			t.Errorf("operation %d has key %q and status %s, want %q and pending", op.Id, op.IdempotencyKey, op.Status, want)
+		}
+	}
+}
+
+// The operations of a uid run in the order they were queued
+func TestOutboxDueOrderPerUid(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	ids := enqueue(t, st.Outbox,
+		models.OutboxOperation{Operation: models.OutboxCreateUser, Uid: "alice"},
+		models.OutboxOperation{Operation: models.OutboxDeleteUser, Uid: "alice"},
+		models.OutboxOperation{Operation: models.OutboxDeleteUser, Uid: "bob"},
+	)
+	create, remove, other := ids[0], ids[1], ids[2]
+	now := time.Now().Add(time.Second)
+
+	checkDue(t, st.Outbox, now, create, other)
+
+	// Held back while the older operation is processing
+	if claimed, err := st.Outbox.Claim(ctx, create); err != nil || !claimed {
+		t.Fatalf("Claim = %t, %v, want claimed", claimed, err)
+	}
+	checkDue(t, st.Outbox, now, other)
+
+	// and while it waits for a retry
+	retryAt := now.Add(time.Minute)
+	if err := st.Outbox.Fail(ctx, create, 1, "unavailable", retryAt, false); err != nil {
+		t.Fatalf("Fail failed: %v", err)
+	}
+	checkDue(t, st.Outbox, now, other)
+	// Due operations come by their next attempt
+	checkDue(t, st.Outbox, retryAt, other, create)
+
+	// Released once the older one is done
+	if _, err := st.Outbox.Claim(ctx, create); err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if err := st.Outbox.Complete(ctx, create); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	checkDue(t, st.Outbox, now, remove, other)
+}
+
+// A failed operation (out of attempts) no longer holds back the next ones of its uid, until it is retried
+func TestOutboxFailedOperation(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	ids := enqueue(t, st.Outbox,
+		models.OutboxOperation{Operation: models.OutboxCreateUser, Uid: "alice"},
+		models.OutboxOperation{Operation: models.OutboxDeleteUser, Uid: "alice"},
+	)
+	now := time.Now().Add(time.Second)
+
+	if _, err := st.Outbox.Claim(ctx, ids[0]); err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if err := st.Outbox.Fail(ctx, ids[0], 10, "invalid email", now, true); err != nil {
+		t.Fatalf("Fail failed: %v", err)
+	}
+	op := findOperation(t, st, ids[0])
+	if op.Status != models.OutboxFailed || op.Attempts != 10 || op.LastError != "invalid email" {
+		t.Errorf("operation is %s after %d attempts (%q), want failed after 10 (%q)", op.Status, op.Attempts, op.LastError, "invalid email")
+	}
+	checkDue(t, st.Outbox, now, ids[1])
+
+	if retried, err := st.Outbox.Retry(ctx, ids[0]); err != nil || !retried {
+		t.Fatalf("Retry = %t, %v, want retried", retried, err)
+	}
+	op = findOperation(t, st, ids[0])
+	if op.Status != models.OutboxPending || op.Attempts != 0 {
+		t.Errorf("operation is %s after %d attempts, want pending after 0", op.Status, op.Attempts)
+	}
+	checkDue(t, st.Outbox, now, ids[0])
+
+	// Done operations are never retried
+	if _, err := st.Outbox.Claim(ctx, ids[0]); err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if err := st.Outbox.Complete(ctx, ids[0]); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if retried, err := st.Outbox.Retry(ctx, ids[0]); err != nil || retried {
+		t.Errorf("Retry of a done operation = %t, %v, want not retried", retried, err)
+	}
+}
+
+func TestOutboxClaim(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	ids := enqueue(t, st.Outbox, models.OutboxOperation{Operation: models.OutboxDeleteUser, Uid: "alice"})
+
+	// Only one worker gets it
+	for i, want := range []bool{true, false} {
+		claimed, err := st.Outbox.Claim(ctx, ids[0])
+		if err != nil || claimed != want {
+			t.Errorf("claim %d = %t, %v, want %t", i+1, claimed, err, want)
+		}
+	}
+	if op := findOperation(t, st, ids[0]); op.Status != models.OutboxProcessing {
+		t.Errorf("operation is %s, want processing", op.Status)
+	}
+	if claimed, err := st.Outbox.Claim(ctx, ids[0]+1); err != nil || claimed {
+		t.Errorf("claim of a missing operation = %t, %v, want not claimed", claimed, err)
+	}
+}
+
+// Operations abandoned in processing go back to pending after the timeout, and only them
+func TestOutboxRelease(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	ids := enqueue(t, st.Outbox,
+		models.OutboxOperation{Operation: models.OutboxDeleteUser, Uid: "abandoned"},
+		models.OutboxOperation{Operation: models.OutboxDeleteUser, Uid: "running"},
+		models.OutboxOperation{Operation: models.OutboxDeleteUser, Uid: "pending"},
+	)
+	for _, id := range ids[:2] {
+		if _, err := st.Outbox.Claim(ctx, id); err != nil {
+			t.Fatalf("Claim failed: %v", err)
+		}
+	}
+	err := st.DB().Model(&models.OutboxOperation{}).Where("id = ?", ids[0]).
+		UpdateColumn("updated_at", time.Now().Add(-10*time.Minute)).Error
+	if err != nil {
+		t.Fatalf("failed to age the operation: %v", err)
+	}
+
+	if err = st.Outbox.Release(ctx, 5*time.Minute); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	for i, want := range []string{models.OutboxPending, models.OutboxProcessing, models.OutboxPending} {
+		if op := findOperation(t, st, ids[i]); op.Status != want {
+			t.Errorf("operation of %s is %s, want %s", op.Uid, op.Status, want)
+		}
+	}
+	checkDue(t, st.Outbox, time.Now().Add(time.Second), ids[0], ids[2])
+}
@@ -0,0 +1,24 @@
+package store
+
+import (
+	"backend/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// PermissionStore gives access to "permissions", the catalogue of permissions shown in frontend
+type PermissionStore interface {
+	// List returns all permissions ordered by category
+	List(ctx context.Context) ([]models.Permission, error)
+}
+
+type permissionStore struct {
+	db *gorm.DB
+}
+
+func (s *permissionStore) List(ctx context.Context) ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := s.db.WithContext(ctx).Order("category").Find(&permissions).Error
+	return permissions, err
+}
@@ -0,0 +1,307 @@
+package store
+
+import (
+	"backend/metrics"
+	"backend/models"
+	"backend/tracing"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// PolicyTx writes casbin rules through a gorm adapter bound to the transaction of a UnitOfWork.
+// The in-memory policy of the enforcer is only updated after the transaction has been committed,
+// so a rolled back unit of work never leaves the enforcer out of sync with "casbin_rule".
+// Every change is also logged in the transaction, for the other instances to replay (see PolicyWatcher).
+// Rules are looked up in the transaction, never in the enforcer: a unit of work holding the policy revision row
+// must not wait for the lock of the enforcer, which is held while the changes of another one are applied.
+type PolicyTx struct {
+	tx       *gorm.DB
+	enforcer *casbin.SyncedEnforcer
+	adapter  *gormadapter.Adapter
+	instance string
+	listener PolicyListener
+	changes  []models.PolicyChange
+}
+
+// PolicyListener is notified of the changes to the in-memory policy of the enforcer.
+// It is called while the enforcer is locked, so it must not use the enforcer.
+type PolicyListener interface {
+	// PolicyChanged is called after change has been applied, with its decoded rules
+	PolicyChanged(change models.PolicyChange, rules [][]string)
+	// PolicyReloaded is called after the whole policy has been reloaded
+	PolicyReloaded()
+}
+
+// NewEnforcer returns a casbin enforcer of the model in modelFile, storing its policy in "casbin_rule"
+// (created by the schema migrations, not by the adapter). Its policy is loaded by NewPolicyWatcher.
+func (s *Store) NewEnforcer(modelFile string) (*casbin.SyncedEnforcer, error) {
+	adapterDB := s.db.WithContext(context.Background())
+	gormadapter.TurnOffAutoMigrate(adapterDB)
+	adapter, err := gormadapter.NewAdapterByDB(adapterDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize casbin adapter: %w", err)
+	}
+
+	// Load models configuration file and policy store adapter
+	enforcer, err := casbin.NewSyncedEnforcer(modelFile, adapter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create casbin enforcer: %w", err)
+	}
+	return enforcer, nil
+}
+
+// errPolicyReload is returned when a change cannot be applied incrementally, and the whole policy must be reloaded
+var errPolicyReload = errors.New("policy must be reloaded")
+
+func newPolicyTx(tx *gorm.DB, enforcer *casbin.SyncedEnforcer, instance string, listener PolicyListener) (*PolicyTx, error) {
+	// Never run the adapter's auto migration inside a transaction (DDL commits implicitly in MySQL)
+	gormadapter.TurnOffAutoMigrate(tx)
+	adapter, err := gormadapter.NewAdapterByDB(tx)
+	if err != nil {
+		return nil, err
+	}
+	return &PolicyTx{tx: tx, enforcer: enforcer, adapter: adapter, instance: instance, listener: listener}, nil
+}
+
+// HasPolicy reports whether the policy, with the changes of this unit of work, has the rule sub, obj, act
+func (p *PolicyTx) HasPolicy(rule ...string) (bool, error) {
+	return p.has("p", "p", rule)
+}
+
+// AddPolicy adds the policy rule sub, obj, act, if it does not exist yet
+func (p *PolicyTx) AddPolicy(rule ...string) error {
+	return p.add("p", "p", rule)
+}
+
+// RemovePolicy removes the policy rule sub, obj, act, if it exists
+func (p *PolicyTx) RemovePolicy(rule ...string) error {
+	return p.remove("p", "p", rule)
+}
+
+// AddNamedPolicy adds a rule of the policy type ptype (of the "p" section), if it does not exist yet
+func (p *PolicyTx) AddNamedPolicy(ptype string, rule ...string) error {
+	return p.add("p", ptype, rule)
+}
+
+// RemoveNamedPolicy removes a rule of the policy type ptype (of the "p" section), if it exists
+func (p *PolicyTx) RemoveNamedPolicy(ptype string, rule ...string) error {
+	return p.remove("p", ptype, rule)
+}
+
+// AddNamedGroupingPolicy adds a rule of the role type ptype (of the "g" section), like user, role, if it does not exist yet
+func (p *PolicyTx) AddNamedGroupingPolicy(ptype string, rule ...string) error {
+	return p.add("g", ptype, rule)
+}
+
+// RemoveNamedGroupingPolicy removes a rule of the role type ptype (of the "g" section), if it exists
+func (p *PolicyTx) RemoveNamedGroupingPolicy(ptype string, rule ...string) error {
+	return p.remove("g", ptype, rule)
+}
+
+// NamedRules returns the rules of type ptype (of either section), as seen by the transaction (with the changes of this unit of work)
+func (p *PolicyTx) NamedRules(ptype string) ([][]string, error) {
+	var rows []models.CasbinRule
+	err := p.tx.Table("casbin_rule").
+		Select("COALESCE(v0, '') AS v0, COALESCE(v1, '') AS v1, COALESCE(v2, '') AS v2, COALESCE(v3, '') AS v3, COALESCE(v4, '') AS v4, COALESCE(v5, '') AS v5").
+		Where("ptype = ?", ptype).
+		Order("id").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	rules := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		rule := []string{row.V0, row.V1, row.V2, row.V3, row.V4, row.V5}
+		// The unused columns are empty, like casbin loads them
+		for len(rule) > 0 && rule[len(rule)-1] == "" {
+			rule = rule[:len(rule)-1]
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// PolicyKey identifies the rule of type ptype, its values being separated by a character they cannot contain
+func PolicyKey(ptype string, rule []string) string {
+	return ptype + "\x00" + strings.Join(rule, "\x00")
+}
+
+// has reports whether "casbin_rule" has the rule, as seen by the transaction (with the changes of this unit of work)
+func (p *PolicyTx) has(sec string, ptype string, rule []string) (bool, error) {
+	if len(rule) > len(ruleColumns) {
+		return false, fmt.Errorf("%s rules have at most %d values", sec, len(ruleColumns))
+	}
+	query := p.tx.Table("casbin_rule").Where("ptype = ?", ptype)
+	for i, column := range ruleColumns {
+		if i < len(rule) {
+			query = query.Where(column+" = ?", rule[i])
+		} else {
+			query = query.Where("(" + column + " IS NULL OR " + column + " = '')")
+		}
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// ruleColumns are the columns of the values of the rules in "casbin_rule"
+var ruleColumns = []string{"v0", "v1", "v2", "v3", "v4", "v5"}
+
+func (p *PolicyTx) add(sec string, ptype string, rule []string) error {
+	exists, err := p.has(sec, ptype, rule)
+	if err != nil || exists {
+		return err
+	}
+	if err = p.adapter.AddPolicy(sec, ptype, rule); err != nil {
+		return err
+	}
+	return p.record(newPolicyChange(models.PolicyAdd, sec, ptype, 0, rule))
+}
+
+func (p *PolicyTx) remove(sec string, ptype string, rule []string) error {
+	exists, err := p.has(sec, ptype, rule)
+	if err != nil || !exists {
+		return err
+	}
+	if err = p.adapter.RemovePolicy(sec, ptype, rule); err != nil {
+		return err
+	}
+	return p.record(newPolicyChange(models.PolicyRemove, sec, ptype, 0, rule))
+}
+
+// AddGroupingPolicy adds the role rule user, role, if it does not exist yet
+func (p *PolicyTx) AddGroupingPolicy(rule ...string) error {
+	return p.add("g", "g", rule)
+}
+
+// RemoveFilteredPolicy removes the policy rules whose values from fieldIndex are fieldValues (an empty value matching any)
+func (p *PolicyTx) RemoveFilteredPolicy(fieldIndex int, fieldValues ...string) error {
+	return p.removeFiltered("p", "p", fieldIndex, fieldValues...)
+}
+
+// RemoveFilteredGroupingPolicy removes the role rules whose values from fieldIndex are fieldValues (an empty value matching any)
+func (p *PolicyTx) RemoveFilteredGroupingPolicy(fieldIndex int, fieldValues ...string) error {
+	return p.removeFiltered("g", "g", fieldIndex, fieldValues...)
+}
+
+// DeleteUser removes all policy and grouping (role) rules of user, like casbin's DeleteUser
+func (p *PolicyTx) DeleteUser(user string) error {
+	if err := p.removeFiltered("g", "g", 0, user); err != nil {
+		return err
+	}
+	return p.removeFiltered("p", "p", 0, user)
+}
+
+func (p *PolicyTx) removeFiltered(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	if err := p.adapter.RemoveFilteredPolicy(sec, ptype, fieldIndex, fieldValues...); err != nil {
+		return err
+	}
+	return p.record(newPolicyChange(models.PolicyRemoveFiltered, sec, ptype, fieldIndex, fieldValues))
+}
+
+// record logs change in the transaction, and keeps it to apply it in memory after commit
+func (p *PolicyTx) record(change models.PolicyChange) error {
+	change.Instance = p.instance
+	if err := recordPolicyChange(p.tx, &change); err != nil {
+		return err
+	}
+	p.changes = append(p.changes, change)
+	return nil
+}
+
+// apply updates the in-memory policy of the enforcer with the committed changes
+func (p *PolicyTx) apply(ctx context.Context) error {
+	return applyPolicyChanges(ctx, p.enforcer, p.listener, p.changes)
+}
+
+// newPolicyChange returns a change of rules (for PolicyRemoveFiltered, the field values as the only rule)
+func newPolicyChange(operation string, sec string, ptype string, fieldIndex int, rules ...[]string) models.PolicyChange {
+	// Encoding a [][]string cannot fail
+	encoded, _ := json.Marshal(rules)
+	return models.PolicyChange{Operation: operation, Sec: sec, Ptype: ptype, FieldIndex: fieldIndex, Rules: string(encoded)}
+}
+
+// recordPolicyChange bumps the policy revision and logs change with it.
+// The revision row stays locked until db's transaction commits, so revisions are committed in order.
+func recordPolicyChange(db *gorm.DB, change *models.PolicyChange) error {
+	err := db.Table("casbin_policy_revision").Where("id = 1").Update("revision", gorm.Expr("revision + 1")).Error
+	if err != nil {
+		return err
+	}
+	err = db.Table("casbin_policy_revision").Where("id = 1").Pluck("revision", &change.Revision).Error
+	if err != nil {
+		return err
+	}
+	return db.Create(change).Error
+}
+
+// applyPolicyChanges updates the in-memory policy of enforcer, without writing to the database,
+// and notifies listener (optional). It returns errPolicyReload if a change cannot be applied incrementally.
+func applyPolicyChanges(ctx context.Context, enforcer *casbin.SyncedEnforcer, listener PolicyListener, changes []models.PolicyChange) (err error) {
+	if len(changes) == 0 {
+		return nil
+	}
+	_, span := tracing.Start(ctx, "casbin.apply_policy_changes", attribute.Int("casbin.changes", len(changes)))
+	defer func() { tracing.End(span, err) }()
+
+	// Hold the enforcer's write lock, so no other request persists anything while auto save is off
+	lock := enforcer.GetLock()
+	lock.Lock()
+	defer lock.Unlock()
+	enforcer.Enforcer.EnableAutoSave(false)
+	defer enforcer.Enforcer.EnableAutoSave(true)
+
+	for _, change := range changes {
+		if change.Operation == models.PolicyReload {
+			return errPolicyReload
+		}
+
+		var rules [][]string
+		if err = json.Unmarshal([]byte(change.Rules), &rules); err != nil {
+			return err
+		}
+
+		// Rules already (or no longer) in memory are skipped, so applying a change twice is harmless
+		for _, rule := range rules {
+			switch change.Operation {
+			case models.PolicyAdd:
+				_, err = enforcer.Enforcer.SelfAddPolicy(change.Sec, change.Ptype, rule)
+			case models.PolicyRemove:
+				_, err = enforcer.Enforcer.SelfRemovePolicy(change.Sec, change.Ptype, rule)
+			case models.PolicyRemoveFiltered:
+				_, err = enforcer.Enforcer.SelfRemoveFilteredPolicy(change.Sec, change.Ptype, change.FieldIndex, rule...)
+			default:
+				return errPolicyReload
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if listener != nil {
+			listener.PolicyChanged(change, rules)
+		}
+	}
+	return nil
+}
+
+// loadPolicy reloads the whole policy of enforcer from the database
+func loadPolicy(ctx context.Context, enforcer *casbin.SyncedEnforcer) (err error) {
+	_, span := tracing.Start(ctx, "casbin.load_policy")
+	defer func() { tracing.End(span, err) }()
+
+	start := time.Now()
+	if err = enforcer.LoadPolicy(); err != nil {
+		return err
+	}
+	metrics.PolicyReloaded(start)
+	return nil
+}
@@ -0,0 +1,354 @@
+package store
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// newTestEnforcer returns an enforcer of st with its policy loaded, and the watcher keeping it in sync
+func newTestEnforcer(t *testing.T, st *Store) (*casbin.SyncedEnforcer, *PolicyWatcher) {
+	t.Helper()
+	enforcer, err := st.NewEnforcer("../config/rbac_model.conf")
+	if err != nil {
+		t.Fatalf("failed to create the enforcer: %v", err)
+	}
+	watcher, err := st.NewPolicyWatcher(enforcer, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create the policy watcher: %v", err)
+	}
+	return enforcer, watcher
+}
+
+// rulesOf returns the policy and role rules in the memory of enforcer, like "p alice data read", sorted
+func rulesOf(t *testing.T, enforcer *casbin.SyncedEnforcer) []string {
+	t.Helper()
+	policy, err := enforcer.GetPolicy()
+	if err != nil {
+		t.Fatalf("failed to get the policy: %v", err)
+	}
+	grouping, err := enforcer.GetGroupingPolicy()
+	if err != nil {
+		t.Fatalf("failed to get the grouping policy: %v", err)
+	}
+	rules := []string{}
+	for _, rule := range policy {
+		rules = append(rules, "p "+strings.Join(rule, " "))
+	}
+	for _, rule := range grouping {
+		rules = append(rules, "g "+strings.Join(rule, " "))
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+// checkRules checks the policy in the memory of enforcer, and that it is the one in the database
+func checkRules(t *testing.T, st *Store, enforcer *casbin.SyncedEnforcer, want ...string) {
+	t.Helper()
+	sort.Strings(want)
+	if want == nil {
+		want = []string{}
+	}
+	if got := rulesOf(t, enforcer); !reflect.DeepEqual(got, want) {
+		t.Errorf("policy in memory = %q, want %q", got, want)
+	}
+	reloaded, _ := newTestEnforcer(t, st)
+	if got := rulesOf(t, reloaded); !reflect.DeepEqual(got, want) {
+		t.Errorf("policy in the database = %q, want %q", got, want)
+	}
+}
+
+func TestPolicyTx(t *testing.T) {
+	tests := []struct {
+		name string
+		// initial is added in a first unit of work
+		initial func(p *PolicyTx) error
+		change  func(p *PolicyTx) error
+		want    []string
+	}{
+		{
+			name: "add then remove then add",
+			change: func(p *PolicyTx) error {
+				return errors.Join(
+					p.AddPolicy("alice", "data", "read"),
+					p.RemovePolicy("alice", "data", "read"),
+					p.AddPolicy("alice", "data", "read"),
+				)
+			},
+			want: []string{"p alice data read"},
+		},
+		{
+			name: "add twice",
+			change: func(p *PolicyTx) error {
+				return errors.Join(p.AddPolicy("alice", "data", "read"), p.AddPolicy("alice", "data", "read"))
+			},
+			want: []string{"p alice data read"},
+		},
+		{
+			name:    "remove then add back",
+			initial: func(p *PolicyTx) error { return p.AddPolicy("alice", "data", "read") },
+			change: func(p *PolicyTx) error {
+				return errors.Join(p.RemovePolicy("alice", "data", "read"), p.AddPolicy("alice", "data", "read"))
+			},
+			want: []string{"p alice data read"},
+		},
+		{
+			name: "remove filtered then add",
+			initial: func(p *PolicyTx) error {
+				return errors.Join(
+					p.AddPolicy("admin", "data", "read"),
+					p.AddPolicy("admin", "data", "write"),
+					p.AddPolicy("viewer", "data", "read"),
+				)
+			},
+			change: func(p *PolicyTx) error {
+				return errors.Join(p.RemoveFilteredPolicy(0, "admin"), p.AddPolicy("admin", "data", "write"))
+			},
+			want: []string{"p admin data write", "p viewer data read"},
+		},
+		{
+			name: "reassign a role",
+			initial: func(p *PolicyTx) error {
+				return errors.Join(p.AddGroupingPolicy("alice", "viewer"), p.AddGroupingPolicy("bob", "viewer"))
+			},
+			change: func(p *PolicyTx) error {
+				return errors.Join(p.RemoveFilteredGroupingPolicy(0, "alice"), p.AddGroupingPolicy("alice", "admin"))
+			},
+			want: []string{"g alice admin", "g bob viewer"},
+		},
+		{
+			name: "delete user",
+			initial: func(p *PolicyTx) error {
+				return errors.Join(
+					p.AddGroupingPolicy("alice", "viewer"),
+					p.AddPolicy("alice", "data", "read"),
+					p.AddPolicy("bob", "data", "read"),
+				)
+			},
+			change: func(p *PolicyTx) error { return p.DeleteUser("alice") },
+			want:   []string{"p bob data read"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			st := openTestStore(t)
+			enforcer, _ := newTestEnforcer(t, st)
+			ctx := context.Background()
+			if test.initial != nil {
+				if err := st.Atomic(ctx, enforcer, func(uow *UnitOfWork) error { return test.initial(uow.Policies) }); err != nil {
+					t.Fatalf("initial policy failed: %v", err)
+				}
+			}
+			if err := st.Atomic(ctx, enforcer, func(uow *UnitOfWork) error { return test.change(uow.Policies) }); err != nil {
+				t.Fatalf("change failed: %v", err)
+			}
+			checkRules(t, st, enforcer, test.want...)
+		})
+	}
+}
+
+// HasPolicy sees the changes of its unit of work, before they are committed
+func TestPolicyTxHasPolicy(t *testing.T) {
+	st := openTestStore(t)
+	enforcer, _ := newTestEnforcer(t, st)
+
+	err := st.Atomic(context.Background(), enforcer, func(uow *UnitOfWork) error {
+		for _, step := range []struct {
+			change func() error
+			want   bool
+		}{
+			{func() error { return nil }, false},
+			{func() error { return uow.Policies.AddPolicy("alice", "data", "read") }, true},
+			{func() error { return uow.Policies.RemovePolicy("alice", "data", "read") }, false},
+			{func() error { return uow.Policies.AddPolicy("alice", "data", "read") }, true},
+			{func() error { return uow.Policies.RemoveFilteredPolicy(1, "data") }, false},
+		} {
+			if err := step.change(); err != nil {
+				return err
+			}
+			has, err := uow.Policies.HasPolicy("alice", "data", "read")
+			if err != nil {
+				return err
+			}
+			if has != step.want {
+				t.Errorf("HasPolicy = %t, want %t", has, step.want)
+			}
+			// Not in memory until committed
+			if inMemory, _ := enforcer.HasPolicy("alice", "data", "read"); inMemory {
+				t.Errorf("rule in memory before commit")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unit of work failed: %v", err)
+	}
+}
+
+func TestPolicyTxRollback(t *testing.T) {
+	st := openTestStore(t)
+	enforcer, watcher := newTestEnforcer(t, st)
+	ctx := context.Background()
+	if err := st.Atomic(ctx, enforcer, func(uow *UnitOfWork) error {
+		return errors.Join(uow.Policies.AddPolicy("admin", "data", "read"), uow.Policies.AddGroupingPolicy("alice", "admin"))
+	}); err != nil {
+		t.Fatalf("initial policy failed: %v", err)
+	}
+	revision, err := watcher.currentRevision(ctx)
+	if err != nil {
+		t.Fatalf("failed to read the revision: %v", err)
+	}
+
+	failure := errors.New("failure")
+	err = st.Atomic(ctx, enforcer, func(uow *UnitOfWork) error {
+		if err := uow.Policies.AddPolicy("bob", "data", "write"); err != nil {
+			return err
+		}
+		if err := uow.Policies.DeleteUser("alice"); err != nil {
+			return err
+		}
+		if err := uow.Policies.RemoveFilteredPolicy(0, "admin"); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Atomic error = %v, want %v", err, failure)
+	}
+
+	checkRules(t, st, enforcer, "p admin data read", "g alice admin")
+	if after, _ := watcher.currentRevision(ctx); after != revision {
+		t.Errorf("revision = %d after rollback, want %d", after, revision)
+	}
+	var logged int64
+	st.DB().Model(&models.PolicyChange{}).Where("revision > ?", revision).Count(&logged)
+	if logged != 0 {
+		t.Errorf("%d changes logged after rollback, want none", logged)
+	}
+}
+
+// policyEvents records the notifications of a PolicyListener
+type policyEvents struct {
+	changes  []string
+	reloaded int
+}
+
+func (e *policyEvents) PolicyChanged(change models.PolicyChange, rules [][]string) {
+	e.changes = append(e.changes, change.Operation)
+}
+
+func (e *policyEvents) PolicyReloaded() {
+	e.reloaded++
+}
+
+// Two instances share the database: each watcher applies the changes made by the other
+func TestPolicyWatcherSync(t *testing.T) {
+	first := openTestStore(t)
+	second := New(first.DB())
+	events := &policyEvents{}
+	second.OnPolicyChange(events)
+	ctx := context.Background()
+
+	firstEnforcer, firstWatcher := newTestEnforcer(t, first)
+	secondEnforcer, secondWatcher := newTestEnforcer(t, second)
+	events.reloaded = 0
+
+	steps := []struct {
+		name   string
+		store  *Store
+		change func(p *PolicyTx) error
+		want   []string
+	}{
+		{
+			name:  "add on the first",
+			store: first,
+			change: func(p *PolicyTx) error {
+				return errors.Join(p.AddPolicy("admin", "data", "read"), p.AddGroupingPolicy("alice", "admin"))
+			},
+			want: []string{"g alice admin", "p admin data read"},
+		},
+		{
+			name:  "reassign on the second",
+			store: second,
+			change: func(p *PolicyTx) error {
+				return errors.Join(p.RemoveFilteredGroupingPolicy(0, "alice"), p.AddGroupingPolicy("alice", "viewer"))
+			},
+			want: []string{"g alice viewer", "p admin data read"},
+		},
+		{
+			name:   "remove filtered on the first",
+			store:  first,
+			change: func(p *PolicyTx) error { return p.RemoveFilteredPolicy(0, "admin") },
+			want:   []string{"g alice viewer"},
+		},
+	}
+	for _, step := range steps {
+		enforcer := firstEnforcer
+		if step.store == second {
+			enforcer = secondEnforcer
+		}
+		if err := step.store.Atomic(ctx, enforcer, func(uow *UnitOfWork) error { return step.change(uow.Policies) }); err != nil {
+			t.Fatalf("%s: change failed: %v", step.name, err)
+		}
+		for _, watcher := range []*PolicyWatcher{firstWatcher, secondWatcher} {
+			if err := watcher.Sync(ctx); err != nil {
+				t.Fatalf("%s: sync failed: %v", step.name, err)
+			}
+		}
+		if got := rulesOf(t, firstEnforcer); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: policy of the first instance = %q, want %q", step.name, got, step.want)
+		}
+		if got := rulesOf(t, secondEnforcer); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: policy of the second instance = %q, want %q", step.name, got, step.want)
+		}
+	}
+
+	// Every change is applied once on the second instance: its own after commit, the others' by the watcher
+	want := []string{models.PolicyAdd, models.PolicyAdd, models.PolicyRemoveFiltered, models.PolicyAdd, models.PolicyRemoveFiltered}
+	if !reflect.DeepEqual(events.changes, want) || events.reloaded != 0 {
+		t.Errorf("second instance notified of %q and %d reloads, want %q and none", events.changes, events.reloaded, want)
+	}
+	if err := secondWatcher.Check(ctx); err != nil {
+		t.Errorf("Check failed after sync: %v", err)
+	}
+}
+
+// An instance behind the pruned changes reloads the whole policy
+func TestPolicyWatcherReloadsPrunedChanges(t *testing.T) {
+	first := openTestStore(t)
+	second := New(first.DB())
+	events := &policyEvents{}
+	second.OnPolicyChange(events)
+	ctx := context.Background()
+
+	firstEnforcer, _ := newTestEnforcer(t, first)
+	secondEnforcer, secondWatcher := newTestEnforcer(t, second)
+	events.reloaded = 0
+
+	if err := first.Atomic(ctx, firstEnforcer, func(uow *UnitOfWork) error {
+		return errors.Join(uow.Policies.AddPolicy("admin", "data", "read"), uow.Policies.AddGroupingPolicy("alice", "admin"))
+	}); err != nil {
+		t.Fatalf("change failed: %v", err)
+	}
+	if err := first.DB().Where("revision = 1").Delete(&models.PolicyChange{}).Error; err != nil {
+		t.Fatalf("failed to prune the change log: %v", err)
+	}
+
+	if err := secondWatcher.Sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	want := []string{"g alice admin", "p admin data read"}
+	if got := rulesOf(t, secondEnforcer); !reflect.DeepEqual(got, want) {
+		t.Errorf("policy of the second instance = %q, want %q", got, want)
+	}
+	if len(events.changes) != 0 || events.reloaded != 1 {
+		t.Errorf("second instance notified of %q and %d reloads, want a reload only", events.changes, events.reloaded)
+	}
+}
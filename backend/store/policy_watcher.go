@@ -0,0 +1,181 @@
+package store
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"gorm.io/gorm"
+)
+
+// Policy change log settings
+const (
+	// policyChangeRetention is how long changes are kept in the log. Instances further behind reload the whole policy
+	policyChangeRetention = 24 * time.Hour
+	policyPruneInterval   = time.Hour
+)
+
+// PolicyWatcher keeps the enforcers of all running instances in sync through the database.
+// Every policy change bumps the revision counter in "casbin_policy_revision" and is logged in "casbin_policy_changes",
+// in the transaction of the UnitOfWork making it (the policy is never changed directly on the enforcer).
+// Each instance polls the revision, and applies the changes made by the other instances incrementally.
+type PolicyWatcher struct {
+	db       *gorm.DB
+	enforcer *casbin.SyncedEnforcer
+	instance string
+	listener PolicyListener
+	interval time.Duration
+
+	mu sync.Mutex
+	// revision is the last revision applied to the enforcer
+	revision int64
+	// outcome of the last sync (or load)
+	lastSync    time.Time
+	lastSyncErr error
+}
+
+// NewPolicyWatcher loads the current policy into enforcer, and returns a watcher keeping it in sync,
+// polling the policy revision every interval
+func (s *Store) NewPolicyWatcher(enforcer *casbin.SyncedEnforcer, interval time.Duration) (*PolicyWatcher, error) {
+	w := &PolicyWatcher{
+		db:       s.db,
+		enforcer: enforcer,
+		instance: s.instance,
+		listener: s.policyListener,
+		interval: interval,
+	}
+	if err := w.reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Run polls the policy revision until ctx is cancelled
+func (w *PolicyWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := w.Sync(ctx); err != nil {
+			slog.ErrorContext(ctx, "policy sync failed", slog.Any("error", err))
+		}
+
+		if time.Since(lastPrune) > policyPruneInterval {
+			err := w.db.WithContext(ctx).Where("created_at < ?", time.Now().Add(-policyChangeRetention)).Delete(&models.PolicyChange{}).Error
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to prune the policy change log", slog.Any("error", err))
+			}
+			lastPrune = time.Now()
+		}
+	}
+}
+
+// Sync applies the changes made by the other instances since the last sync
+func (w *PolicyWatcher) Sync(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.syncLocked(ctx)
+	w.lastSync, w.lastSyncErr = time.Now(), err
+	return err
+}
+
+// Check reports whether the policy is loaded and in sync: the last sync must have succeeded, recently
+func (w *PolicyWatcher) Check(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.lastSyncErr != nil {
+		return fmt.Errorf("last policy sync failed: %w", w.lastSyncErr)
+	}
+	// A few missed polls are tolerated
+	if stale := time.Since(w.lastSync); stale > 5*w.interval {
+		return fmt.Errorf("policy not synced for %s", stale.Round(time.Second))
+	}
+	return nil
+}
+
+func (w *PolicyWatcher) syncLocked(ctx context.Context) error {
+	current, err := w.currentRevision(ctx)
+	if err != nil {
+		return err
+	}
+	if current == w.revision {
+		return nil
+	}
+
+	var changes []models.PolicyChange
+	err = w.db.WithContext(ctx).
+		Where("revision > ? AND revision <= ?", w.revision, current).
+		Order("revision").
+		Find(&changes).Error
+	if err != nil {
+		return err
+	}
+	// Some changes have already been pruned
+	if int64(len(changes)) != current-w.revision {
+		return w.reloadLocked(ctx)
+	}
+
+	var foreign []models.PolicyChange
+	for _, change := range changes {
+		// Changes of this instance are already applied
+		if change.Instance != w.instance {
+			foreign = append(foreign, change)
+		}
+	}
+	err = applyPolicyChanges(ctx, w.enforcer, w.listener, foreign)
+	if err != nil {
+		if !errors.Is(err, errPolicyReload) {
+			slog.WarnContext(ctx, "failed to apply policy changes, reloading the policy", slog.Any("error", err))
+		}
+		return w.reloadLocked(ctx)
+	}
+
+	w.revision = current
+	return nil
+}
+
+func (w *PolicyWatcher) reload(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.reloadLocked(ctx)
+	w.lastSync, w.lastSyncErr = time.Now(), err
+	return err
+}
+
+// reloadLocked loads the whole policy. The revision is read first,
+// so changes committed during the load are applied again by the next sync (which is harmless)
+func (w *PolicyWatcher) reloadLocked(ctx context.Context) error {
+	current, err := w.currentRevision(ctx)
+	if err != nil {
+		return err
+	}
+	if err = loadPolicy(ctx, w.enforcer); err != nil {
+		return err
+	}
+	if w.listener != nil {
+		w.listener.PolicyReloaded()
+	}
+	w.revision = current
+	return nil
+}
+
+func (w *PolicyWatcher) currentRevision(ctx context.Context) (int64, error) {
+	var revision int64
+	err := w.db.WithContext(ctx).Table("casbin_policy_revision").Where("id = 1").Pluck("revision", &revision).Error
+	return revision, err
+}
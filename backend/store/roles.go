@@ -0,0 +1,50 @@
+package store
+
+import (
+	"backend/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// RoleStore gives access to "roles"
+type RoleStore interface {
+	List(ctx context.Context) ([]models.Role, error)
+	// Exists reports whether role is in "roles"
+	Exists(ctx context.Context, role string) (bool, error)
+	// Save adds a new role or updates the description of an existing one
+	Save(ctx context.Context, role *models.Role) error
+	Delete(ctx context.Context, role *models.Role) error
+	// CountAssignments returns the number of "casbin_rule" rows that refer to role
+	CountAssignments(ctx context.Context, role string) (int64, error)
+}
+
+type roleStore struct {
+	db *gorm.DB
+}
+
+func (s *roleStore) List(ctx context.Context) ([]models.Role, error) {
+	var roles []models.Role
+	err := s.db.WithContext(ctx).Find(&roles).Error
+	return roles, err
+}
+
+func (s *roleStore) Exists(ctx context.Context, role string) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Role{}).Where(&models.Role{Role: role}).Count(&count).Error
+	return count > 0, err
+}
+
+func (s *roleStore) Save(ctx context.Context, role *models.Role) error {
+	return s.db.WithContext(ctx).Save(role).Error
+}
+
+func (s *roleStore) Delete(ctx context.Context, role *models.Role) error {
+	return s.db.WithContext(ctx).Delete(role).Error
+}
+
+func (s *roleStore) CountAssignments(ctx context.Context, role string) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.CasbinRule{}).Where(&models.CasbinRule{V1: role}).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"backend/models"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// containsKeyword is a scope matching the rows where any of columns contains keyword, ignoring case.
+// Columns are compared as text, so numeric ids can be searched too, on every supported database.
+func containsKeyword(keyword string, columns ...string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		textType := "TEXT"
+		if db.Dialector.Name() == "mysql" {
+			textType = "CHAR"
+		}
+
+		conditions := make([]string, len(columns))
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			conditions[i] = "LOWER(CAST(" + column + " AS " + textType + ")) LIKE ?"
+			values[i] = "%" + strings.ToLower(keyword) + "%"
+		}
+		return db.Where("("+strings.Join(conditions, " OR ")+")", values...)
+	}
+}
+
+// InvalidSortError is returned when a list is sorted by a column it cannot be sorted by
+type InvalidSortError struct {
+	Column   string
+	Sortable []string
+}
+
+func (e *InvalidSortError) Error() string {
+	return fmt.Sprintf("cannot sort by %q, sortable columns are %s", e.Column, strings.Join(e.Sortable, ", "))
+}
+
+// sortColumns are the columns a list can be sorted by: the name given in the request, and the column in the query.
+// The list is always sorted last by key, so that the pages are stable.
+type sortColumns struct {
+	columns map[string]string
+	key     string
+	// used when no sort is requested
+	defaultSort string
+}
+
+func (s sortColumns) names() []string {
+	names := make([]string, 0, len(s.columns))
+	for name := range s.columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// orderBy parses the sort of the request, like "-full_name,id"
+func (s sortColumns) orderBy(requested string) (clause.OrderBy, error) {
+	if strings.TrimSpace(requested) == "" {
+		requested = s.defaultSort
+	}
+
+	var orderBy clause.OrderBy
+	sortedByKey := false
+	for _, name := range strings.Split(requested, ",") {
+		name = strings.TrimSpace(name)
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		if name == "" {
+			continue
+		}
+		column, ok := s.columns[name]
+		if !ok {
+			return orderBy, &InvalidSortError{Column: name, Sortable: s.names()}
+		}
+		sortedByKey = sortedByKey || column == s.key
+		orderBy.Columns = append(orderBy.Columns, clause.OrderByColumn{Column: clause.Column{Name: column, Raw: true}, Desc: desc})
+	}
+	if !sortedByKey {
+		orderBy.Columns = append(orderBy.Columns, clause.OrderByColumn{Column: clause.Column{Name: s.key, Raw: true}})
+	}
+	return orderBy, nil
+}
+
+// findPage counts the rows of query matching the expression of filter (parsed against filterable),
+// and finds the page of filter in dest, sorted by the sort of filter
+func findPage(query *gorm.DB, filter models.Filter, sortable sortColumns, filterable FilterFields, dest interface{}) (int64, error) {
+	orderBy, err := sortable.orderBy(filter.Sort)
+	if err != nil {
+		return 0, err
+	}
+	matches, err := matchesFilter(filter.Expression, filterable)
+	if err != nil {
+		return 0, err
+	}
+	query = query.Scopes(matches)
+
+	var total int64
+	if err = query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	err = query.Clauses(orderBy).Limit(filter.Limit()).Offset(filter.Offset()).Find(dest).Error
+	return total, err
+}
+
+// rename sets full_name of the row of model with id, failing with ErrNotFound if there is none
+// (unlike Save, which would insert it)
+func rename(db *gorm.DB, model interface{}, id int, fullName string) error {
+	result := db.Model(model).Where("id = ?", id).Update("full_name", fullName)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	// MySQL only counts the rows changed, so renaming to the same name affects none
+	var count int64
+	if err := db.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	sqlitedriver "github.com/glebarez/go-sqlite"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// Extended result codes of the constraint violations of SQLite (https://www.sqlite.org/rescode.html)
+const (
+	sqliteConstraintForeignKey = 787
+	sqliteConstraintPrimaryKey = 1555
+	sqliteConstraintUnique     = 2067
+)
+
+// sqliteDialector adds to the SQLite dialector the translation of its errors into the errors of gorm,
+// like the mysql and postgres dialectors do
+type sqliteDialector struct {
+	*sqlite.Dialector
+}
+
+// Translate returns gorm.ErrDuplicatedKey and gorm.ErrForeignKeyViolated for the violations of these constraints
+func (d sqliteDialector) Translate(err error) error {
+	sqliteErr, ok := err.(*sqlitedriver.Error)
+	if !ok {
+		return err
+	}
+	switch sqliteErr.Code() {
+	case sqliteConstraintPrimaryKey, sqliteConstraintUnique:
+		return gorm.ErrDuplicatedKey
+	case sqliteConstraintForeignKey:
+		return gorm.ErrForeignKeyViolated
+	}
+	return err
+}
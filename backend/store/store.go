@@ -0,0 +1,143 @@
+package store
+
+import (
+	"backend/config"
+	"backend/tracing"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// ErrNotFound is returned when the record looked up does not exist
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// Store holds the single pooled database connection of the backend
+// and the typed repositories built on top of it
+type Store struct {
+	db *gorm.DB
+
+	Customers   CustomerStore
+	Employees   EmployeeStore
+	Users       UserStore
+	Roles       RoleStore
+	Permissions PermissionStore
+	Outbox      OutboxStore
+
+	// called after a unit of work that queued outbox operations has been committed
+	outboxNotify func()
+	// identifies this process in the policy change log
+	instance string
+	// notified of every change to the in-memory policy
+	policyListener PolicyListener
+}
+
+// Open connects to the RBAC database with the configured driver and configures the connection pool.
+// It should be called once at startup, and the returned Store shared by all handlers.
+// Queries are logged through logger.
+func Open(cfg config.DatabaseConfig, logger gormlogger.Interface) (*Store, error) {
+	dialector, err := dialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger, TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if err = sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	return New(db), nil
+}
+
+// dialector returns the gorm dialector of the configured driver
+func dialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "mysql":
+		return mysql.Open(cfg.DSN()), nil
+	case "postgres":
+		return postgres.Open(cfg.DSN()), nil
+	case "sqlite":
+		return sqliteDialector{&sqlite.Dialector{DSN: cfg.DSN()}}, nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q (expected mysql, postgres or sqlite)", cfg.Driver)
+	}
+}
+
+// New wraps an existing gorm connection
+func New(db *gorm.DB) *Store {
+	return &Store{
+		db:          db,
+		Customers:   &customerStore{db: db},
+		Employees:   &employeeStore{db: db},
+		Users:       &userStore{db: db},
+		Roles:       &roleStore{db: db},
+		Permissions: &permissionStore{db: db},
+		Outbox:      &outboxStore{db: db},
+		instance:    newInstanceID(),
+	}
+}
+
+// OnOutboxEnqueued registers fn to be called every time a unit of work that queued outbox operations is committed
+// (e.g. to wake up the outbox worker)
+func (s *Store) OnOutboxEnqueued(fn func()) {
+	s.outboxNotify = fn
+}
+
+// OnPolicyChange registers listener to be notified of every change to the in-memory policy of the enforcer
+// (e.g. to invalidate cached decisions). It must be called before NewPolicyWatcher.
+func (s *Store) OnPolicyChange(listener PolicyListener) {
+	s.policyListener = listener
+}
+
+// DB returns the underlying gorm connection (e.g. for the casbin adapter and migrations)
+func (s *Store) DB() *gorm.DB {
+	return s.db
+}
+
+// Ping checks that the database is reachable
+func (s *Store) Ping(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close closes the connection pool
+func (s *Store) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// newInstanceID returns a random id for this process
+func newInstanceID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,29 @@
+package store
+
+import (
+	"backend/config"
+	"backend/database/migrate"
+	"path/filepath"
+	"testing"
+
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// openTestStore opens a migrated sqlite database, in a file removed at the end of the test
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	st, err := Open(config.DatabaseConfig{
+		Driver:       "sqlite",
+		Path:         filepath.Join(t.TempDir(), "test.db"),
+		MaxOpenConns: 1,
+		MaxIdleConns: 1,
+	}, gormlogger.Discard)
+	if err != nil {
+		t.Fatalf("failed to open the database: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	if _, err = migrate.Up(st.DB()); err != nil {
+		t.Fatalf("failed to migrate the database: %v", err)
+	}
+	return st
+}
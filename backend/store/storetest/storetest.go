@@ -0,0 +1,31 @@
+// Package storetest opens throwaway databases for the tests of the packages built on the store
+package storetest
+
+import (
+	"backend/config"
+	"backend/database/migrate"
+	"backend/store"
+	"path/filepath"
+	"testing"
+
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Open returns a store on a migrated sqlite database, in a file removed at the end of the test
+func Open(t testing.TB) *store.Store {
+	t.Helper()
+	st, err := store.Open(config.DatabaseConfig{
+		Driver:       "sqlite",
+		Path:         filepath.Join(t.TempDir(), "test.db"),
+		MaxOpenConns: 1,
+		MaxIdleConns: 1,
+	}, gormlogger.Discard)
+	if err != nil {
+		t.Fatalf("failed to open the database: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	if _, err = migrate.Up(st.DB()); err != nil {
+		t.Fatalf("failed to migrate the database: %v", err)
+	}
+	return st
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"backend/tracing"
+	"context"
+	"log/slog"
+
+	"github.com/casbin/casbin/v2"
+	"gorm.io/gorm"
+)
+
+// UnitOfWork is a set of database and casbin changes that commit (or roll back) together.
+// Changes to external systems, like deleting a Firebase user, are queued in the Outbox within the same transaction
+// and executed by the outbox worker once the transaction has been committed.
+type UnitOfWork struct {
+	Customers CustomerStore
+	Employees EmployeeStore
+	Users     UserStore
+	Roles     RoleStore
+	Outbox    OutboxStore
+	Policies  *PolicyTx
+}
+
+// Atomic runs fn in a unit of work. If fn returns an error, every database and casbin change is rolled back
+// and no outbox operation is queued. Otherwise the changes are committed, applied to the enforcer,
+// and the outbox worker is woken up if operations were queued.
+// enforcer may be nil when fn does not change any policy.
+func (s *Store) Atomic(ctx context.Context, enforcer *casbin.SyncedEnforcer, fn func(uow *UnitOfWork) error) (err error) {
+	ctx, span := tracing.Start(ctx, "store.atomic")
+	defer func() { tracing.End(span, err) }()
+
+	var uow *UnitOfWork
+	var outbox *outboxStore
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		policies, err := newPolicyTx(tx, enforcer, s.instance, s.policyListener)
+		if err != nil {
+			return err
+		}
+		outbox = &outboxStore{db: tx}
+		uow = &UnitOfWork{
+			Customers: &customerStore{db: tx},
+			Employees: &employeeStore{db: tx},
+			Users:     &userStore{db: tx},
+			Roles:     &roleStore{db: tx},
+			Outbox:    outbox,
+			Policies:  policies,
+		}
+		return fn(uow)
+	})
+	if err != nil {
+		return err
+	}
+
+	if outbox.enqueued && s.outboxNotify != nil {
+		s.outboxNotify()
+	}
+
+	// The database is the source of truth: if memory cannot be updated incrementally, reload it
+	if err = uow.Policies.apply(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to apply policy changes, reloading the policy", slog.Any("error", err))
+		if err = loadPolicy(ctx, enforcer); err != nil {
+			return err
+		}
+		if s.policyListener != nil {
+			s.policyListener.PolicyReloaded()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"backend/models"
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// UserStore gives access to the (firebase) users kept in "users"
+type UserStore interface {
+	// List returns id and email of the page of users whose id or email matches the keyword of filter, and their total
+	List(ctx context.Context, filter models.Filter) ([]models.User, int64, error)
+	// ListWithRoles returns the page of users assigned to an employee, along with their role, and their total
+	ListWithRoles(ctx context.Context, filter models.Filter) ([]models.FrontendUser, int64, error)
+	// UnassignedEmails returns the emails of users not associated with any employee or customer
+	UnassignedEmails(ctx context.Context) ([]string, error)
+	// CustomerEmails returns the emails of users that can be associated with a customer
+	CustomerEmails(ctx context.Context) ([]string, error)
+
+	Exists(ctx context.Context, id string) (bool, error)
+	// FindByEmail returns the user of email, compared case-insensitively like Firebase does
+	// (emails are kept in lower case, so the lookup uses idx_users_email)
+	FindByEmail(ctx context.Context, email string) (models.User, error)
+	// Create inserts user, with its email in lower case
+	Create(ctx context.Context, user *models.User) error
+	// Upsert inserts or updates user (with its email in lower case), keeping its employee association untouched
+	Upsert(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, users ...models.User) error
+
+	// Customers returns all customers associated with user id
+	Customers(ctx context.Context, userId string) ([]models.Customer, error)
+	CountCustomers(ctx context.Context, user *models.User) int64
+	// ClearCustomers deletes all associations with user from "customer_user"
+	ClearCustomers(ctx context.Context, user *models.User) error
+}
+
+type userStore struct {
+	db *gorm.DB
+}
+
+// UserFilters are the fields of the filter of the users lists
+var UserFilters = FilterFields{
+	{Name: "id", Type: FilterString, Description: "firebase uid of the user", condition: equals("users.id")},
+	{Name: "email", Type: FilterString, Description: "exact email of the user", condition: func(value interface{}) (string, []interface{}) {
+		return equals("users.email")(normalizeEmail(value.(string)))
+	}},
+	{Name: "email_domain", Type: FilterString, Description: "users whose email is in this domain, like example.com", condition: emailDomain("users.email")},
+	{Name: "role", Type: FilterString, Description: "users with this role", condition: func(value interface{}) (string, []interface{}) {
+		return "users.id IN (SELECT v0 FROM casbin_rule WHERE ptype = 'g' AND v1 = ?)", []interface{}{value}
+	}},
+	{Name: "has_employee", Type: FilterBool, Description: "users assigned to an employee", condition: isNull("users.employee_id")},
+	{Name: "employee_id", Type: FilterInt, Description: "users assigned to this employee", condition: equals("users.employee_id")},
+	{Name: "customer_id", Type: FilterInt, Description: "users associated with this customer", condition: func(value interface{}) (string, []interface{}) {
+		return "users.id IN (SELECT user_id FROM customer_user WHERE customer_id = ?)", []interface{}{value}
+	}},
+	{Name: "created_after", Type: FilterTime, Description: "users created in firebase after this time", condition: compareMillis("users.creation_timestamp", ">")},
+	{Name: "created_before", Type: FilterTime, Description: "users created in firebase before this time", condition: compareMillis("users.creation_timestamp", "<")},
+	{Name: "last_login_after", Type: FilterTime, Description: "users who last logged in after this time", condition: compareMillis("users.last_login_timestamp", ">")},
+	{Name: "last_login_before", Type: FilterTime, Description: "users who last logged in before this time", condition: compareMillis("users.last_login_timestamp", "<")},
+}
+
+var userSortColumns = sortColumns{
+	columns: map[string]string{"id": "id", "email": "email"},
+	key:     "id",
+}
+
+func (s *userStore) List(ctx context.Context, filter models.Filter) ([]models.User, int64, error) {
+	var users []models.User
+	query := s.db.WithContext(ctx).Model(&models.User{}).
+		Select("id", "email").
+		Scopes(containsKeyword(filter.Keyword, "id", "email"))
+	total, err := findPage(query, filter, userSortColumns, UserFilters, &users)
+	return users, total, err
+}
+
+var userWithRoleSortColumns = sortColumns{
+	columns: map[string]string{"id": "users.id", "full_name": "employees.full_name", "email": "users.email", "role": "casbin_rule.v1"},
+	key:     "users.id",
+}
+
+func (s *userStore) ListWithRoles(ctx context.Context, filter models.Filter) ([]models.FrontendUser, int64, error) {
+	var users []models.FrontendUser
+	// Get all employees and their roles (excluding those who haven't been assigned a firebase user yet)
+	query := s.db.WithContext(ctx).Table("employees").Joins("JOIN users ON users.employee_id = employees.id").
+		Joins("LEFT JOIN casbin_rule ON casbin_rule.v0 = users.id").
+		Select("users.id, employees.full_name, users.email, casbin_rule.v1 AS role").
+		Scopes(containsKeyword(filter.Keyword, "users.id", "employees.full_name", "users.email", "casbin_rule.v1"))
+	total, err := findPage(query, filter, userWithRoleSortColumns, UserFilters, &users)
+	return users, total, err
+}
+
+func (s *userStore) UnassignedEmails(ctx context.Context) ([]string, error) {
+	var emails []string
+	err := s.db.WithContext(ctx).
+		Table("users").
+		Where("employee_id IS NULL AND users.id NOT IN ( SELECT user_id FROM customer_user )").
+		Select("email").
+		Scan(&emails).Error
+	return emails, err
+}
+
+func (s *userStore) CustomerEmails(ctx context.Context) ([]string, error) {
+	var emails []string
+	err := s.db.WithContext(ctx).
+		Table("users").
+		Where("users.employee_id IS NULL AND users.email NOT LIKE '%@digitalminds.com%'").
+		Select("email").
+		Scan(&emails).Error
+	return emails, err
+}
+
+func (s *userStore) Exists(ctx context.Context, id string) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.User{}).Where(&models.User{Id: id}).Count(&count).Error
+	return count > 0, err
+}
+
+func (s *userStore) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).Where("email = ?", normalizeEmail(email)).First(&user).Error
+	return user, err
+}
+
+func (s *userStore) Create(ctx context.Context, user *models.User) error {
+	user.Email = normalizeEmail(user.Email)
+	return s.db.WithContext(ctx).Create(user).Error
+}
+
+func (s *userStore) Upsert(ctx context.Context, user *models.User) error {
+	user.Email = normalizeEmail(user.Email)
+	return s.db.WithContext(ctx).Omit("EmployeeID").Save(user).Error
+}
+
+func (s *userStore) Delete(ctx context.Context, users ...models.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Delete(&users).Error
+}
+
+func (s *userStore) Customers(ctx context.Context, userId string) ([]models.Customer, error) {
+	var customers []models.Customer
+	err := s.db.WithContext(ctx).Joins("JOIN customer_user ON customers.id = customer_user.customer_id").
+		Where("customer_user.user_id = ?", userId).
+		Find(&customers).Error
+	return customers, err
+}
+
+func (s *userStore) CountCustomers(ctx context.Context, user *models.User) int64 {
+	return s.db.WithContext(ctx).Model(user).Association("Customers").Count()
+}
+
+func (s *userStore) ClearCustomers(ctx context.Context, user *models.User) error {
+	return s.db.WithContext(ctx).Model(user).Association("Customers").Clear()
+}
+
+// normalizeEmail is how emails are kept in "users": in lower case, as Firebase compares them case-insensitively
+func normalizeEmail(email string) string {
+	return strings.ToLower(email)
+}
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"backend/logging"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a span for each request, named after its route, continuing the caller's trace (W3C traceparent header).
+// The trace ID is added to the request's log lines.
+func Middleware(c *gin.Context) {
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	ctx, span := otel.Tracer("backend").Start(ctx, c.Request.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethod(c.Request.Method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(c.Request.URL.Path),
+			semconv.ClientAddress(c.ClientIP()),
+			attribute.String("http.request_id", c.Writer.Header().Get(logging.RequestIDHeader)),
+		),
+	)
+	defer span.End()
+
+	if span.SpanContext().IsValid() {
+		ctx = logging.With(ctx, slog.String("trace_id", span.SpanContext().TraceID().String()))
+	}
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+
+	status := c.Writer.Status()
+	span.SetAttributes(semconv.HTTPStatusCode(status))
+	if uid, ok := c.Get("UUID"); ok {
+		span.SetAttributes(attribute.String("enduser.id", fmt.Sprint(uid)))
+	}
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	for _, err := range c.Errors {
+		span.RecordError(err.Err)
+	}
+}
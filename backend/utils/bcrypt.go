@@ -9,10 +9,15 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(pass *string) {
+// HashPassword replaces pass with its bcrypt hash. It fails for passwords longer than 72 bytes
+func HashPassword(pass *string) error {
 	bytePass := []byte(*pass)
-	hPass, _ := bcrypt.GenerateFromPassword(bytePass, bcrypt.DefaultCost)
+	hPass, err := bcrypt.GenerateFromPassword(bytePass, bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
 	*pass = string(hPass)
+	return nil
 }
 
 func ComparePassword(dbPass, pass string) bool {
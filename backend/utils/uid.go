@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+const uidAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// NewUID generates a random 28 character uid, like the ones firebase generates
+func NewUID() (string, error) {
+	uid := make([]byte, 28)
+	max := big.NewInt(int64(len(uidAlphabet)))
+	for i := range uid {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		uid[i] = uidAlphabet[n.Int64()]
+	}
+	return string(uid), nil
+}
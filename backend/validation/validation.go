@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-playground/validator/v10/non-standard/validators"
+)
+
+// Custom rules, used in the `binding` tags of the request structs next to the built-in ones (required, email, gt=0, ...):
+//
+//	notblank  the string is not empty or only spaces
+//	resource  the string is a casbin resource name, like "rbac::data" or "portal::data::1996::finance"
+//
+// Rules only check the request itself: whether the role or customer it names exists is checked by the services,
+// where a database error is not mistaken for an invalid field
+const (
+	RuleNotBlank = "notblank"
+	RuleResource = "resource"
+)
+
+var resourcePattern = regexp.MustCompile(`^[a-z0-9_-]+(::[a-zA-Z0-9_*-]+)*$`)
+
+// FieldError is the error of one field of an invalid request
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Register adds the custom rules to gin's validator (used by c.ShouldBind*),
+// and names the fields after their json (or uri, or form) tag in the errors
+func Register() error {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return fmt.Errorf("unexpected validator engine %T", binding.Validator.Engine())
+	}
+
+	engine.RegisterTagNameFunc(fieldName)
+
+	rules := map[string]validator.Func{
+		RuleNotBlank: validators.NotBlank,
+		RuleResource: func(fl validator.FieldLevel) bool {
+			return resourcePattern.MatchString(fl.Field().String())
+		},
+	}
+	for tag, rule := range rules {
+		if err := engine.RegisterValidation(tag, rule); err != nil {
+			return fmt.Errorf("failed to register validation rule %q: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// Fields returns the errors of each field, with a user-facing message
+func Fields(errs validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, FieldError{Field: fe.Field(), Rule: fe.Tag(), Param: fe.Param(), Message: message(fe)})
+	}
+	return fields
+}
+
+// Summary joins the messages of fields, prefixed by their field
+func Summary(fields []FieldError) string {
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, field.Field+" "+field.Message)
+	}
+	return strings.Join(messages, ", ")
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case RuleNotBlank:
+		return "must not be blank"
+	case "email":
+		return "must be a valid email"
+	case RuleResource:
+		return `must be a resource name like "portal::data::1996::finance"`
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "lte":
+		return "must be at most " + fe.Param()
+	case "min":
+		if fe.Kind() == reflect.String {
+			return "must be at least " + fe.Param() + " characters long"
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if fe.Kind() == reflect.String {
+			return "must be at most " + fe.Param() + " characters long"
+		}
+		return "must be at most " + fe.Param()
+	default:
+		return "is invalid (" + fe.Tag() + ")"
+	}
+}
+
+// fieldName is the name of the field in the request: its json, uri or form tag
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "uri", "form"} {
+		name := strings.SplitN(field.Tag.Get(key), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -3,9 +3,13 @@ import axios from 'axios';
 import { notification } from 'antd';
 const axiosApiInstance = axios.create();
 
+// Error envelope of the backend (see backend/apierror)
 type ApiError = {
+    code?: string;
     message?: string;
     type?: string;
+    details?: unknown;
+    request_id?: string;
 };
 
 // Request interceptor for API calls
@@ -0,0 +1,24 @@
+import type { SortOrder } from 'antd/es/table/interface';
+
+// Page of a list endpoint of the backend (see backend/models/filter.go)
+export type Page<T> = {
+    items: T[];
+    total: number;
+    page: number;
+    page_size: number;
+};
+
+// Query parameters of a list endpoint, from the params and the sort of a ProTable request
+export const pageParams = (
+    params: { keyword?: string; current?: number; pageSize?: number },
+    sort: Record<string, SortOrder>
+) => ({
+    keyword: params.keyword,
+    page: params.current,
+    page_size: params.pageSize,
+    // e.g. "-full_name" for descending full names
+    sort: Object.entries(sort)
+        .filter(([, order]) => order)
+        .map(([field, order]) => (order === 'descend' ? '-' : '') + field)
+        .join(',') || undefined
+});
@@ -7,6 +7,7 @@ import CustomerUserRelationship from "./CustomerUserRelationship";
 import {PageContainer} from "@ant-design/pro-components";
 import {GlobalStateContext} from "../context/GlobalContext";
 import axiosApiInstance from "../api/axiosClient";
+import {Page, pageParams} from "../api/page";
 
 type Props = {};
 
@@ -73,8 +74,8 @@ const Customers = (props: Props) => {
     }
 
     const columns: ProColumns <Customer>[] = [
-        {title: 'Id (Podio  Id)', dataIndex: 'id', align: "center", width: '25%', editable: () => editable},
-        {title: 'Full Name', dataIndex: 'full_name', align: "center", width: '50%'},
+        {title: 'Id (Podio  Id)', dataIndex: 'id', align: "center", sorter: true, width: '25%', editable: () => editable},
+        {title: 'Full Name', dataIndex: 'full_name', align: "center", sorter: true, width: '50%'},
 
         {
             title: 'Action',
@@ -105,12 +106,10 @@ const Customers = (props: Props) => {
                 <EditableProTable<Customer>
                     request={async (params, sort, filter) => {
                         try {
-                            const res = await axiosApiInstance.get<Customer[]>('/api/customers/', {
-                                params: {
-                                    keyword: params.keyword
-                                }
+                            const res = await axiosApiInstance.get<Page<Customer>>('/api/customers/', {
+                                params: pageParams(params, sort)
                             })
-                            return {data: res.data, success: true, total: res.data.length}
+                            return {data: res.data.items, success: true, total: res.data.total}
                         } catch (e: any) {
                             notification.error({message: e.response.data.message})
                             return {data: [], success: false, total: 0}
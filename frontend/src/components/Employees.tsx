@@ -7,6 +7,7 @@ import EmployeeUserRelationship from "./EmployeeUserRelationship";
 import {PageContainer} from "@ant-design/pro-components";
 import {GlobalStateContext} from "../context/GlobalContext";
 import axiosApiInstance from "../api/axiosClient";
+import {Page, pageParams} from "../api/page";
 
 type Props = {};
 
@@ -72,8 +73,8 @@ const Employees = (props: Props) => {
     }
 
     const columns: ProColumns <Employee>[] = [
-        {title: 'Id', dataIndex: 'id', align: "center", editable: false},
-        {title: 'Full name', dataIndex: 'full_name', align: "center"},
+        {title: 'Id', dataIndex: 'id', align: "center", sorter: true, editable: false},
+        {title: 'Full name', dataIndex: 'full_name', align: "center", sorter: true},
 
         {
             title: 'Action',
@@ -104,12 +105,10 @@ const Employees = (props: Props) => {
                 <EditableProTable<Employee>
                     request={async (params, sort, filter) => {
                         try {
-                            const res = await axiosApiInstance.get<Employee[]>('/api/employees/', {
-                                params: {
-                                    keyword: params.keyword
-                                }
+                            const res = await axiosApiInstance.get<Page<Employee>>('/api/employees/', {
+                                params: pageParams(params, sort)
                             })
-                            return {data: res.data, success: true, total: res.data.length}
+                            return {data: res.data.items, success: true, total: res.data.total}
                         } catch (e: any) {
                             notification.error({message: e.response.data.message})
                             return {data: [], success: false, total: 0}
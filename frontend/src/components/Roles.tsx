@@ -5,6 +5,7 @@ import {EditableProTable} from '@ant-design/pro-table';
 import enUSIntl from "antd/lib/locale/en_US";
 import {PageContainer} from "@ant-design/pro-components";
 import axiosApiInstance from "../api/axiosClient";
+import {Page, pageParams} from "../api/page";
 import {UserEmail} from "./CustomerUserRelationship";
 
 type Props = {};
@@ -70,11 +71,11 @@ const Roles = (props: Props) => {
 
 
     const columns: ProColumns<User>[] = [
-        {title: 'User id', dataIndex: 'id', editable: false, align: "center"},
-        {title: 'Full name', dataIndex: 'full_name', editable: false, align: "center"},
-        {title: 'Email', dataIndex: 'email', editable: false, align: "center"},
+        {title: 'User id', dataIndex: 'id', editable: false, align: "center", sorter: true},
+        {title: 'Full name', dataIndex: 'full_name', editable: false, align: "center", sorter: true},
+        {title: 'Email', dataIndex: 'email', editable: false, align: "center", sorter: true},
         {
-            title: 'Role', dataIndex: 'role', valueType: 'select', align: "center",
+            title: 'Role', dataIndex: 'role', valueType: 'select', align: "center", sorter: true,
             // request: getRoles,
             fieldProps: {
                 // showSearch: true,
@@ -118,12 +119,10 @@ const Roles = (props: Props) => {
                             request={async (params, sort, filter) => {
 
                                 try {
-                                    const res = await axiosApiInstance.get('/api/users/', {
-                                        params: {
-                                            keyword: params.keyword
-                                        }
+                                    const res = await axiosApiInstance.get<Page<User>>('/api/users/', {
+                                        params: pageParams(params, sort)
                                     })
-                                    return {data: res.data, success: true, total: res.data.length}
+                                    return {data: res.data.items, success: true, total: res.data.total}
                                 } catch (e: any) {
                                     notification.error({message: e.response.data.message})
                                     //Το return να είναι της μορφής αυτού που όντως επιστρέφεται
@@ -7,6 +7,7 @@ import {ModalForm, PageContainer, ProFormGroup, ProFormText} from "@ant-design/p
 import {PlusOutlined} from "@ant-design/icons";
 import {GlobalStateContext} from "../context/GlobalContext";
 import axiosApiInstance from "..//api/axiosClient";
+import {Page, pageParams} from "../api/page";
 
 type Props = {};
 
@@ -42,8 +43,8 @@ const Users = (props: Props) => {
     }
 
     const columns: ProColumns<User>[] = [
-        {title: 'Id (Firebase Id)', dataIndex: 'id', align: "center", editable: false},
-        {title: 'Email', dataIndex: 'email', align: "center"},
+        {title: 'Id (Firebase Id)', dataIndex: 'id', align: "center", sorter: true, editable: false},
+        {title: 'Email', dataIndex: 'email', align: "center", sorter: true},
 
         {
             title: 'Action',
@@ -104,12 +105,10 @@ const Users = (props: Props) => {
                     <EditableProTable<User>
                         request={async (params, sort, filter) => {
                             try {
-                                const res = await axiosApiInstance.get<User[]>('/api/firebase/', {
-                                    params: {
-                                        keyword: params.keyword
-                                    }
+                                const res = await axiosApiInstance.get<Page<User>>('/api/firebase/', {
+                                    params: pageParams(params, sort)
                                 })
-                                return {data: res.data, success: true, total: res.data.length}
+                                return {data: res.data.items, success: true, total: res.data.total}
                             } catch (e: any) {
                                 notification.error({message: e.response.data.message})
                                 return {data: [], success: false, total: 0}